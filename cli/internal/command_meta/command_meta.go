@@ -15,10 +15,57 @@ type CommandMeta struct {
 	Args []string
 	// Локальные для команды переменные окружения
 	Envs envsholder.Env
+	// Перенаправления ввода-вывода в порядке их появления в команде
+	Redirects []Redirect
+	// Подстановки процессов в порядке их появления в команде
+	Substitutions []ProcessSubstitution
+}
+
+// Операторы перенаправления ввода-вывода
+const (
+	RedirectInput   = "<"
+	RedirectOutput  = ">"
+	RedirectAppend  = ">>"
+	RedirectClobber = ">|"
+)
+
+// Перенаправление ввода или вывода команды в файл
+type Redirect struct {
+	// Оператор перенаправления
+	Op string
+	// Имя файла
+	Target string
+}
+
+// Направления подстановки процесса
+const (
+	// <(команда): основная команда читает вывод пайплайна
+	SubstituteInput = "<"
+	// >(команда): основная команда пишет во ввод пайплайна
+	SubstituteOutput = ">"
+)
+
+// Подстановка процесса <(команда) или >(команда).
+// При исполнении исходный текст подстановки в аргументе или имени файла
+// перенаправления заменяется на путь к каналу, соединенному с пайплайном.
+type ProcessSubstitution struct {
+	// Направление подстановки
+	Op string
+	// Исходный текст подстановки вместе с оператором и скобками
+	Text string
+	// Слово, в котором находится текст подстановки: номер аргумента в Args
+	// или, если InRedirect, номер перенаправления в Redirects;
+	// -1, если текст подстановки не попал ни в аргумент, ни в имя файла
+	Word       int
+	InRedirect bool
+	// Смещение текста подстановки в слове в байтах
+	Offset int
+	// Пайплайн, который исполняется параллельно с основной командой
+	Commands []CommandMeta
 }
 
 func (m *CommandMeta) IsEmpty() bool {
-	return m.Name == "" && len(m.Envs.Vars) == 0
+	return m.Name == "" && len(m.Envs.Vars) == 0 && len(m.Redirects) == 0
 }
 
 func (m *CommandMeta) Equal(r *CommandMeta) bool {
@@ -48,5 +95,15 @@ func (m *CommandMeta) Equal(r *CommandMeta) bool {
 		}
 	}
 
+	if len(m.Redirects) != len(r.Redirects) {
+		return false
+	}
+
+	for i := range r.Redirects {
+		if m.Redirects[i] != r.Redirects[i] {
+			return false
+		}
+	}
+
 	return true
 }
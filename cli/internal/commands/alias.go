@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strings"
+)
+
+// AliasCommand задает и выводит алиасы сессии.
+// Потоками ввода-вывода данная структура не владеет.
+type AliasCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = AliasCommand{}
+
+// Execute без аргументов выводит все алиасы в виде, пригодном для повторного ввода.
+// Аргумент name=value задает алиас, аргумент name выводит его значение.
+// Если хотя бы один из алиасов не найден, команда завершается с кодом 1.
+func (cmd AliasCommand) Execute(ctx context.Context) error {
+	names := cmd.meta.Args
+	if len(names) == 0 {
+		names = cmd.sess.Aliases()
+	}
+
+	var missing []string
+	for _, arg := range names {
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			if name == "" {
+				return fmt.Errorf("alias: `%s': invalid alias name", arg)
+			}
+			cmd.sess.SetAlias(name, value)
+			continue
+		}
+
+		value, ok := cmd.sess.Alias(arg)
+		if !ok {
+			missing = append(missing, arg)
+			continue
+		}
+		if _, err := fmt.Fprintf(cmd.output, "alias %s=%s\n", arg, ShellQuote(value)); err != nil {
+			return err
+		}
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("alias: %s: not found", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+//////////////////////////////////
+
+// UnaliasCommand удаляет алиасы сессии.
+// Потоками ввода-вывода данная структура не владеет.
+type UnaliasCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type unaliasOptions struct {
+	All bool `short:"a"`
+
+	Positional struct {
+		Names []string
+	} `positional-args:"true"`
+}
+
+var _ Command = UnaliasCommand{}
+
+// Execute удаляет переданные алиасы, а с флагом -a - все алиасы сессии
+func (cmd UnaliasCommand) Execute(ctx context.Context) error {
+	var opts unaliasOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	names := opts.Positional.Names
+	if opts.All {
+		names = cmd.sess.Aliases()
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("unalias: usage: unalias [-a] name [name ...]")
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !cmd.sess.Unalias(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("unalias: %s: not found", strings.Join(missing, ", "))
+	}
+	return nil
+}
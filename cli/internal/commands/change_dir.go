@@ -1,26 +1,47 @@
 package commands
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strings"
 )
 
-// ChangeDirCommand изменяет текущую рабочую директорию терминала.
+// ChangeDirCommand изменяет текущую рабочую директорию сессии.
 // Если команда вызвана без аргументов, то текущей рабочей директорией становится домашнаяя директория пользователя
 // Если у пользователя не установленая домашная директория - команда вернет ошибку.
+// Потоками ввода-вывода данная структура не владеет.
 type ChangeDirCommand struct {
-	meta command_meta.CommandMeta
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
 type сhangeDirOptions struct {
+	Logical  bool `short:"L"`
+	Physical bool `short:"P"`
+
 	Positional struct {
 		Path string
 	} `positional-args:"true" maximum:"1"`
 }
 
+// Переменная со списком директорий, в которых cd ищет относительные пути
+const cdPathKey = "CDPATH"
+
 var _ Command = ChangeDirCommand{}
 
-func (cmd ChangeDirCommand) Execute() error {
+// Execute меняет директорию сессии и обновляет переменные PWD и OLDPWD.
+// Аргумент "-" означает предыдущую директорию ($OLDPWD).
+// Относительный путь, не начинающийся с "." или "..", ищется в директориях из $CDPATH.
+// По умолчанию (-L) символические ссылки в пути сохраняются, с флагом -P раскрываются.
+// Если директория была взята из $OLDPWD или $CDPATH, новый путь выводится в output.
+func (cmd ChangeDirCommand) Execute(ctx context.Context) error {
 	var opts сhangeDirOptions
 	err := arg_parse(&opts, cmd.meta.Args)
 	if err != nil {
@@ -28,12 +49,72 @@ func (cmd ChangeDirCommand) Execute() error {
 	}
 
 	path := opts.Positional.Path
-	if path == "" {
-		path, err = os.UserHomeDir()
+	printDir := false
+	switch path {
+	case "":
+		path, err = homeDir(cmd.sess)
 		if err != nil {
 			return err
 		}
+	case "-":
+		path, _ = cmd.sess.Get(session.OldPwdKey)
+		if path == "" {
+			return fmt.Errorf("cd: OLDPWD not set")
+		}
+		printDir = true
+	default:
+		if found, print, ok := searchCdPath(cmd.sess, path); ok {
+			path, printDir = found, print
+		}
+	}
+
+	if opts.Physical {
+		path, err = filepath.EvalSymlinks(cmd.sess.Path(path))
+		if err != nil {
+			return fmt.Errorf("cd: %v", err)
+		}
+	}
+
+	if err := cmd.sess.Chdir(path); err != nil {
+		return fmt.Errorf("cd: %v", err)
+	}
+	if printDir {
+		if _, err := fmt.Fprintln(cmd.output, cmd.sess.Dir()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// homeDir возвращает домашнюю директорию: значение $HOME сессии
+// или, если оно не задано, домашнюю директорию пользователя процесса
+func homeDir(sess *session.Session) (string, error) {
+	if home, _ := sess.Get("HOME"); home != "" {
+		return home, nil
 	}
+	return os.UserHomeDir()
+}
 
-	return os.Chdir(path)
+// searchCdPath ищет директорию path в директориях из $CDPATH.
+// Пустой элемент $CDPATH означает текущую директорию.
+// Возвращает найденный путь и признак того, что его нужно вывести:
+// как и в bash, путь выводится, если он найден не в текущей директории.
+func searchCdPath(sess *session.Session, path string) (string, bool, bool) {
+	cdPath, _ := sess.Get(cdPathKey)
+	if cdPath == "" || filepath.IsAbs(path) || path == "." || path == ".." ||
+		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") {
+		return "", false, false
+	}
+
+	for _, dir := range strings.Split(cdPath, ":") {
+		candidate := filepath.Join(dir, path)
+		if dir == "" {
+			candidate = path
+		}
+		info, err := sess.FS().Stat(sess.Path(candidate))
+		if err == nil && info.IsDir() {
+			return sess.Path(candidate), dir != "", true
+		}
+	}
+	return "", false, false
 }
@@ -1,85 +1,161 @@
 package commands
 
-// import (
-// 	"os"
-// 	"path/filepath"
-// 	"shell/internal/command_meta"
-// 	"testing"
-
-// 	"github.com/stretchr/testify/require"
-// )
-
-// func TestChangeDir(t *testing.T) {
-// 	baseDir, err := os.MkdirTemp("", "test_cd")
-// 	require.NoError(t, err, "Could not create temp dir")
-// 	defer os.RemoveAll(baseDir)
-
-// 	innerDir, err := os.MkdirTemp(baseDir, "inner")
-// 	require.NoError(t, err)
-
-// 	homeDir, err := os.UserHomeDir()
-// 	require.NoError(t, err)
-
-// 	cases := []struct {
-// 		name         string
-// 		argPath      string
-// 		expectedPath string
-// 		expectErr    bool
-// 	}{
-// 		{
-// 			name:         "return home",
-// 			argPath:      "",
-// 			expectedPath: homeDir,
-// 		},
-// 		{
-// 			name:         "absolute path",
-// 			argPath:      innerDir,
-// 			expectedPath: innerDir,
-// 		},
-// 		{
-// 			name:         "relative path",
-// 			argPath:      innerDir[len(baseDir)+1:],
-// 			expectedPath: innerDir,
-// 		},
-// 		{
-// 			name:      "non-existent path",
-// 			argPath:   filepath.Join(baseDir, "foo", "bar"),
-// 			expectErr: true,
-// 		},
-// 	}
-
-// 	for _, tc := range cases {
-// 		t.Run(tc.name, func(t *testing.T) {
-// 			// Перейти в базовую директорию в начале теста
-// 			require.NoError(t, os.Chdir(baseDir))
-
-// 			args := make([]string, 0, 1)
-// 			if tc.argPath != "" {
-// 				args = append(args, tc.argPath)
-// 			}
-// 			meta := command_meta.CommandMeta{Name: "cd", Args: args}
-
-// 			oldPwd, err := os.Getwd()
-// 			require.NoError(t, err)
-
-// 			cmd := ChangeDirCommand{meta}
-// 			err = cmd.Execute()
-
-// 			if tc.expectErr {
-// 				require.Error(t, err)
-// 			} else {
-// 				newPwd, err := os.Getwd()
-// 				require.NoError(t, err)
-// 				require.NotEqual(t, oldPwd, newPwd, "pwd did not change after execution. Test is inconclusive")
-
-// 				// os.MkdirTemp создает папку в /var,
-// 				// что на macos на самом деле симлинк на /private/var
-// 				// поэтому, чтобы тест честно проходил, надо здесь резолвить симлинк
-// 				expectedDir, err := filepath.EvalSymlinks(tc.expectedPath)
-// 				require.NoError(t, err)
-// 				require.Equal(t, expectedDir, newPwd)
-// 			}
-// 		})
-// 	}
-
-// }
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeDir(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "test_cd")
+	require.NoError(t, err, "Could not create temp dir")
+	defer os.RemoveAll(baseDir)
+
+	innerDir, err := os.MkdirTemp(baseDir, "inner")
+	require.NoError(t, err)
+
+	homeDir, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	processDir, err := os.Getwd()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		argPath      string
+		expectedPath string
+		expectErr    bool
+	}{
+		{
+			name:         "return home",
+			argPath:      "",
+			expectedPath: homeDir,
+		},
+		{
+			name:         "absolute path",
+			argPath:      innerDir,
+			expectedPath: innerDir,
+		},
+		{
+			name:         "relative path",
+			argPath:      innerDir[len(baseDir)+1:],
+			expectedPath: innerDir,
+		},
+		{
+			name:      "non-existent path",
+			argPath:   filepath.Join(baseDir, "foo", "bar"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Каждый случай начинается в базовой директории
+			sess := newTestSession(t, baseDir)
+
+			args := make([]string, 0, 1)
+			if tc.argPath != "" {
+				args = append(args, tc.argPath)
+			}
+			meta := command_meta.CommandMeta{Name: "cd", Args: args}
+
+			cmd := ChangeDirCommand{nil, io.Discard, meta, sess}
+			err = cmd.Execute(context.Background())
+
+			if tc.expectErr {
+				require.Error(t, err)
+				require.Equal(t, baseDir, sess.Dir())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expectedPath, sess.Dir())
+				pwd, _ := sess.Get("PWD")
+				require.Equal(t, tc.expectedPath, pwd)
+				oldPwd, _ := sess.Get("OLDPWD")
+				require.Equal(t, baseDir, oldPwd)
+			}
+
+			// Директория процесса при этом не меняется
+			cwd, err := os.Getwd()
+			require.NoError(t, err)
+			require.Equal(t, processDir, cwd)
+		})
+	}
+}
+
+func TestChangeDirPrevious(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	sess := newTestSession(t, first)
+
+	_, err := runBuiltin(sess, "cd", "-")
+	require.EqualError(t, err, "cd: OLDPWD not set")
+
+	_, err = runBuiltin(sess, "cd", second)
+	require.NoError(t, err)
+
+	output, err := runBuiltin(sess, "cd", "-")
+	require.NoError(t, err)
+	require.Equal(t, first+"\n", output)
+	require.Equal(t, first, sess.Dir())
+
+	oldPwd, _ := sess.Get("OLDPWD")
+	require.Equal(t, second, oldPwd)
+}
+
+func TestChangeDirCdPath(t *testing.T) {
+	base, projects := t.TempDir(), t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(projects, "app"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(base, "local"), 0755))
+
+	sess := newTestSession(t, base)
+	sess.Set("CDPATH", ":"+projects)
+
+	// Найденная в $CDPATH директория выводится
+	output, err := runBuiltin(sess, "cd", "app")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(projects, "app")+"\n", output)
+
+	// Пустой элемент $CDPATH - текущая директория, путь не выводится
+	require.NoError(t, sess.Chdir(base))
+	output, err = runBuiltin(sess, "cd", "local")
+	require.NoError(t, err)
+	require.Empty(t, output)
+	require.Equal(t, filepath.Join(base, "local"), sess.Dir())
+
+	// Пути, начинающиеся с ./, в $CDPATH не ищутся
+	require.NoError(t, sess.Chdir(base))
+	_, err = runBuiltin(sess, "cd", "./app")
+	require.Error(t, err)
+}
+
+func TestChangeDirPhysical(t *testing.T) {
+	base := t.TempDir()
+	target := filepath.Join(base, "target")
+	link := filepath.Join(base, "link")
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, os.Symlink(target, link))
+	physicalTarget, err := filepath.EvalSymlinks(target)
+	require.NoError(t, err)
+
+	sess := newTestSession(t, base)
+	_, err = runBuiltin(sess, "cd", "link")
+	require.NoError(t, err)
+	require.Equal(t, link, sess.Dir())
+
+	physical, err := sess.PhysicalDir()
+	require.NoError(t, err)
+	require.Equal(t, physicalTarget, physical)
+
+	// Логический переход на уровень выше возвращает в base, а не в родителя target
+	_, err = runBuiltin(sess, "cd", "..")
+	require.NoError(t, err)
+	require.Equal(t, base, sess.Dir())
+
+	_, err = runBuiltin(sess, "cd", "-P", "link")
+	require.NoError(t, err)
+	require.Equal(t, physicalTarget, sess.Dir())
+}
@@ -2,79 +2,280 @@ package commands
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"os/exec"
 	"regexp"
 	"shell/internal/command_meta"
 	envsholder "shell/internal/envs_holder"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // Интерфейс, который реализуют все команды,
 // представленные в интерпретаторе
 type Command interface {
-	Execute() error
+	Execute(ctx context.Context) error
 }
 
 //////////////////////////////////
 
-// Фабрика для создания конкретных команд на основании метаданных команды
+// Фабрика для создания конкретных команд на основании метаданных команды.
+// Все созданные фабрикой команды работают с состоянием одной сессии.
 type CommandFactory struct {
+	sess   *session.Session
+	runner PipelineRunner
 }
 
-// Метод фабрики, который создает конкретную команду на основании метаданных
-func (f *CommandFactory) CommandFromMeta(meta command_meta.CommandMeta, in *os.File, out *os.File) Command {
-	switch meta.Name {
-	case "cat":
-		return CatCommand{in, out, meta}
-	case "wc":
-		return WcCommand{in, out, meta}
-	case "echo":
-		return EchoCommand{in, out, meta}
-	case "pwd":
-		return PwdCommand{in, out, meta}
-	case "exit":
-		return ExitCommand{in, out, meta}
-	case "grep":
-		return GrepCommand{in, out, meta}
-	case "cd":
-		return ChangeDirCommand{meta}
-	case "ls":
-		return ListDirCommand{out, meta}
-	case "":
-		return SetGlobalEnvCommand{in, out, meta}
-	default:
-		return ProcessCommand{in, out, meta}
+// PipelineRunner создает и исполняет пайплайн команд, возвращая его код возврата
+// и ошибку, которой он завершился. Его реализует исполнитель, чтобы встроенные
+// команды могли запускать пайплайны так же, как сессия.
+// WrapCommand оборачивает команду meta, которую встроенная команда запускает
+// сама, в обход пайплайна, чтобы исполнитель учел ее, например, в журнале аудита.
+type PipelineRunner interface {
+	RunPipeline(ctx context.Context, input io.Reader, output io.Writer, metas []command_meta.CommandMeta) (int, error)
+	WrapCommand(meta command_meta.CommandMeta, cmd Command) Command
+}
+
+// Создает фабрику команд для сессии sess
+func NewCommandFactory(sess *session.Session) *CommandFactory {
+	return &CommandFactory{sess: sess}
+}
+
+// Session возвращает сессию, с которой работают команды фабрики
+func (f *CommandFactory) Session() *session.Session {
+	return f.sess
+}
+
+// SetPipelineRunner задает исполнитель пайплайнов для команд фабрики
+func (f *CommandFactory) SetPipelineRunner(runner PipelineRunner) {
+	f.runner = runner
+}
+
+// PipelineRunner возвращает исполнитель пайплайнов или nil, если он не задан
+func (f *CommandFactory) PipelineRunner() PipelineRunner {
+	return f.runner
+}
+
+// ChildCommand создает команду, которую запускает другая встроенная команда
+// (xargs, find -exec, timeout, command). Если задан исполнитель пайплайнов,
+// команда оборачивается им, как и команды пайплайнов.
+func (f *CommandFactory) ChildCommand(meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+	cmd := f.CommandFromMeta(meta, in, out)
+	if f.runner != nil {
+		return f.runner.WrapCommand(meta, cmd)
 	}
+	return cmd
+}
+
+// IsExternal сообщает, что команда будет исполнена внешним процессом.
+// Такой команде для ввода-вывода нужны настоящие файловые дескрипторы.
+func (f *CommandFactory) IsExternal(meta command_meta.CommandMeta) bool {
+	_, ok := f.CommandFromMeta(meta, nil, nil).(ProcessCommand)
+	return ok
+}
+
+// Метод фабрики, который создает конкретную команду на основании метаданных.
+// В ограниченном режиме сессии запрещенная команда заменяется командой,
+// которая завершается с ошибкой RestrictedError.
+func (f *CommandFactory) CommandFromMeta(meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+	if err := checkRestricted(f.sess, meta); err != nil {
+		return restrictedCommand{err}
+	}
+
+	if meta.Name == "" {
+		return SetGlobalEnvCommand{in, out, meta, f.sess}
+	}
+	if create, ok := builtins[meta.Name]; ok {
+		return create(f, meta, in, out)
+	}
+	return ProcessCommand{input: in, output: out, meta: meta, sess: f.sess}
+}
+
+// Конструктор встроенной команды
+type builtinConstructor func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command
+
+// Встроенные команды, которые создает CommandFactory, по именам.
+// Из этой же таблицы берутся имена для IsBuiltin и подсказок.
+var builtins = map[string]builtinConstructor{
+	"cat": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return CatCommand{in, out, meta, f.sess}
+	},
+	"wc": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return WcCommand{in, out, meta, f.sess}
+	},
+	"echo": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return EchoCommand{in, out, meta}
+	},
+	"pwd": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return PwdCommand{in, out, meta, f.sess}
+	},
+	"exit": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ExitCommand{in, out, meta, f.sess}
+	},
+	"grep": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return GrepCommand{in, out, meta, f.sess}
+	},
+	"cd": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ChangeDirCommand{in, out, meta, f.sess}
+	},
+	"pushd": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return PushdCommand{in, out, meta, f.sess}
+	},
+	"popd": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return PopdCommand{in, out, meta, f.sess}
+	},
+	"dirs": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return DirsCommand{in, out, meta, f.sess}
+	},
+	"ls": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ListDirCommand{out, meta, f.sess}
+	},
+	"head": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return HeadCommand{in, out, meta, f.sess}
+	},
+	"tail": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TailCommand{in, out, meta, f.sess}
+	},
+	"sort": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return SortCommand{in, out, meta, f.sess}
+	},
+	"uniq": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return UniqCommand{in, out, meta, f.sess}
+	},
+	"cut": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return CutCommand{in, out, meta, f.sess}
+	},
+	"tr": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TrCommand{in, out, meta}
+	},
+	"tee": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TeeCommand{in, out, meta, f.sess}
+	},
+	"find": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return FindCommand{in, out, meta, f}
+	},
+	"xargs": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return XargsCommand{in, out, meta, f}
+	},
+	"parallel": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ParallelCommand{in, out, meta, f}
+	},
+	"watch": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return WatchCommand{in, out, meta, f}
+	},
+	"printf": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return PrintfCommand{in, out, meta}
+	},
+	"read": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ReadCommand{in, out, meta, f.sess}
+	},
+	"test": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TestCommand{in, out, meta, f.sess}
+	},
+	"[": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TestCommand{in, out, meta, f.sess}
+	},
+	"let": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return LetCommand{in, out, meta, f.sess}
+	},
+	"alias": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return AliasCommand{in, out, meta, f.sess}
+	},
+	"unalias": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return UnaliasCommand{in, out, meta, f.sess}
+	},
+	"env": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return EnvCommand{in, out, meta, f.sess}
+	},
+	"loadenv": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return LoadenvCommand{in, out, meta, f.sess}
+	},
+	"set": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return SetCommand{in, out, meta, f.sess}
+	},
+	"shift": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ShiftCommand{in, out, meta, f.sess}
+	},
+	"trap": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TrapCommand{in, out, meta, f.sess}
+	},
+	"timeout": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return TimeoutCommand{in, out, meta, f}
+	},
+	"hash": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return HashCommand{in, out, meta, f.sess}
+	},
+	"which": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return WhichCommand{in, out, meta, f.sess}
+	},
+	"command": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return CommandCommand{in, out, meta, f}
+	},
+	"ps": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return PsCommand{in, out, meta}
+	},
+	"from-json": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return FromJsonCommand{in, out, meta, f.sess}
+	},
+	"to-json": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return ToJsonCommand{in, out, meta}
+	},
+	"where": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return WhereCommand{in, out, meta}
+	},
+	"select": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return SelectCommand{in, out, meta}
+	},
+	"sort-by": func(f *CommandFactory, meta command_meta.CommandMeta, in io.Reader, out io.Writer) Command {
+		return SortByCommand{in, out, meta}
+	},
 }
 
 //////////////////////////////////
 
 // Команда wc.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type WcCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
 // Команда wc выводит количество строк, слов и байтов в файле.
 // Имя файла берется из метаданных команды.
 // Результат работы выводится в файл, который представлен дескриптором output.
-func (cmd WcCommand) Execute() error {
+func (cmd WcCommand) Execute(ctx context.Context) error {
 	in := cmd.input
 	filename := ""
 	if len(cmd.meta.Args) != 0 {
 		filename = cmd.meta.Args[0]
-		file, err := os.Open(filename)
+		file, err := vfs.Open(cmd.sess.FS(), cmd.sess.Path(filename))
 		if err != nil {
 			return err
 		}
 		in = file
 		defer file.Close()
 	}
+	// Записи считаются строками в формате JSON lines, как после to-json -l
+	if _, ok := in.(RecordReader); ok {
+		var lines bytes.Buffer
+		err := readRecords(in, func(record Record) error {
+			return json.NewEncoder(&lines).Encode(record)
+		})
+		if err != nil {
+			return err
+		}
+		in = &lines
+	}
 
 	scanner := bufio.NewScanner(in)
 	lineCount, wordCount, byteCount := 0, 0, 0
@@ -102,34 +303,38 @@ func (cmd WcCommand) Execute() error {
 //////////////////////////////////
 
 // Команда cat.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type CatCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
 // Команда cat выводит содержимое файла.
 // Имя файла берется из метаданных команды.
 // Результат работы выводится в файл, который представлен дескриптором output.
-func (cmd CatCommand) Execute() error {
-	var in *os.File
+func (cmd CatCommand) Execute(ctx context.Context) error {
+	var in io.Reader
 	var err error = nil
 
 	if len(cmd.meta.Args) != 0 {
 		filename := cmd.meta.Args[0]
-		in, err = os.Open(filename)
+		in, err = vfs.Open(cmd.sess.FS(), cmd.sess.Path(filename))
 	} else {
 		in = cmd.input
 	}
 
 	if err != nil {
-		fmt.Printf("cat: Failed to open file with err: %s\n", err)
+		fmt.Fprintf(cmd.sess.Stderr, "cat: Failed to open file with err: %s\n", err)
 		return err
 	}
 
 	buffer := make([]byte, 4096)
 	for err == nil {
+		if err = ctx.Err(); err != nil {
+			break
+		}
 		var n int
 		n, err = in.Read(buffer)
 		if err == nil {
@@ -149,44 +354,98 @@ func (cmd CatCommand) Execute() error {
 //////////////////////////////////
 
 // Команда echo.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type EchoCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
 }
 
 // Команда echo выводит свои аргументы.
 // Результат работы выводится в файл, который представлен дескриптором output.
 // Аргументы команды берутся из метаданных команды.
-func (cmd EchoCommand) Execute() error {
-	buffer := []byte(strings.Join(cmd.meta.Args, " "))
-	buffer = append(buffer, '\n')
+// Флаг -n отключает перевод строки в конце, -e включает обработку
+// escape-последовательностей, -E выключает ее.
+func (cmd EchoCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	newLine, escapes := true, false
+	for len(args) > 0 && isEchoFlags(args[0]) {
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'n':
+				newLine = false
+			case 'e':
+				escapes = true
+			case 'E':
+				escapes = false
+			}
+		}
+		args = args[1:]
+	}
+
+	text := strings.Join(args, " ")
+	if escapes {
+		var stop bool
+		text, stop = expand_escapes(text, octalWithZero)
+		if stop {
+			newLine = false
+		}
+	}
+
+	buffer := []byte(text)
+	if newLine {
+		buffer = append(buffer, '\n')
+	}
 	if _, err := cmd.output.Write(buffer); err != nil {
 		return err
 	}
 	return nil
 }
 
+// isEchoFlags проверяет, что аргумент состоит только из флагов echo.
+// Иначе, как и в bash, аргумент выводится как есть.
+func isEchoFlags(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	return strings.Trim(arg[1:], "neE") == ""
+}
+
 //////////////////////////////////
 
 // Команда pwd.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type PwdCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
-// Команда pwd выводит содержимое текущей директории.
-// Имя директории берется из метаданных команды.
+// Аргументы команды pwd.
+type pwdOptions struct {
+	Logical  bool `short:"L"`
+	Physical bool `short:"P"`
+}
+
+// Команда pwd выводит текущую директорию сессии.
+// По умолчанию (-L) выводится логический путь, с флагом -P символические ссылки раскрываются.
 // Результат работы выводится в файл, который представлен дескриптором output.
-func (cmd PwdCommand) Execute() error {
-	dir, err := os.Getwd()
+func (cmd PwdCommand) Execute(ctx context.Context) error {
+	var opts pwdOptions
+	err := arg_parse(&opts, cmd.meta.Args)
 	if err != nil {
 		return err
 	}
 
+	dir := cmd.sess.Dir()
+	if opts.Physical {
+		dir, err = cmd.sess.PhysicalDir()
+		if err != nil {
+			return err
+		}
+	}
+
 	buffer := []byte(dir)
 	if _, err := cmd.output.Write(buffer); err != nil {
 		return err
@@ -196,25 +455,57 @@ func (cmd PwdCommand) Execute() error {
 
 //////////////////////////////////
 
+// Время, которое дается внешнему процессу на завершение после SIGTERM
+const processKillDelay = 2 * time.Second
+
 // Команда process.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type ProcessCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
+	// Процесс получает только локальные переменные команды, без переменных сессии
+	onlyLocalEnvs bool
 }
 
 // Данный метод запускает внешнюю программу с указанным именем и набором аргументов.
 // Аргументы и имя программы берется из метаданных команды.
+// Программа ищется в PATH сессии с запоминанием в таблице сессии (см. команду hash);
+// если она не найдена, возвращается CommandNotFoundError с похожими именами,
+// а если путь ведет к неисполняемому файлу - CommandNotExecutableError.
+// Окружение процесса - локальные переменные команды и переменные сессии
+// (с onlyLocalEnvs - только локальные, так запускает программы env).
 // Ввод команда берет из файла, который представлен дескриптором input.
 // Результат работы выводится в файл, который представлен дескриптором output.
-func (cmd ProcessCommand) Execute() error {
-	process := exec.Command(cmd.meta.Name, cmd.meta.Args...)
+func (cmd ProcessCommand) Execute(ctx context.Context) error {
+	path, err := lookPath(cmd.sess, cmd.meta.Name, true)
+	if notFound, ok := err.(*CommandNotFoundError); ok && !notFound.IsPath {
+		notFound.Suggestions = suggestCommands(cmd.sess, cmd.meta.Name)
+	}
+	if err != nil {
+		return err
+	}
+	process := exec.CommandContext(ctx, path, cmd.meta.Args...)
+	process.Args[0] = cmd.meta.Name
+	// При отмене контекста процесс сначала получает SIGTERM
+	// и только через processKillDelay завершается принудительно
+	process.Cancel = func() error {
+		return process.Process.Signal(syscall.SIGTERM)
+	}
+	process.WaitDelay = processKillDelay
+	process.Dir = cmd.sess.Dir()
 	process.Stdin = cmd.input
 	process.Stdout = cmd.output
+	process.Stderr = cmd.sess.Stderr
 	process.Env = cmd.meta.Envs.Environ()
-	process.Env = append(process.Env, envsholder.GlobalEnv.Environ()...)
-	err := process.Run()
+	if !cmd.onlyLocalEnvs {
+		process.Env = append(process.Env, cmd.sess.Environ()...)
+	}
+	err = process.Run()
+	if usage := usageFrom(ctx); usage != nil && process.ProcessState != nil {
+		usage.Add(process.ProcessState)
+	}
 	if err != nil {
 		return err
 	}
@@ -224,27 +515,46 @@ func (cmd ProcessCommand) Execute() error {
 //////////////////////////////////
 
 // Команда exit.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type ExitCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
-// Команда exit завершает исполнение процесса shell.
-func (cmd ExitCommand) Execute() error {
-	os.Exit(0)
-	return nil
+// Команда exit завершает сессию оболочки, в которой она исполнена.
+// Сам процесс продолжает работу: сессия узнает о завершении по ошибке ExitError.
+// Код завершения берется из аргумента по модулю 256, без аргумента - код последней команды.
+// Если аргумент не число, сессия завершается с кодом 2.
+func (cmd ExitCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) > 1 {
+		return fmt.Errorf("exit: too many arguments")
+	}
+	if len(args) == 0 {
+		status, _ := cmd.sess.Get(envsholder.ExecStatusKey)
+		code, _ := strconv.Atoi(status)
+		return ExitError{Status: code}
+	}
+
+	code, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Fprintf(cmd.sess.Stderr, "exit: %s: numeric argument required\n", args[0])
+		return ExitError{Status: 2}
+	}
+	return ExitError{Status: code & 0xff}
 }
 
 //////////////////////////////////
 
 // Команда grep.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type GrepCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
 // Аргументы команды grep.
@@ -263,7 +573,7 @@ type GrepOptions struct {
 // переданное в дескриптор input.
 // Регулярное выражение передается первым аргументом из метаданных команды.
 // Результат работы выводится в файл, представленный дескриптором output.
-func (cmd GrepCommand) Execute() error {
+func (cmd GrepCommand) Execute(ctx context.Context) error {
 	var opts GrepOptions
 	err := arg_parse(&opts, cmd.meta.Args)
 	if err != nil {
@@ -273,10 +583,12 @@ func (cmd GrepCommand) Execute() error {
 	expr := opts.Positional.Expr
 	input := cmd.input
 	if opts.Positional.Filename != "" {
-		input, err = os.Open(opts.Positional.Filename)
+		file, err := vfs.Open(cmd.sess.FS(), cmd.sess.Path(opts.Positional.Filename))
 		if err != nil {
 			return err
 		}
+		defer file.Close()
+		input = file
 	}
 	if opts.OnlyWholeWords {
 		expr = fmt.Sprintf("([^[:alnum:]_.]|^)%s([^[:alnum:]_.]|$)", expr)
@@ -294,6 +606,9 @@ func (cmd GrepCommand) Execute() error {
 	scanner := bufio.NewScanner(input)
 
 	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		includeLine := false
 
 		if remaining_lines > 0 {
@@ -320,18 +635,25 @@ func (cmd GrepCommand) Execute() error {
 
 //////////////////////////////////
 
-// Установка переменных окружения в глобальной области видимости.
-// Дескрипторами файлов данная структура не владеет.
+// Установка переменных окружения в области видимости сессии.
+// Потоками ввода-вывода данная структура не владеет.
 type SetGlobalEnvCommand struct {
-	input  *os.File
-	output *os.File
+	input  io.Reader
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
-// Данная команда устанавливает переданные переменные окружения в глобальное хранилище.
-func (cmd SetGlobalEnvCommand) Execute() error {
+// Данная команда устанавливает переданные переменные окружения в хранилище сессии.
+// В ограниченном режиме переменные PATH, SHELL и ENV изменять нельзя.
+func (cmd SetGlobalEnvCommand) Execute(ctx context.Context) error {
+	for k := range cmd.meta.Envs.Vars {
+		if err := CheckAssignment(cmd.sess, k); err != nil {
+			return err
+		}
+	}
 	for k, v := range cmd.meta.Envs.Vars {
-		envsholder.GlobalEnv.Set(k, v)
+		cmd.sess.Set(k, v)
 	}
 	return nil
 }
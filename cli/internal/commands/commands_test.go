@@ -3,6 +3,7 @@ package commands
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,22 +11,50 @@ import (
 	"os/exec"
 	"shell/internal/command_meta"
 	envsholder "shell/internal/envs_holder"
+	"shell/internal/session"
+	"shell/pkg/vfs"
 	"testing"
 )
 
-func TestWcExecuteSimple(t *testing.T) {
-	file, err := ioutil.TempFile(os.TempDir(), "test")
+// newTestSession создает сессию с текущей директорией dir.
+// Пустой dir означает текущую директорию процесса.
+func newTestSession(t *testing.T, dir string) *session.Session {
+	sess, err := session.New(dir, io.Discard)
 	if err != nil {
-		t.Fatal("Can't create temp file", err)
+		t.Fatal("Can't create session", err)
 	}
-	defer os.Remove(file.Name())
+	return sess
+}
 
-	expected := []byte(fmt.Sprintf("\t%d\t%d\t%d\t%s\n", 2, 2, 12, file.Name()))
-	file.Write([]byte("Hello\nworld"))
-	file.Close()
+// runBuiltin исполняет команду name, созданную фабрикой сессии sess, и возвращает ее вывод
+func runBuiltin(sess *session.Session, name string, args ...string) (string, error) {
+	var output bytes.Buffer
+	meta := command_meta.CommandMeta{Name: name, Args: args}
+	err := NewCommandFactory(sess).CommandFromMeta(meta, nil, &output).Execute(context.Background())
+	return output.String(), err
+}
+
+// newMemSession создает сессию в корне файловой системы в памяти
+// с файлами files: абсолютный путь -> содержимое
+func newMemSession(t *testing.T, files map[string]string) *session.Session {
+	fsys := vfs.NewMem()
+	for name, content := range files {
+		if err := vfs.WriteFile(fsys, name, []byte(content), 0644); err != nil {
+			t.Fatal("Can't create file", err)
+		}
+	}
+
+	sess := newTestSession(t, "/")
+	sess.SetFS(fsys)
+	return sess
+}
+
+func TestWcExecuteSimple(t *testing.T) {
+	sess := newMemSession(t, map[string]string{"/test": "Hello\nworld"})
+	expected := []byte(fmt.Sprintf("\t%d\t%d\t%d\t%s\n", 2, 2, 12, "/test"))
 
 	args := make([]string, 0)
-	args = append(args, file.Name())
+	args = append(args, "/test")
 	meta := command_meta.CommandMeta{Name: "wc", Args: args}
 	rp, wp, err := os.Pipe()
 	if err != nil {
@@ -35,10 +64,10 @@ func TestWcExecuteSimple(t *testing.T) {
 
 	r := bufio.NewReader(rp)
 	buf := make([]byte, 0, 128)
-	cmd := WcCommand{nil, wp, meta}
+	cmd := WcCommand{nil, wp, meta, sess}
 	go func(cmd WcCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	for {
@@ -62,18 +91,11 @@ func TestWcExecuteSimple(t *testing.T) {
 }
 
 func TestWcExecuteEmpty(t *testing.T) {
-	file, err := ioutil.TempFile(os.TempDir(), "test")
-	if err != nil {
-		t.Fatal("Can't create temp file", err)
-	}
-	defer os.Remove(file.Name())
-
-	expected := []byte(fmt.Sprintf("\t%d\t%d\t%d\t%s\n", 0, 0, 0, file.Name()))
-	file.Write([]byte(""))
-	file.Close()
+	sess := newMemSession(t, map[string]string{"/test": ""})
+	expected := []byte(fmt.Sprintf("\t%d\t%d\t%d\t%s\n", 0, 0, 0, "/test"))
 
 	args := make([]string, 0)
-	args = append(args, file.Name())
+	args = append(args, "/test")
 	meta := command_meta.CommandMeta{Name: "wc", Args: args}
 	rp, wp, err := os.Pipe()
 	if err != nil {
@@ -83,10 +105,10 @@ func TestWcExecuteEmpty(t *testing.T) {
 
 	r := bufio.NewReader(rp)
 	buf := make([]byte, 0, 128)
-	cmd := WcCommand{nil, wp, meta}
+	cmd := WcCommand{nil, wp, meta, sess}
 	go func(cmd WcCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	{
@@ -106,18 +128,11 @@ func TestWcExecuteEmpty(t *testing.T) {
 //////////////////////////////////
 
 func TestCatExecuteSimple(t *testing.T) {
-	file, err := ioutil.TempFile(os.TempDir(), "test")
-	if err != nil {
-		t.Fatal("Can't create temp file", err)
-	}
-	defer os.Remove(file.Name())
-
+	sess := newMemSession(t, map[string]string{"/test": "Hello world"})
 	expected := []byte("Hello world")
-	file.Write([]byte("Hello world"))
-	file.Close()
 
 	args := make([]string, 0)
-	args = append(args, file.Name())
+	args = append(args, "/test")
 	meta := command_meta.CommandMeta{Name: "cat", Args: args}
 	rp, wp, err := os.Pipe()
 	if err != nil {
@@ -127,10 +142,10 @@ func TestCatExecuteSimple(t *testing.T) {
 
 	r := bufio.NewReader(rp)
 	buf := make([]byte, 0, 128)
-	cmd := CatCommand{nil, wp, meta}
+	cmd := CatCommand{nil, wp, meta, sess}
 	go func(cmd CatCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	for {
@@ -154,18 +169,11 @@ func TestCatExecuteSimple(t *testing.T) {
 }
 
 func TestCatExecuteEmpty(t *testing.T) {
-	file, err := ioutil.TempFile(os.TempDir(), "test")
-	if err != nil {
-		t.Fatal("Can't create temp file", err)
-	}
-	defer os.Remove(file.Name())
-
+	sess := newMemSession(t, map[string]string{"/test": ""})
 	expected := []byte("")
-	file.Write([]byte(""))
-	file.Close()
 
 	args := make([]string, 0)
-	args = append(args, file.Name())
+	args = append(args, "/test")
 	meta := command_meta.CommandMeta{Name: "cat", Args: args}
 	rp, wp, err := os.Pipe()
 	if err != nil {
@@ -175,10 +183,10 @@ func TestCatExecuteEmpty(t *testing.T) {
 
 	r := bufio.NewReader(rp)
 	buf := make([]byte, 0, 128)
-	cmd := CatCommand{nil, wp, meta}
+	cmd := CatCommand{nil, wp, meta, sess}
 	go func(cmd CatCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	{
@@ -212,7 +220,7 @@ func TestEchoExecuteSimple(t *testing.T) {
 	cmd := EchoCommand{nil, wp, meta}
 	go func(cmd EchoCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	for {
@@ -251,7 +259,7 @@ func TestEchoExecuteEmpty(t *testing.T) {
 	cmd := EchoCommand{nil, wp, meta}
 	go func(cmd EchoCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	{
@@ -287,10 +295,10 @@ func TestPwdExecuteSimple(t *testing.T) {
 
 	r := bufio.NewReader(rp)
 	buf := make([]byte, 0, 128)
-	cmd := PwdCommand{nil, wp, meta}
+	cmd := PwdCommand{nil, wp, meta, newTestSession(t, "")}
 	go func(cmd PwdCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	{
@@ -340,10 +348,10 @@ func TestProcessExecuteSimple(t *testing.T) {
 
 	r := bufio.NewReader(rp)
 	buf := make([]byte, 0, 1024)
-	cmd := ProcessCommand{nil, wp, meta}
+	cmd := ProcessCommand{output: wp, meta: meta, sess: newTestSession(t, "")}
 	go func(cmd ProcessCommand, wp *os.File) {
 		defer wp.Close()
-		cmd.Execute()
+		cmd.Execute(context.Background())
 	}(cmd, wp)
 
 	{
@@ -373,10 +381,11 @@ func TestSetEnvs(t *testing.T) {
 			Vars: map[string]string{"hello": expected},
 		},
 	}
-	cmd := SetGlobalEnvCommand{nil, nil, meta}
-	cmd.Execute()
+	sess := newTestSession(t, "")
+	cmd := SetGlobalEnvCommand{nil, nil, meta, sess}
+	cmd.Execute(context.Background())
 
-	val := envsholder.GlobalEnv.Vars["hello"]
+	val, _ := sess.Get("hello")
 	if val != expected {
 		t.Fatalf(`Different outputs: %q != %q`, val, expected)
 	}
@@ -552,11 +561,11 @@ func RunGrepTest(t *testing.T, input string, expected string, args []string) {
 	}
 	defer rp.Close()
 
-	cmd := GrepCommand{file, wp, meta}
+	cmd := GrepCommand{file, wp, meta, newTestSession(t, "")}
 
 	file.Sync()
 	file.Seek(0, io.SeekStart)
-	if err := cmd.Execute(); err != nil {
+	if err := cmd.Execute(context.Background()); err != nil {
 		t.Fatal("Cant grep", err)
 	}
 	wp.Close()
@@ -572,3 +581,31 @@ func RunGrepTest(t *testing.T, input string, expected string, args []string) {
 		t.Fatalf(`Different outputs: %q != %q`, buf, expected)
 	}
 }
+
+func TestEchoExecuteFlags(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"no newline", []string{"-n", "a", "b"}, "a b"},
+		{"escapes", []string{"-e", `a\tb\n`}, "a\tb\n\n"},
+		{"escapes disabled", []string{`a\tb`}, "a\\tb\n"},
+		{"combined", []string{"-ne", `x\101`}, `x\101`},
+		{"octal", []string{"-en", `x\0101`}, "xA"},
+		{"stop", []string{"-e", `a\cb`}, "a"},
+		{"not a flag", []string{"-x", "a"}, "-x a\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "echo", Args: tc.args}
+			actual := runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+				return EchoCommand{in, out, meta}
+			})
+			if actual != tc.expected {
+				t.Fatalf(`Different outputs: %q != %q`, actual, tc.expected)
+			}
+		})
+	}
+}
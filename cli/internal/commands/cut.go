@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strconv"
+	"strings"
+)
+
+// CutCommand выводит выбранные поля каждой строки.
+// Потоками ввода-вывода данная структура не владеет.
+type CutCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type cutOptions struct {
+	Delimiter string `short:"d" default:"\t"`
+	Fields    string `short:"f" required:"true"`
+
+	Positional struct {
+		Filename string
+	} `positional-args:"true" maximum:"1"`
+}
+
+// Диапазон номеров полей [from, to], нумерация с 1.
+// Нулевое значение to означает "до последнего поля".
+type fieldRange struct {
+	from int
+	to   int
+}
+
+var _ Command = CutCommand{}
+
+// Execute выводит поля, перечисленные во флаге -f (например 1,3-5,7-),
+// используя разделитель из флага -d (по умолчанию табуляция).
+// Строки без разделителя выводятся целиком.
+func (cmd CutCommand) Execute(ctx context.Context) error {
+	var opts cutOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	if len([]rune(opts.Delimiter)) != 1 {
+		return fmt.Errorf("cut: the delimiter must be a single character")
+	}
+	ranges, err := parseFieldRanges(opts.Fields)
+	if err != nil {
+		return err
+	}
+
+	in, closeInput, err := open_input(cmd.sess, opts.Positional.Filename, cmd.input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	lines, err := read_lines(in)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if strings.Contains(line, opts.Delimiter) {
+			line = selectFields(strings.Split(line, opts.Delimiter), ranges, opts.Delimiter)
+		}
+		if _, err := io.WriteString(cmd.output, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFieldRanges разбирает список полей вида 1,3-5,7-
+func parseFieldRanges(list string) ([]fieldRange, error) {
+	var ranges []fieldRange
+	for _, part := range strings.Split(list, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+
+		var r fieldRange
+		var err error
+		if from == "" {
+			r.from = 1
+		} else if r.from, err = strconv.Atoi(from); err != nil || r.from < 1 {
+			return nil, fmt.Errorf("cut: invalid field value: %s", part)
+		}
+
+		switch {
+		case !isRange:
+			r.to = r.from
+		case to == "":
+			r.to = 0
+		default:
+			if r.to, err = strconv.Atoi(to); err != nil || r.to < r.from {
+				return nil, fmt.Errorf("cut: invalid field range: %s", part)
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// selectFields склеивает поля, попавшие хотя бы в один диапазон
+func selectFields(fields []string, ranges []fieldRange, delimiter string) string {
+	selected := make([]string, 0, len(fields))
+	for i, field := range fields {
+		num := i + 1
+		for _, r := range ranges {
+			if num >= r.from && (r.to == 0 || num <= r.to) {
+				selected = append(selected, field)
+				break
+			}
+		}
+	}
+	return strings.Join(selected, delimiter)
+}
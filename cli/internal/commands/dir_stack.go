@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strconv"
+	"strings"
+)
+
+// PushdCommand добавляет директорию в стек директорий сессии и переходит в нее.
+// Потоками ввода-вывода данная структура не владеет.
+type PushdCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = PushdCommand{}
+
+// Execute без аргументов меняет местами текущую директорию и вершину стека.
+// С аргументом +N или -N стек прокручивается так, чтобы N-я директория
+// (считая слева или справа соответственно) стала текущей.
+// Иначе текущая директория кладется в стек, и сессия переходит в переданную.
+// После успешного перехода стек выводится, как командой dirs.
+func (cmd PushdCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) > 1 {
+		return fmt.Errorf("pushd: too many arguments")
+	}
+
+	full := append([]string{cmd.sess.Dir()}, cmd.sess.DirStack()...)
+	switch {
+	case len(args) == 0:
+		if len(full) < 2 {
+			return fmt.Errorf("pushd: no other directory")
+		}
+		full[0], full[1] = full[1], full[0]
+	default:
+		index, ok, err := parseStackIndex("pushd", args[0], len(full))
+		if err != nil {
+			return err
+		}
+		if ok {
+			full = append(full[index:], full[:index]...)
+		} else {
+			full = append([]string{cmd.sess.Path(args[0])}, full...)
+		}
+	}
+
+	if err := cmd.sess.Chdir(full[0]); err != nil {
+		return fmt.Errorf("pushd: %v", err)
+	}
+	cmd.sess.SetDirStack(full[1:])
+	return writeDirStack(cmd.output, cmd.sess, dirsOptions{})
+}
+
+//////////////////////////////////
+
+// PopdCommand удаляет директорию из стека директорий сессии.
+// Потоками ввода-вывода данная структура не владеет.
+type PopdCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = PopdCommand{}
+
+// Execute без аргументов снимает директорию с вершины стека и переходит в нее.
+// С аргументом +N или -N удаляется N-я директория (считая слева или справа).
+// Удаление нулевой директории, как и в bash, означает переход в следующую.
+// После успешного удаления стек выводится, как командой dirs.
+func (cmd PopdCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) > 1 {
+		return fmt.Errorf("popd: too many arguments")
+	}
+
+	full := append([]string{cmd.sess.Dir()}, cmd.sess.DirStack()...)
+	if len(full) < 2 {
+		return fmt.Errorf("popd: directory stack empty")
+	}
+
+	index := 0
+	if len(args) == 1 {
+		var ok bool
+		var err error
+		index, ok, err = parseStackIndex("popd", args[0], len(full))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("popd: %s: invalid argument", args[0])
+		}
+	}
+
+	full = append(full[:index], full[index+1:]...)
+	if index == 0 {
+		if err := cmd.sess.Chdir(full[0]); err != nil {
+			return fmt.Errorf("popd: %v", err)
+		}
+	}
+	cmd.sess.SetDirStack(full[1:])
+	return writeDirStack(cmd.output, cmd.sess, dirsOptions{})
+}
+
+//////////////////////////////////
+
+// DirsCommand выводит стек директорий сессии.
+// Потоками ввода-вывода данная структура не владеет.
+type DirsCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type dirsOptions struct {
+	Clear    bool `short:"c"`
+	Long     bool `short:"l"`
+	PerLine  bool `short:"p"`
+	Numbered bool `short:"v"`
+}
+
+var _ Command = DirsCommand{}
+
+// Execute выводит текущую директорию и стек в одну строку.
+// Флаг -p выводит по одной директории в строке, -v дополнительно нумерует их,
+// -l отключает замену домашней директории на ~, -c очищает стек.
+func (cmd DirsCommand) Execute(ctx context.Context) error {
+	var opts dirsOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	if opts.Clear {
+		cmd.sess.SetDirStack(nil)
+		return nil
+	}
+	return writeDirStack(cmd.output, cmd.sess, opts)
+}
+
+// writeDirStack выводит текущую директорию и стек директорий в формате dirs
+func writeDirStack(output io.Writer, sess *session.Session, opts dirsOptions) error {
+	home, _ := sess.Get("HOME")
+	dirs := append([]string{sess.Dir()}, sess.DirStack()...)
+	for i, dir := range dirs {
+		if !opts.Long && home != "" && (dir == home || strings.HasPrefix(dir, home+"/")) {
+			dirs[i] = "~" + dir[len(home):]
+		}
+	}
+
+	var text string
+	switch {
+	case opts.Numbered:
+		for i, dir := range dirs {
+			text += fmt.Sprintf("%2d  %s\n", i, dir)
+		}
+	case opts.PerLine:
+		text = strings.Join(dirs, "\n") + "\n"
+	default:
+		text = strings.Join(dirs, " ") + "\n"
+	}
+
+	_, err := io.WriteString(output, text)
+	return err
+}
+
+// parseStackIndex разбирает аргумент вида +N или -N в индекс стека размера size,
+// включающего текущую директорию. Если аргумент другого вида, возвращается ok == false.
+func parseStackIndex(name string, arg string, size int) (int, bool, error) {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil || n < 0 {
+		return 0, false, nil
+	}
+	if n >= size {
+		return 0, false, fmt.Errorf("%s: %s: directory stack index out of range", name, arg)
+	}
+	if arg[0] == '-' {
+		n = size - 1 - n
+	}
+	return n, true, nil
+}
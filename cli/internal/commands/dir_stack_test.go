@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"shell/internal/command_meta"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirStack(t *testing.T) {
+	a, b, c := t.TempDir(), t.TempDir(), t.TempDir()
+	sess := newTestSession(t, a)
+
+	run := func(name string, args ...string) string {
+		var output bytes.Buffer
+		meta := command_meta.CommandMeta{Name: name, Args: args}
+		cmd := NewCommandFactory(sess).CommandFromMeta(meta, nil, &output)
+		require.NoError(t, cmd.Execute(context.Background()))
+		return output.String()
+	}
+
+	require.Equal(t, b+" "+a+"\n", run("pushd", b))
+	require.Equal(t, c+" "+b+" "+a+"\n", run("pushd", c))
+	require.Equal(t, c, sess.Dir())
+
+	// Без аргументов pushd меняет местами две верхние директории
+	require.Equal(t, b+" "+c+" "+a+"\n", run("pushd"))
+	require.Equal(t, b, sess.Dir())
+
+	// +N прокручивает стек
+	require.Equal(t, a+" "+b+" "+c+"\n", run("pushd", "+2"))
+	require.Equal(t, a, sess.Dir())
+
+	require.Equal(t, " 0  "+a+"\n 1  "+b+"\n 2  "+c+"\n", run("dirs", "-v"))
+
+	// -N считает справа, удаление не меняет текущую директорию
+	require.Equal(t, a+" "+c+"\n", run("popd", "-1"))
+	require.Equal(t, a, sess.Dir())
+
+	require.Equal(t, c+"\n", run("popd"))
+	require.Equal(t, c, sess.Dir())
+
+	var output bytes.Buffer
+	meta := command_meta.CommandMeta{Name: "popd"}
+	err := PopdCommand{nil, &output, meta, sess}.Execute(context.Background())
+	require.EqualError(t, err, "popd: directory stack empty")
+
+	meta = command_meta.CommandMeta{Name: "pushd", Args: []string{"+5"}}
+	err = PushdCommand{nil, &output, meta, sess}.Execute(context.Background())
+	require.EqualError(t, err, "pushd: +5: directory stack index out of range")
+}
+
+func TestDirsHome(t *testing.T) {
+	home := t.TempDir()
+	sess := newTestSession(t, home)
+	sess.Set("HOME", home)
+
+	var output bytes.Buffer
+	meta := command_meta.CommandMeta{Name: "dirs"}
+	require.NoError(t, DirsCommand{nil, &output, meta, sess}.Execute(context.Background()))
+	require.Equal(t, "~\n", output.String())
+
+	output.Reset()
+	meta = command_meta.CommandMeta{Name: "dirs", Args: []string{"-l"}}
+	require.NoError(t, DirsCommand{nil, &output, meta, sess}.Execute(context.Background()))
+	require.Equal(t, home+"\n", output.String())
+}
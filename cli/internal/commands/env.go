@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"sort"
+	"strings"
+)
+
+// LoadEnv читает файл .env из r и задает прочитанные переменные в сессии.
+// ${ИМЯ} в значениях подставляется из переменных, заданных выше в файле, и из сессии.
+// В ограниченном режиме файл, меняющий PATH, SHELL или ENV, не применяется целиком.
+func LoadEnv(sess *session.Session, r io.Reader) error {
+	vars, err := envsholder.ParseDotenv(r, sess.Get)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	if err := CheckAssignment(sess, names...); err != nil {
+		return err
+	}
+	for _, v := range vars {
+		sess.Set(v.Name, v.Value)
+	}
+	return nil
+}
+
+//////////////////////////////////
+
+// LoadenvCommand задает переменные сессии из файлов .env.
+// Потоками ввода-вывода данная структура не владеет.
+type LoadenvCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = LoadenvCommand{}
+
+// Execute применяет файлы из аргументов по порядку, поэтому следующий файл
+// может ссылаться на переменные предыдущего.
+// Ошибка в файле прерывает команду; переменные из этого файла не задаются.
+func (cmd LoadenvCommand) Execute(ctx context.Context) error {
+	if len(cmd.meta.Args) == 0 {
+		return fmt.Errorf("loadenv: usage: loadenv FILE...")
+	}
+	for _, name := range cmd.meta.Args {
+		if err := cmd.load(name); err != nil {
+			return fmt.Errorf("loadenv: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (cmd LoadenvCommand) load(name string) error {
+	file, err := vfs.Open(cmd.sess.FS(), cmd.sess.Path(name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return LoadEnv(cmd.sess, file)
+}
+
+//////////////////////////////////
+
+// EnvCommand запускает программу с измененным окружением
+// или выводит окружение, если программа не указана.
+// Потоками ввода-вывода данная структура не владеет.
+type EnvCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type envOptions struct {
+	IgnoreEnvironment bool     `short:"i" long:"ignore-environment"`
+	Files             []string `short:"f" long:"file"`
+}
+
+var _ Command = EnvCommand{}
+
+// Execute разбирает аргументы env [-i] [-f FILE]... [ИМЯ=значение]... [COMMAND [ARG]...].
+// Окружение строится из переменных сессии (с -i - из пустого), затем из файлов .env
+// по порядку и присваиваний из аргументов; ${ИМЯ} в файлах подставляется из уже
+// построенного окружения. Переменные сессии не меняются.
+// Программа запускается как внешняя команда (ProcessCommand) и получает только
+// построенное окружение; встроенные команды и алиасы не используются.
+func (cmd EnvCommand) Execute(ctx context.Context) error {
+	flagArgs, rest, err := splitLeadingFlags("env", cmd.meta.Args, "-f", "--file")
+	if err != nil {
+		return err
+	}
+	var opts envOptions
+	if err := arg_parse(&opts, flagArgs); err != nil {
+		return err
+	}
+
+	env := &envsholder.Env{}
+	env.Init()
+	if !opts.IgnoreEnvironment {
+		env = snapshotEnv(cmd.sess)
+	}
+	for _, name := range opts.Files {
+		if err := cmd.loadFile(env, name); err != nil {
+			return fmt.Errorf("env: %s: %v", name, err)
+		}
+	}
+	for len(rest) > 0 {
+		name, value, ok := strings.Cut(rest[0], "=")
+		if !ok || name == "" {
+			break
+		}
+		env.Set(name, value)
+		rest = rest[1:]
+	}
+
+	if len(rest) == 0 {
+		return cmd.printEnv(env)
+	}
+	meta := command_meta.CommandMeta{Name: rest[0], Args: rest[1:], Envs: *env}
+	if err := checkRestricted(cmd.sess, command_meta.CommandMeta{Name: meta.Name}); err != nil {
+		return err
+	}
+	// В ограниченном режиме env не должен запускать программу вместо встроенной команды
+	if cmd.sess.Restricted() && !cmd.sess.ProgramAllowed(meta.Name) {
+		return &RestrictedError{Reason: "command not allowed"}
+	}
+	return ProcessCommand{input: cmd.input, output: cmd.output, meta: meta, sess: cmd.sess, onlyLocalEnvs: true}.Execute(ctx)
+}
+
+func (cmd EnvCommand) loadFile(env *envsholder.Env, name string) error {
+	file, err := vfs.Open(cmd.sess.FS(), cmd.sess.Path(name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	vars, err := envsholder.ParseDotenv(file, func(name string) (string, bool) {
+		value, ok := env.Vars[name]
+		return value, ok
+	})
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		env.Set(v.Name, v.Value)
+	}
+	return nil
+}
+
+// printEnv выводит окружение, отсортированное по имени, в виде ИМЯ=значение
+func (cmd EnvCommand) printEnv(env *envsholder.Env) error {
+	vars := env.Environ()
+	sort.Strings(vars)
+	for _, v := range vars {
+		if _, err := fmt.Fprintln(cmd.output, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDotenv = `# настройки сервиса
+export NAME=world
+GREETING="hello ${NAME}\n"   # комментарий
+RAW='$NAME ${NAME}'
+PLAIN = a b # хвост
+HOME_DIR=$HOME/app
+PORT=${MISSING:-8080}
+MULTI="one
+two"
+`
+
+func TestLoadEnv(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.Set("HOME", "/home/user")
+
+	require.NoError(t, LoadEnv(sess, strings.NewReader(testDotenv)))
+	expected := map[string]string{
+		"NAME":     "world",
+		"GREETING": "hello world\n",
+		"RAW":      "$NAME ${NAME}",
+		"PLAIN":    "a b",
+		"HOME_DIR": "/home/user/app",
+		"PORT":     "8080",
+		"MULTI":    "one\ntwo",
+	}
+	for name, value := range expected {
+		actual, ok := sess.Get(name)
+		require.True(t, ok, name)
+		require.Equal(t, value, actual, name)
+	}
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	sess := newTestSession(t, "")
+	for source, message := range map[string]string{
+		"A=1\n1X=2\n":      "line 2: invalid variable name",
+		"A 1\n":            "line 1: expected A=VALUE",
+		"A=\"x\nB=2\n":     "line 3: unterminated quoted value",
+		"A='x' y\n":        "line 1: unexpected characters after quoted value",
+		"A=${B\n":          "line 1: unterminated ${B",
+		"export\nA=1\n":    "line 1: expected export=VALUE",
+		"A=1\nB=\"${}\"\n": "line 2: invalid variable name in ${...}",
+	} {
+		require.EqualError(t, LoadEnv(sess, strings.NewReader(source)), message, source)
+	}
+	_, ok := sess.Get("A")
+	require.False(t, ok)
+
+	sess.Restrict(nil)
+	var restricted *RestrictedError
+	require.ErrorAs(t, LoadEnv(sess, strings.NewReader("A=1\nPATH=/tmp\n")), &restricted)
+	_, ok = sess.Get("A")
+	require.False(t, ok)
+}
+
+func TestLoadenvCommand(t *testing.T) {
+	sess := newMemSession(t, map[string]string{"/first.env": "A=1\n", "/second.env": "B=${A}2\n"})
+	meta := command_meta.CommandMeta{Name: "loadenv", Args: []string{"first.env", "second.env"}}
+	require.NoError(t, LoadenvCommand{nil, nil, meta, sess}.Execute(context.Background()))
+	value, _ := sess.Get("B")
+	require.Equal(t, "12", value)
+
+	meta.Args = nil
+	require.Error(t, LoadenvCommand{nil, nil, meta, sess}.Execute(context.Background()))
+	meta.Args = []string{"missing.env"}
+	require.ErrorContains(t, LoadenvCommand{nil, nil, meta, sess}.Execute(context.Background()), "loadenv: missing.env:")
+}
+
+func TestEnvCommand(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("A=1\nB=${A}${S}\n"), 0644))
+	sess := newTestSession(t, dir)
+	sess.Set("S", "session")
+
+	output, err := runBuiltin(sess, "env", "-i", "-f", ".env", "C=3")
+	require.NoError(t, err)
+	require.Equal(t, "A=1\nB=1\nC=3\n", output)
+
+	output, err = runBuiltin(sess, "env", "-f", ".env")
+	require.NoError(t, err)
+	require.Contains(t, output, "B=1session\n")
+	require.Contains(t, output, "S=session\n")
+
+	// Программа получает только построенное окружение
+	envPath, err := lookPath(sess, "env", false)
+	if err != nil {
+		t.Skip("env program not found")
+	}
+	output, err = runBuiltin(sess, "env", "-i", "-f", ".env", envPath)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"A=1", "B=1"}, strings.Fields(output))
+
+	_, ok := sess.Get("A")
+	require.False(t, ok)
+}
+
+func TestEnvRestricted(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.Restrict([]string{"true"})
+
+	_, err := runBuiltin(sess, "env", "-i", "ls")
+	var restricted *RestrictedError
+	require.ErrorAs(t, err, &restricted)
+	_, err = runBuiltin(sess, "env", "/bin/true")
+	require.ErrorAs(t, err, &restricted)
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"strings"
+)
+
+// Запись восьмеричных кодов в escape-последовательностях
+type octalEscapes int
+
+const (
+	octalPlain    octalEscapes = iota // \NNN, как в формате printf
+	octalWithZero                     // \0NNN, как в echo -e
+	octalAny                          // \0NNN и \NNN, как в аргументах %b
+)
+
+// expand_escapes раскрывает escape-последовательности в стиле echo -e и printf:
+// \a \b \e \f \n \r \t \v \\, \xHH и восьмеричные коды, записанные так, как задает octal.
+// Последовательность \c прекращает вывод, о чем сообщает второе возвращаемое значение.
+func expand_escapes(s string, octal octalEscapes) (string, bool) {
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			result.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch c := s[i]; c {
+		case 'a':
+			result.WriteByte('\a')
+		case 'b':
+			result.WriteByte('\b')
+		case 'e', 'E':
+			result.WriteByte(0x1b)
+		case 'f':
+			result.WriteByte('\f')
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 't':
+			result.WriteByte('\t')
+		case 'v':
+			result.WriteByte('\v')
+		case '\\':
+			result.WriteByte('\\')
+		case 'c':
+			return result.String(), true
+		case 'x':
+			value, n := parse_digits(s[i+1:], 16, 2)
+			if n == 0 {
+				result.WriteString(`\x`)
+				continue
+			}
+			result.WriteByte(byte(value))
+			i += n
+		default:
+			if c < '0' || c > '7' || (octal == octalWithZero && c != '0') {
+				result.WriteByte('\\')
+				result.WriteByte(c)
+				continue
+			}
+			// В записи \0NNN ведущий ноль не входит в код
+			digits := s[i+1:]
+			if c != '0' || octal == octalPlain {
+				digits = s[i:]
+				i--
+			}
+			value, n := parse_digits(digits, 8, 3)
+			result.WriteByte(byte(value))
+			i += n
+		}
+	}
+	return result.String(), false
+}
+
+// parse_digits разбирает не более limit цифр в указанной системе счисления
+// с начала строки. Возвращает значение и количество разобранных символов.
+func parse_digits(s string, base int, limit int) (int, int) {
+	value, n := 0, 0
+	for n < len(s) && n < limit {
+		digit := strings.IndexByte("0123456789abcdef", lower_byte(s[n]))
+		if digit < 0 || digit >= base {
+			break
+		}
+		value = value*base + digit
+		n++
+	}
+	return value, n
+}
+
+func lower_byte(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,369 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/pkg/vfs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FindCommand обходит дерево директорий и выводит (или обрабатывает)
+// файлы, удовлетворяющие выражению.
+// Потоками ввода-вывода данная структура не владеет.
+type FindCommand struct {
+	input   io.Reader
+	output  io.Writer
+	meta    command_meta.CommandMeta
+	factory *CommandFactory
+}
+
+// Предикат выражения find. Возвращает true, если файл удовлетворяет условию
+type findPredicate func(path string, info fs.FileInfo) (bool, error)
+
+// Разобранные аргументы команды find
+type findExpression struct {
+	roots      []string
+	maxDepth   int
+	predicates []findPredicate
+	// Было ли в выражении явное действие (-print, -print0, -exec).
+	// Если нет, то подходящие файлы печатаются по умолчанию.
+	hasAction bool
+	// Команду -exec не удалось выполнить хотя бы для одного файла
+	execFailed bool
+}
+
+var _ Command = FindCommand{}
+
+// Execute выводит пути файлов из переданных директорий (по умолчанию текущей),
+// для которых выполнены все условия выражения.
+// Поддерживаются -name, -iname, -type, -maxdepth, -mtime, -size, -newer, !,
+// а также действия -print, -print0 и -exec cmd {} \;
+func (cmd FindCommand) Execute(ctx context.Context) error {
+	expr, err := cmd.parseExpression(ctx, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	for _, root := range expr.roots {
+		// Обходим абсолютный путь относительно директории сессии,
+		// а выводим пути в том виде, в котором был задан корень
+		absRoot := cmd.factory.sess.Path(root)
+		err := vfs.WalkDir(cmd.factory.sess.FS(), absRoot, func(absPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			info, err := cmd.factory.sess.FS().Lstat(absPath)
+			if err != nil {
+				return err
+			}
+			path := findPath(root, absRoot, absPath)
+
+			matched := true
+			for _, predicate := range expr.predicates {
+				ok, err := predicate(path, info)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					matched = false
+					break
+				}
+			}
+
+			if matched && !expr.hasAction {
+				if _, err := io.WriteString(cmd.output, path+"\n"); err != nil {
+					return err
+				}
+			}
+			// Глубже -maxdepth не спускаемся
+			if expr.maxDepth >= 0 && entry.IsDir() && findDepth(absRoot, absPath) >= expr.maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("find: %v", err)
+		}
+	}
+	if expr.execFailed {
+		return ExitStatusError{Status: 1}
+	}
+	return nil
+}
+
+// findPath возвращает путь файла absPath в том виде, в котором его выводит find:
+// корень обхода, как он был задан, и путь относительно корня через разделитель.
+// Разделитель не дублируется, если корень им заканчивается, как у /.
+func findPath(root string, absRoot string, absPath string) string {
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == "." {
+		return root
+	}
+	if strings.HasSuffix(root, string(filepath.Separator)) {
+		return root + rel
+	}
+	return root + string(filepath.Separator) + rel
+}
+
+// findDepth возвращает глубину пути относительно корня обхода
+func findDepth(root string, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// parseExpression разбирает аргументы find: сначала идут корни обхода,
+// затем выражение из предикатов, которые объединяются логическим И
+func (cmd FindCommand) parseExpression(ctx context.Context, args []string) (*findExpression, error) {
+	expr := &findExpression{maxDepth: -1}
+
+	i := 0
+	for ; i < len(args) && !strings.HasPrefix(args[i], "-") && args[i] != "!"; i++ {
+		expr.roots = append(expr.roots, args[i])
+	}
+	if len(expr.roots) == 0 {
+		expr.roots = []string{"."}
+	}
+
+	negate := false
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "!" || arg == "-not" {
+			negate = !negate
+			continue
+		}
+
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("find: missing argument to `%s'", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var predicate findPredicate
+		switch arg {
+		case "-name", "-iname":
+			pattern, err := value()
+			if err != nil {
+				return nil, err
+			}
+			predicate = findNamePredicate(pattern, arg == "-iname")
+		case "-type":
+			fileType, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if predicate, err = findTypePredicate(fileType); err != nil {
+				return nil, err
+			}
+		case "-maxdepth":
+			depth, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if expr.maxDepth, err = strconv.Atoi(depth); err != nil || expr.maxDepth < 0 {
+				return nil, fmt.Errorf("find: invalid argument `%s' to `-maxdepth'", depth)
+			}
+			continue
+		case "-mtime":
+			days, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if predicate, err = findMtimePredicate(days); err != nil {
+				return nil, err
+			}
+		case "-size":
+			size, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if predicate, err = findSizePredicate(size); err != nil {
+				return nil, err
+			}
+		case "-newer":
+			reference, err := value()
+			if err != nil {
+				return nil, err
+			}
+			info, err := cmd.factory.sess.FS().Stat(cmd.factory.sess.Path(reference))
+			if err != nil {
+				return nil, fmt.Errorf("find: %v", err)
+			}
+			modTime := info.ModTime()
+			predicate = func(_ string, info fs.FileInfo) (bool, error) {
+				return info.ModTime().After(modTime), nil
+			}
+		case "-print", "-print0":
+			terminator := "\n"
+			if arg == "-print0" {
+				terminator = "\x00"
+			}
+			expr.hasAction = true
+			predicate = func(path string, _ fs.FileInfo) (bool, error) {
+				_, err := io.WriteString(cmd.output, path+terminator)
+				return true, err
+			}
+		case "-exec":
+			end := i + 1
+			for end < len(args) && args[end] != ";" {
+				end++
+			}
+			if end == len(args) || end == i+1 {
+				return nil, fmt.Errorf("find: missing argument to `-exec'")
+			}
+			template := args[i+1 : end]
+			i = end
+			expr.hasAction = true
+			predicate = cmd.execPredicate(ctx, expr, template)
+		default:
+			return nil, fmt.Errorf("find: unknown predicate `%s'", arg)
+		}
+
+		if negate {
+			inner := predicate
+			predicate = func(path string, info fs.FileInfo) (bool, error) {
+				ok, err := inner(path, info)
+				return !ok, err
+			}
+			negate = false
+		}
+		expr.predicates = append(expr.predicates, predicate)
+	}
+
+	return expr, nil
+}
+
+func findNamePredicate(pattern string, caseInsensitive bool) findPredicate {
+	if caseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	return func(path string, _ fs.FileInfo) (bool, error) {
+		name := filepath.Base(path)
+		if caseInsensitive {
+			name = strings.ToLower(name)
+		}
+		return filepath.Match(pattern, name)
+	}
+}
+
+func findTypePredicate(fileType string) (findPredicate, error) {
+	var check func(fs.FileMode) bool
+	switch fileType {
+	case "f":
+		check = fs.FileMode.IsRegular
+	case "d":
+		check = fs.FileMode.IsDir
+	case "l":
+		check = func(mode fs.FileMode) bool { return mode&fs.ModeSymlink != 0 }
+	case "p":
+		check = func(mode fs.FileMode) bool { return mode&fs.ModeNamedPipe != 0 }
+	case "s":
+		check = func(mode fs.FileMode) bool { return mode&fs.ModeSocket != 0 }
+	default:
+		return nil, fmt.Errorf("find: unknown argument to -type: %s", fileType)
+	}
+	return func(_ string, info fs.FileInfo) (bool, error) {
+		return check(info.Mode()), nil
+	}, nil
+}
+
+// parseFindNumber разбирает числовой аргумент вида +N, -N или N.
+// Возвращает функцию сравнения с этим числом.
+func parseFindNumber(arg string) (int64, func(value int64, n int64) bool, error) {
+	compare := func(value int64, n int64) bool { return value == n }
+	switch {
+	case strings.HasPrefix(arg, "+"):
+		compare = func(value int64, n int64) bool { return value > n }
+		arg = arg[1:]
+	case strings.HasPrefix(arg, "-"):
+		compare = func(value int64, n int64) bool { return value < n }
+		arg = arg[1:]
+	}
+
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n < 0 {
+		return 0, nil, fmt.Errorf("find: invalid number `%s'", arg)
+	}
+	return n, compare, nil
+}
+
+// findMtimePredicate сравнивает время последнего изменения файла в сутках
+func findMtimePredicate(days string) (findPredicate, error) {
+	n, compare, err := parseFindNumber(days)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return func(_ string, info fs.FileInfo) (bool, error) {
+		age := int64(now.Sub(info.ModTime()) / (24 * time.Hour))
+		return compare(age, n), nil
+	}, nil
+}
+
+// findSizePredicate сравнивает размер файла в единицах, указанных суффиксом:
+// c - байты, k - килобайты, M - мегабайты, G - гигабайты,
+// без суффикса - блоки по 512 байт. Размер округляется вверх.
+func findSizePredicate(size string) (findPredicate, error) {
+	unit := int64(512)
+	switch {
+	case strings.HasSuffix(size, "c"):
+		unit = 1
+	case strings.HasSuffix(size, "k"):
+		unit = 1024
+	case strings.HasSuffix(size, "M"):
+		unit = 1024 * 1024
+	case strings.HasSuffix(size, "G"):
+		unit = 1024 * 1024 * 1024
+	}
+	if unit != 512 {
+		size = size[:len(size)-1]
+	}
+
+	n, compare, err := parseFindNumber(size)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ string, info fs.FileInfo) (bool, error) {
+		units := int64(math.Ceil(float64(info.Size()) / float64(unit)))
+		return compare(units, n), nil
+	}, nil
+}
+
+// execPredicate запускает команду для каждого найденного файла,
+// подставляя путь файла вместо {}. Условие выполнено, если команда завершилась успешно.
+// Ненулевой код возврата только делает условие ложным. Об ошибке команды,
+// которая не сводится к коду возврата (например, команда не найдена),
+// сообщается в stderr, и find завершается с кодом 1.
+func (cmd FindCommand) execPredicate(ctx context.Context, expr *findExpression, template []string) findPredicate {
+	return func(path string, _ fs.FileInfo) (bool, error) {
+		args := make([]string, len(template)-1)
+		for i, arg := range template[1:] {
+			args[i] = strings.ReplaceAll(arg, "{}", path)
+		}
+		meta := command_meta.CommandMeta{Name: strings.ReplaceAll(template[0], "{}", path), Args: args}
+		err := cmd.factory.ChildCommand(meta, cmd.input, cmd.output).Execute(ctx)
+		var exitErr *exec.ExitError
+		if err != nil && !IsSilent(err) && !errors.As(err, &exitErr) && ctx.Err() == nil {
+			fmt.Fprintf(cmd.factory.Session().Stderr, "find: %s: %v\n", meta.Name, err)
+			expr.execFailed = true
+		}
+		return err == nil, nil
+	}
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/pkg/vfs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	baseDir := t.TempDir()
+	err := make_inner_files(t, vfs.OS, baseDir, map[string]any{
+		"a.txt": true,
+		"B.TXT": true,
+		"c.go":  true,
+		"dir": map[string]any{
+			"d.txt": true,
+			"inner": map[string]any{
+				"e.txt": true,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "big"), make([]byte, 2048), 0644))
+
+	old := time.Now().Add(-72 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(baseDir, "c.go"), old, old))
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"name", []string{"-name", "*.txt"}, []string{"a.txt", "dir/d.txt", "dir/inner/e.txt"}},
+		{"iname", []string{"-iname", "b.txt"}, []string{"B.TXT"}},
+		{"type", []string{"-type", "d"}, []string{"", "dir", "dir/inner"}},
+		{"maxdepth", []string{"-maxdepth", "1", "-name", "*.txt"}, []string{"a.txt"}},
+		{"negation", []string{"-type", "f", "!", "-name", "*.txt"}, []string{"B.TXT", "c.go", "big"}},
+		{"mtime", []string{"-type", "f", "-mtime", "+1"}, []string{"c.go"}},
+		{"size", []string{"-type", "f", "-size", "+1k"}, []string{"big"}},
+		{"newer", []string{"-type", "f", "-newer", filepath.Join(baseDir, "c.go"), "-name", "*.go"}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "find", Args: append([]string{baseDir}, tc.args...)}
+			actual := runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+				return FindCommand{in, out, meta, NewCommandFactory(newTestSession(t, ""))}
+			})
+
+			var paths []string
+			for _, line := range strings.Split(strings.TrimSpace(actual), "\n") {
+				if line != "" {
+					rel, err := filepath.Rel(baseDir, line)
+					require.NoError(t, err)
+					paths = append(paths, strings.TrimPrefix(filepath.ToSlash(rel), "."))
+				}
+			}
+			require.ElementsMatch(t, tc.expected, paths)
+		})
+	}
+}
+
+func TestFindPrint0AndExec(t *testing.T) {
+	baseDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "file"), []byte("content\n"), 0644))
+
+	meta := command_meta.CommandMeta{Name: "find", Args: []string{baseDir, "-type", "f", "-print0"}}
+	actual := runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+		return FindCommand{in, out, meta, NewCommandFactory(newTestSession(t, ""))}
+	})
+	require.Equal(t, filepath.Join(baseDir, "file")+"\x00", actual)
+
+	meta = command_meta.CommandMeta{Name: "find", Args: []string{baseDir, "-type", "f", "-exec", "cat", "{}", ";"}}
+	actual = runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+		return FindCommand{in, out, meta, NewCommandFactory(newTestSession(t, ""))}
+	})
+	require.Equal(t, "content\n", actual)
+}
+
+func TestFindPath(t *testing.T) {
+	cases := []struct {
+		root     string
+		absRoot  string
+		absPath  string
+		expected string
+	}{
+		{"/", "/", "/", "/"},
+		{"/", "/", "/foo", "/foo"},
+		{"/", "/", "/foo/bar", "/foo/bar"},
+		{".", "/home", "/home/foo", "./foo"},
+		{"dir/", "/home/dir", "/home/dir/foo", "dir/foo"},
+		{"dir//", "/home/dir", "/home/dir/foo", "dir//foo"},
+		{"dir", "/home/dir", "/home/dir", "dir"},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.expected, findPath(tc.root, tc.absRoot, tc.absPath), tc)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	sess := newMemSession(t, map[string]string{"/foo": ""})
+	require.NoError(t, sess.FS().Mkdir("/dir", 0755))
+	require.NoError(t, vfs.WriteFile(sess.FS(), "/dir/bar", nil, 0644))
+	meta := command_meta.CommandMeta{Name: "find", Args: []string{"/", "-type", "f"}}
+	actual := runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+		return FindCommand{in, out, meta, NewCommandFactory(sess)}
+	})
+	require.ElementsMatch(t, []string{"/foo", "/dir/bar"}, strings.Fields(actual))
+}
+
+func TestFindExecError(t *testing.T) {
+	sess := newMemSession(t, map[string]string{"/a": "", "/b": ""})
+	var stderr bytes.Buffer
+	sess.Stderr = &stderr
+
+	var output bytes.Buffer
+	meta := command_meta.CommandMeta{Name: "find", Args: []string{"/", "-type", "f", "-exec", "no-such-command", "{}", ";", "-print"}}
+	err := FindCommand{strings.NewReader(""), &output, meta, NewCommandFactory(sess)}.Execute(context.Background())
+	require.Equal(t, 1, ExitStatus(err))
+	require.True(t, IsSilent(err))
+	require.Empty(t, output.String())
+	require.Equal(t, 2, strings.Count(stderr.String(), "find: "), stderr.String())
+	require.Contains(t, stderr.String(), "no-such-command")
+
+	// Ненулевой код возврата команды - это просто ложное условие
+	stderr.Reset()
+	meta.Args = []string{"/", "-type", "f", "-exec", "test", "{}", "=", "/a", ";", "-print"}
+	err = FindCommand{strings.NewReader(""), &output, meta, NewCommandFactory(sess)}.Execute(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "/a\n", output.String())
+	require.Empty(t, stderr.String())
+}
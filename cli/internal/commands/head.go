@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+)
+
+// HeadCommand выводит первые строки файла или входного потока.
+// Потоками ввода-вывода данная структура не владеет.
+type HeadCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type headOptions struct {
+	Lines int `short:"n" default:"10"`
+
+	Positional struct {
+		Filename string
+	} `positional-args:"true" maximum:"1"`
+}
+
+var _ Command = HeadCommand{}
+
+// Execute выводит первые -n строк (по умолчанию 10).
+// Если имя файла не передано, строки читаются из дескриптора input.
+func (cmd HeadCommand) Execute(ctx context.Context) error {
+	var opts headOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	in, closeInput, err := open_input(cmd.sess, opts.Positional.Filename, cmd.input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	reader := bufio.NewReader(in)
+	for i := 0; i < opts.Lines; i++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 {
+			if _, err := cmd.output.Write(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
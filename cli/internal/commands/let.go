@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/parser"
+	"shell/internal/session"
+)
+
+// LetCommand вычисляет арифметические выражения.
+// Потоками ввода-вывода данная структура не владеет.
+type LetCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = LetCommand{}
+
+// Execute вычисляет каждый аргумент как арифметическое выражение.
+// Присваивания в выражениях изменяют переменные сессии;
+// в ограниченном режиме PATH, SHELL и ENV изменять нельзя.
+// Команда завершается с кодом 1, если значение последнего выражения равно нулю.
+func (cmd LetCommand) Execute(ctx context.Context) error {
+	if len(cmd.meta.Args) == 0 {
+		return fmt.Errorf("let: expression expected")
+	}
+
+	// Режим определяется до блокировки переменных, которую удерживает вычисление
+	restricted := cmd.sess.Restricted()
+
+	var result int64
+	var err error
+	cmd.sess.WithEnv(func(env *envsholder.Env) {
+		set := func(name string, value string) error {
+			if restricted {
+				if err := checkRestrictedVariables(name); err != nil {
+					return err
+				}
+			}
+			env.Set(name, value)
+			return nil
+		}
+		for _, expr := range cmd.meta.Args {
+			result, err = parser.EvalArithmetic(expr, env, set)
+			if err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("let: %w", err)
+	}
+
+	if result == 0 {
+		return ExitStatusError{Status: 1}
+	}
+	return nil
+}
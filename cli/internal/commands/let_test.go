@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"context"
+	"shell/internal/command_meta"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLet(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.Set("counter", "1")
+
+	meta := command_meta.CommandMeta{Name: "let", Args: []string{"counter += 1", "counter *= 10"}}
+	require.NoError(t, LetCommand{nil, nil, meta, sess}.Execute(context.Background()))
+	counter, _ := sess.Get("counter")
+	require.Equal(t, "20", counter)
+
+	meta = command_meta.CommandMeta{Name: "let", Args: []string{"counter - 20"}}
+	err := LetCommand{nil, nil, meta, sess}.Execute(context.Background())
+	require.Equal(t, 1, ExitStatus(err))
+	require.True(t, IsSilent(err))
+
+	meta = command_meta.CommandMeta{Name: "let", Args: []string{"counter / 0"}}
+	err = LetCommand{nil, nil, meta, sess}.Execute(context.Background())
+	require.Error(t, err)
+	require.False(t, IsSilent(err))
+}
@@ -1,18 +1,23 @@
 package commands
 
 import (
+	"io"
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
 	"shell/internal/command_meta"
+	"shell/internal/session"
+	"time"
 )
 
 // ListDirCommand выводит файлы и директории в директории-аргументе
 // если переданной директории не существует, то будет возвращена ошибка.
-// Дескрипторами файлов данная структура не владеет.
+// Потоками ввода-вывода данная структура не владеет.
 type ListDirCommand struct {
-	output *os.File
+	output io.Writer
 	meta   command_meta.CommandMeta
+	sess   *session.Session
 }
 
 type listDirOptions struct {
@@ -24,35 +29,28 @@ type listDirOptions struct {
 var _ Command = ListDirCommand{}
 
 // Execute implements Command.
-func (cmd ListDirCommand) Execute() error {
+func (cmd ListDirCommand) Execute(ctx context.Context) error {
 	var opts listDirOptions
 	err := arg_parse(&opts, cmd.meta.Args)
 	if err != nil {
 		return err
 	}
 
-	// Если нам не передали path, то используем текущую директорию
-	path := opts.Positional.Path
-	if path == "" {
-		path, err = os.Getwd()
-		if err != nil {
-			return fmt.Errorf("unable to determine current directory: %v", err)
-
-		}
-	}
+	// Если нам не передали path, то используем текущую директорию сессии
+	path := cmd.sess.Path(opts.Positional.Path)
 
-	fileInfo, err := os.Lstat(path)
+	fileInfo, err := cmd.sess.FS().Lstat(path)
 	if err != nil {
 		return fmt.Errorf("unable to read file info (%s): %v", path, err)
 	}
 
 	switch mode := fileInfo.Mode(); {
 	case mode.IsRegular():
-		if _, err := cmd.output.WriteString(reportEntry(fileInfo)); err != nil {
+		if err := cmd.writeEntry(fileInfo); err != nil {
 			return err
 		}
 	case mode.IsDir():
-		entries, err := os.ReadDir(path)
+		entries, err := cmd.sess.FS().ReadDir(path)
 		if err != nil {
 			return fmt.Errorf("unable to read given directory (%s): %v", path, err)
 		}
@@ -63,7 +61,7 @@ func (cmd ListDirCommand) Execute() error {
 				return fmt.Errorf("unable to read file info (%s): %v", path, err)
 			}
 
-			if _, err := cmd.output.WriteString(reportEntry(fileInfo)); err != nil {
+			if err := cmd.writeEntry(fileInfo); err != nil {
 				return err
 			}
 		}
@@ -72,6 +70,34 @@ func (cmd ListDirCommand) Execute() error {
 	return nil
 }
 
+// writeEntry выводит сведения о файле строкой или, если выход принимает записи,
+// записью с полями name, type, size, mode и modified
+func (cmd ListDirCommand) writeEntry(fileInfo fs.FileInfo) error {
+	writer, ok := cmd.output.(RecordWriter)
+	if !ok {
+		_, err := io.WriteString(cmd.output, reportEntry(fileInfo))
+		return err
+	}
+
+	entryType := "file"
+	switch mode := fileInfo.Mode(); {
+	case mode.IsDir():
+		entryType = "dir"
+	case mode&fs.ModeSymlink != 0:
+		entryType = "symlink"
+	case !mode.IsRegular():
+		entryType = "other"
+	}
+
+	record := NewRecord()
+	record.Set("name", fileInfo.Name())
+	record.Set("type", entryType)
+	record.Set("size", fileInfo.Size())
+	record.Set("mode", permissionString(fileInfo.Mode()))
+	record.Set("modified", fileInfo.ModTime().Format(time.RFC3339))
+	return writer.WriteRecord(record)
+}
+
 func reportEntry(fileInfo fs.FileInfo) string {
 	return fmt.Sprintf("%s %s\n", permissionString(fileInfo.Mode()), fileInfo.Name())
 }
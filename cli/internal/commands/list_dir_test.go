@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
 	"shell/internal/command_meta"
+	"shell/pkg/vfs"
 	"strings"
 	"testing"
 
@@ -12,24 +14,24 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func make_inner_files(t *testing.T, baseDir string, structure map[string]any) error {
+func make_inner_files(t *testing.T, fsys vfs.FS, baseDir string, structure map[string]any) error {
 	for entryName, v := range structure {
 		entryFullPath := filepath.Join(baseDir, entryName)
 		switch value := v.(type) {
 		case bool:
 			// file
-			_, err := os.Create(entryFullPath)
+			err := vfs.WriteFile(fsys, entryFullPath, nil, 0666)
 			if err != nil {
 				return err
 			}
 		case map[string]any:
 			// directory
-			err := os.Mkdir(entryFullPath, 0777)
+			err := fsys.Mkdir(entryFullPath, 0777)
 			if err != nil {
 				return err
 			}
 
-			err = make_inner_files(t, entryFullPath, value)
+			err = make_inner_files(t, fsys, entryFullPath, value)
 			if err != nil {
 				return err
 			}
@@ -42,9 +44,10 @@ func make_inner_files(t *testing.T, baseDir string, structure map[string]any) er
 }
 
 func TestListDir(t *testing.T) {
-	baseDir, err := os.MkdirTemp("", "test_ls")
-	require.NoError(t, err, "Could not create temp dir")
-	defer os.RemoveAll(baseDir)
+	// Дерево файлов создается в памяти, а не на диске
+	fsys := vfs.NewMem()
+	baseDir := "/test_ls"
+	require.NoError(t, fsys.Mkdir(baseDir, 0777))
 
 	treeStructure := map[string]any{
 		"file1": true,
@@ -55,9 +58,8 @@ func TestListDir(t *testing.T) {
 			"dd":  map[string]any{},
 		},
 	}
-	err = make_inner_files(t, baseDir, treeStructure)
+	err := make_inner_files(t, fsys, baseDir, treeStructure)
 	require.NoError(t, err)
-	require.NoError(t, os.Chdir(baseDir))
 
 	cases := []struct {
 		name           string
@@ -106,8 +108,10 @@ func TestListDir(t *testing.T) {
 			}
 			defer rp.Close()
 
-			cmd := ListDirCommand{wp, meta}
-			err = cmd.Execute()
+			sess := newTestSession(t, baseDir)
+			sess.SetFS(fsys)
+			cmd := ListDirCommand{wp, meta, sess}
+			err = cmd.Execute(context.Background())
 			wp.Close()
 
 			if tc.expectErr {
@@ -0,0 +1,488 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"sort"
+	"strings"
+)
+
+// Код возврата команды, которая не найдена
+const NotFoundStatus = 127
+
+// Код возврата команды, файл которой найден, но не может быть исполнен
+const NotExecutableStatus = 126
+
+// Имена встроенных команд в алфавитном порядке
+var builtinNames = func() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}()
+
+// IsBuiltin сообщает, что name - имя встроенной команды
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+// IsKnownCommand сообщает, что name - алиас, встроенная команда или программа,
+// которую можно запустить. В ограниченном режиме команды, которые исполнитель
+// откажется запускать (не из списка разрешенных, с / в имени, cd), неизвестны.
+// В отличие от запуска команды, проверка не меняет таблицу программ сессии.
+func IsKnownCommand(sess *session.Session, name string) bool {
+	if _, ok := sess.Alias(name); ok {
+		return true
+	}
+	if checkRestricted(sess, command_meta.CommandMeta{Name: name}) != nil {
+		return false
+	}
+	if IsBuiltin(name) {
+		return true
+	}
+	if strings.Contains(name, "/") {
+		return isExecutable(sess.Path(name))
+	}
+	if path, ok := sess.Hashed(name, false); ok && isExecutable(path) {
+		return true
+	}
+	return name != "" && len(lookPathAll(sess, name)) > 0
+}
+
+// Ошибка запуска команды, которая не найдена ни среди встроенных, ни в PATH
+type CommandNotFoundError struct {
+	Name string
+	// Имя содержит путь, и файл по этому пути не найден
+	IsPath bool
+	// Похожие имена встроенных команд, алиасов и программ
+	Suggestions []string
+}
+
+func (e *CommandNotFoundError) Error() string {
+	if e.IsPath {
+		return "No such file or directory"
+	}
+	if len(e.Suggestions) == 0 {
+		return "command not found"
+	}
+	return fmt.Sprintf("command not found, did you mean: %s?", strings.Join(e.Suggestions, ", "))
+}
+
+// Ошибка запуска команды по пути к файлу, который существует, но не исполняемый
+type CommandNotExecutableError struct {
+	Name string
+	// Путь ведет к директории
+	IsDir bool
+}
+
+func (e *CommandNotExecutableError) Error() string {
+	if e.IsDir {
+		return "Is a directory"
+	}
+	return "Permission denied"
+}
+
+// isExecutable сообщает, что path - исполняемый файл
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// lookPath находит программу name: имя, содержащее /, разрешается относительно
+// директории сессии (если файл есть, но не исполняемый, возвращается
+// CommandNotExecutableError), остальные имена ищутся сначала в таблице сессии, затем в PATH.
+// Найденная в PATH программа запоминается в таблице.
+// Если hit установлен, поиск засчитывается как запуск программы.
+func lookPath(sess *session.Session, name string, hit bool) (string, error) {
+	if strings.Contains(name, "/") {
+		path := sess.Path(name)
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			return "", &CommandNotFoundError{Name: name, IsPath: true}
+		case !isExecutable(path):
+			return "", &CommandNotExecutableError{Name: name, IsDir: info.IsDir()}
+		}
+		return path, nil
+	}
+
+	if path, ok := sess.Hashed(name, hit); ok {
+		if isExecutable(path) {
+			return path, nil
+		}
+		// Программа удалена или перемещена после того, как была найдена
+		sess.Unhash(name)
+	}
+
+	if name != "" {
+		for _, dir := range filepath.SplitList(sess.SearchPath()) {
+			if dir == "" {
+				dir = "."
+			}
+			path := filepath.Join(sess.Path(dir), name)
+			if isExecutable(path) {
+				sess.Hash(name, path)
+				sess.Hashed(name, hit)
+				return path, nil
+			}
+		}
+	}
+	return "", &CommandNotFoundError{Name: name}
+}
+
+// lookPathAll находит все программы name в директориях PATH
+func lookPathAll(sess *session.Session, name string) []string {
+	var paths []string
+	for _, dir := range filepath.SplitList(sess.SearchPath()) {
+		if dir == "" {
+			dir = "."
+		}
+		path := filepath.Join(sess.Path(dir), name)
+		if isExecutable(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Не более чем столько похожих команд предлагается вместо ненайденной
+const maxSuggestions = 3
+
+// suggestCommands возвращает имена встроенных команд, алиасов и программ из PATH,
+// близкие к name по редакционному расстоянию, начиная с самых близких
+func suggestCommands(sess *session.Session, name string) []string {
+	maxDistance := min(max(len(name)/3, 1), 2)
+
+	distances := map[string]int{}
+	consider := func(candidate string) {
+		if _, ok := distances[candidate]; ok || candidate == name {
+			return
+		}
+		if d := editDistance(name, candidate); d <= maxDistance {
+			distances[candidate] = d
+		}
+	}
+
+	for _, candidate := range builtinNames {
+		consider(candidate)
+	}
+	for _, candidate := range sess.Aliases() {
+		consider(candidate)
+	}
+	for _, dir := range filepath.SplitList(sess.SearchPath()) {
+		entries, err := os.ReadDir(sess.Path(dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			// Проверка прав дорогая, поэтому выполняется только для близких имен
+			if _, ok := distances[entry.Name()]; ok || editDistance(name, entry.Name()) > maxDistance {
+				continue
+			}
+			if isExecutable(filepath.Join(sess.Path(dir), entry.Name())) {
+				consider(entry.Name())
+			}
+		}
+	}
+
+	suggestions := make([]string, 0, len(distances))
+	for candidate := range distances {
+		suggestions = append(suggestions, candidate)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if distances[a] != distances[b] {
+			return distances[a] < distances[b]
+		}
+		return a < b
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// editDistance вычисляет расстояние Дамерау-Левенштейна (вариант с оптимальным выравниванием):
+// число вставок, удалений, замен символов и перестановок соседних символов
+func editDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+//////////////////////////////////
+
+// HashCommand управляет таблицей найденных в PATH программ.
+// Потоками ввода-вывода данная структура не владеет.
+type HashCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type hashOptions struct {
+	Reset  bool `short:"r"`
+	Delete bool `short:"d"`
+	Paths  bool `short:"t"`
+
+	Positional struct {
+		Names []string
+	} `positional-args:"true"`
+}
+
+var _ Command = HashCommand{}
+
+// Execute без аргументов выводит таблицу с числом запусков каждой программы.
+// С флагом -r очищает таблицу, с -d удаляет из нее переданные имена,
+// с -t выводит пути к переданным программам, иначе ищет программы и добавляет их в таблицу.
+func (cmd HashCommand) Execute(ctx context.Context) error {
+	var options hashOptions
+	if err := arg_parse(&options, cmd.meta.Args); err != nil {
+		return err
+	}
+	names := options.Positional.Names
+
+	if options.Reset {
+		cmd.sess.ResetHashes()
+	}
+	if len(names) == 0 {
+		if options.Reset {
+			return nil
+		}
+		return cmd.printTable()
+	}
+
+	var failed []string
+	for _, name := range names {
+		switch {
+		case options.Delete:
+			if !cmd.sess.Unhash(name) {
+				failed = append(failed, name)
+			}
+		case options.Paths:
+			path, err := lookPath(cmd.sess, name, false)
+			if err != nil {
+				failed = append(failed, name)
+				continue
+			}
+			if _, err := fmt.Fprintln(cmd.output, path); err != nil {
+				return err
+			}
+		case IsBuiltin(name):
+		default:
+			if _, err := lookPath(cmd.sess, name, false); err != nil {
+				failed = append(failed, name)
+			}
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("hash: %s: not found", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// printTable выводит таблицу найденных программ
+func (cmd HashCommand) printTable() error {
+	entries := cmd.sess.Hashes()
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(cmd.output, "hash: hash table empty")
+		return err
+	}
+
+	if _, err := fmt.Fprintln(cmd.output, "hits\tcommand"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(cmd.output, "%4d\t%s\n", entry.Hits, entry.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//////////////////////////////////
+
+// WhichCommand выводит, чем будет исполнено каждое из переданных имен.
+// Потоками ввода-вывода данная структура не владеет.
+type WhichCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type whichOptions struct {
+	All bool `short:"a"`
+
+	Positional struct {
+		Names []string
+	} `positional-args:"true"`
+}
+
+var _ Command = WhichCommand{}
+
+// Execute выводит для встроенных команд "имя: shell builtin", для программ - путь к ним,
+// а с флагом -a - все найденные варианты. Если хотя бы одно имя не найдено, код возврата 1.
+func (cmd WhichCommand) Execute(ctx context.Context) error {
+	var options whichOptions
+	if err := arg_parse(&options, cmd.meta.Args); err != nil {
+		return err
+	}
+
+	status := 0
+	for _, name := range options.Positional.Names {
+		var found []string
+		if IsBuiltin(name) {
+			found = append(found, name+": shell builtin")
+		}
+		if options.All && !strings.Contains(name, "/") {
+			found = append(found, lookPathAll(cmd.sess, name)...)
+		} else if len(found) == 0 {
+			if path, err := lookPath(cmd.sess, name, false); err == nil {
+				found = append(found, path)
+			}
+		}
+		if len(found) == 0 {
+			status = 1
+			continue
+		}
+		if !options.All {
+			found = found[:1]
+		}
+		for _, line := range found {
+			if _, err := fmt.Fprintln(cmd.output, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	if status != 0 {
+		return ExitStatusError{Status: status}
+	}
+	return nil
+}
+
+//////////////////////////////////
+
+// CommandCommand исполняет команду в обход алиасов, а с флагами -v и -V
+// выводит, чем будет исполнено каждое из переданных имен.
+// Потоками ввода-вывода данная структура не владеет.
+type CommandCommand struct {
+	input   io.Reader
+	output  io.Writer
+	meta    command_meta.CommandMeta
+	factory *CommandFactory
+}
+
+var _ Command = CommandCommand{}
+
+// Execute разбирает аргументы вручную, так как аргументы исполняемой команды
+// go-flags принял бы за свои флаги
+func (cmd CommandCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	describe, verbose := false, false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		flag := args[0]
+		args = args[1:]
+		if flag == "--" {
+			break
+		}
+		for _, c := range flag[1:] {
+			switch c {
+			case 'v':
+				describe = true
+			case 'V':
+				describe, verbose = true, true
+			default:
+				return fmt.Errorf("command: -%c: invalid option", c)
+			}
+		}
+	}
+
+	if !describe {
+		if len(args) == 0 {
+			return nil
+		}
+		meta := command_meta.CommandMeta{Name: args[0], Args: args[1:], Envs: cmd.meta.Envs}
+		return cmd.factory.ChildCommand(meta, cmd.input, cmd.output).Execute(ctx)
+	}
+
+	status := 0
+	for _, name := range args {
+		line, ok := cmd.describe(name, verbose)
+		if !ok {
+			status = 1
+			if verbose {
+				fmt.Fprintf(cmd.factory.Session().Stderr, "command: %s: not found\n", name)
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(cmd.output, line); err != nil {
+			return err
+		}
+	}
+	if status != 0 {
+		return ExitStatusError{Status: status}
+	}
+	return nil
+}
+
+// describe возвращает описание имени в формате command -v или command -V
+func (cmd CommandCommand) describe(name string, verbose bool) (string, bool) {
+	sess := cmd.factory.Session()
+	if value, ok := sess.Alias(name); ok {
+		if verbose {
+			return fmt.Sprintf("%s is aliased to `%s'", name, value), true
+		}
+		return fmt.Sprintf("alias %s=%s", name, ShellQuote(value)), true
+	}
+	if IsBuiltin(name) {
+		if verbose {
+			return name + " is a shell builtin", true
+		}
+		return name, true
+	}
+
+	_, hashed := sess.Hashed(name, false)
+	path, err := lookPath(sess, name, false)
+	if err != nil {
+		return "", false
+	}
+	if !verbose {
+		return path, true
+	}
+	if hashed {
+		return fmt.Sprintf("%s is hashed (%s)", name, path), true
+	}
+	return fmt.Sprintf("%s is %s", name, path), true
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newPathSession создает сессию, в PATH которой одна директория с исполняемыми файлами names
+func newPathSession(t *testing.T, names ...string) (*session.Session, string) {
+	dir := t.TempDir()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\necho \"$@\"\n"), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), nil, 0644))
+
+	sess := newTestSession(t, dir)
+	sess.Set(session.PathKey, dir)
+	return sess, dir
+}
+
+func TestBuiltinNames(t *testing.T) {
+	require.True(t, sort.StringsAreSorted(builtinNames))
+	require.Len(t, builtinNames, len(builtins))
+
+	factory := NewCommandFactory(newTestSession(t, ""))
+	for _, name := range builtinNames {
+		require.True(t, IsBuiltin(name), name)
+		require.False(t, factory.IsExternal(command_meta.CommandMeta{Name: name}), name)
+	}
+	require.False(t, IsBuiltin("bash"))
+	require.True(t, factory.IsExternal(command_meta.CommandMeta{Name: "bash"}))
+}
+
+func TestEditDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("grep", "grep"))
+	require.Equal(t, 1, editDistance("gerp", "grep"))
+	require.Equal(t, 1, editDistance("sl", "ls"))
+	require.Equal(t, 2, editDistance("ct", "cut"+"s"))
+	require.Equal(t, 3, editDistance("", "cat"))
+}
+
+func TestLookPathHashesPrograms(t *testing.T) {
+	sess, dir := newPathSession(t, "hello")
+
+	output, err := runBuiltin(sess, "hello", "world")
+	require.NoError(t, err)
+	require.Equal(t, "world\n", output)
+	_, err = runBuiltin(sess, "hello")
+	require.NoError(t, err)
+
+	output, err = runBuiltin(sess, "hash")
+	require.NoError(t, err)
+	require.Equal(t, "hits\tcommand\n   2\t"+filepath.Join(dir, "hello")+"\n", output)
+
+	// Удаленная программа ищется в PATH заново
+	require.NoError(t, os.Remove(filepath.Join(dir, "hello")))
+	_, err = runBuiltin(sess, "hello")
+	require.Equal(t, NotFoundStatus, ExitStatus(err))
+
+	_, err = runBuiltin(sess, "hash", "-r")
+	require.NoError(t, err)
+	output, err = runBuiltin(sess, "hash")
+	require.NoError(t, err)
+	require.Equal(t, "hash: hash table empty\n", output)
+}
+
+func TestCommandNotFound(t *testing.T) {
+	sess, _ := newPathSession(t, "deploy")
+	sess.SetAlias("gst", "git status")
+
+	cases := map[string]string{
+		"deplyo": "command not found, did you mean: deploy?",
+		"gerp":   "command not found, did you mean: grep?",
+		"gs":     "command not found, did you mean: gst, ls, ps?",
+		"data":   "command not found",
+		"zzzzzz": "command not found",
+	}
+	for name, expected := range cases {
+		_, err := runBuiltin(sess, name)
+		require.EqualError(t, err, expected, name)
+		require.Equal(t, NotFoundStatus, ExitStatus(err), name)
+	}
+
+	_, err := runBuiltin(sess, "./missing")
+	require.EqualError(t, err, "No such file or directory")
+	require.Equal(t, NotFoundStatus, ExitStatus(err))
+
+	// Файл есть, но исполнить его нельзя
+	_, err = runBuiltin(sess, "./data")
+	require.EqualError(t, err, "Permission denied")
+	require.Equal(t, NotExecutableStatus, ExitStatus(err))
+	_, err = runBuiltin(sess, "./")
+	require.EqualError(t, err, "Is a directory")
+	require.Equal(t, NotExecutableStatus, ExitStatus(err))
+}
+
+func TestHashCommand(t *testing.T) {
+	sess, dir := newPathSession(t, "hello", "other")
+
+	_, err := runBuiltin(sess, "hash", "hello", "cat")
+	require.NoError(t, err)
+	output, err := runBuiltin(sess, "hash", "-t", "other")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "other")+"\n", output)
+
+	_, err = runBuiltin(sess, "hash", "-d", "other")
+	require.NoError(t, err)
+	entries := sess.Hashes()
+	require.Len(t, entries, 1)
+	require.Equal(t, "hello", entries[0].Name)
+
+	_, err = runBuiltin(sess, "hash", "missing")
+	require.EqualError(t, err, "hash: missing: not found")
+
+	// Изменение PATH сбрасывает таблицу
+	sess.Set(session.PathKey, dir+string(filepath.ListSeparator)+dir)
+	require.Empty(t, sess.Hashes())
+}
+
+func TestWhichAndCommand(t *testing.T) {
+	sess, dir := newPathSession(t, "hello", "cat")
+	sess.SetAlias("hi", "hello there")
+	hello := filepath.Join(dir, "hello")
+
+	output, err := runBuiltin(sess, "which", "hello", "cat", "missing")
+	require.Equal(t, 1, ExitStatus(err))
+	require.Equal(t, hello+"\ncat: shell builtin\n", output)
+
+	output, err = runBuiltin(sess, "which", "-a", "cat")
+	require.NoError(t, err)
+	require.Equal(t, "cat: shell builtin\n"+filepath.Join(dir, "cat")+"\n", output)
+
+	output, err = runBuiltin(sess, "command", "-v", "hi", "cat", "hello")
+	require.NoError(t, err)
+	require.Equal(t, "alias hi='hello there'\ncat\n"+hello+"\n", output)
+
+	output, err = runBuiltin(sess, "command", "-V", "hello", "cat")
+	require.NoError(t, err)
+	require.Equal(t, "hello is hashed ("+hello+")\ncat is a shell builtin\n", output)
+
+	_, err = runBuiltin(sess, "command", "-v", "missing")
+	require.Equal(t, 1, ExitStatus(err))
+
+	// Без флагов исполняется переданная команда
+	output, err = runBuiltin(sess, "command", "echo", "x", "y")
+	require.NoError(t, err)
+	require.Equal(t, "x y\n", output)
+}
+
+func TestIsKnownCommand(t *testing.T) {
+	sess, dir := newPathSession(t, "allowed", "forbidden")
+	sess.SetAlias("ll", "ls -l")
+
+	for _, name := range []string{"echo", "cd", "ll", "allowed", "forbidden", filepath.Join(dir, "allowed")} {
+		require.True(t, IsKnownCommand(sess, name), name)
+	}
+	for _, name := range []string{"missing", "data", ""} {
+		require.False(t, IsKnownCommand(sess, name), name)
+	}
+	_, hashed := sess.Hashed("allowed", false)
+	require.False(t, hashed)
+
+	// В ограниченном режиме известны только команды, которые можно запустить
+	sess.Restrict([]string{"allowed"})
+	for _, name := range []string{"echo", "ll", "allowed"} {
+		require.True(t, IsKnownCommand(sess, name), name)
+	}
+	for _, name := range []string{"cd", "forbidden", filepath.Join(dir, "allowed")} {
+		require.False(t, IsKnownCommand(sess, name), name)
+	}
+}
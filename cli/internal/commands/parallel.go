@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"shell/internal/command_meta"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ParallelCommand запускает пайплайн-шаблон для каждой строки входа,
+// исполняя несколько пайплайнов одновременно.
+// Пайплайны исполняет PipelineRunner фабрики, то есть тот же исполнитель,
+// что и введенные в сессии команды, поэтому шаблон может содержать пайпы
+// и перенаправления, встроенные команды и внешние программы.
+// Потоками ввода-вывода данная структура не владеет.
+type ParallelCommand struct {
+	input   io.Reader
+	output  io.Writer
+	meta    command_meta.CommandMeta
+	factory *CommandFactory
+}
+
+type parallelOptions struct {
+	Jobs        int  `short:"j" long:"jobs" default:"-1"`
+	KeepOrder   bool `short:"k" long:"keep-order"`
+	HaltOnError bool `long:"halt-on-error"`
+	Summary     bool `long:"summary"`
+}
+
+var _ Command = ParallelCommand{}
+
+// Место в шаблоне, куда подставляется строка входа
+const parallelPlaceholder = "{}"
+
+// Наибольший код возврата parallel, равный числу неудачных заданий
+const parallelMaxFailed = 101
+
+// Задание parallel: строка входа, пайплайн и результат его исполнения
+type parallelJob struct {
+	arg    string
+	metas  []command_meta.CommandMeta
+	output bytes.Buffer
+	status int
+	err    error
+	// Задание завершилось или не было запущено
+	done    bool
+	started bool
+}
+
+// Execute читает строки из input и для каждой непустой строки исполняет шаблон,
+// подставив вместо {} строку в кавычках (без {} строка добавляется в конец шаблона).
+// Аргументы команды объединяются пробелами и разбираются как строка оболочки.
+// -j N ограничивает число одновременно исполняемых пайплайнов
+// (по умолчанию - число процессоров, 0 - без ограничений).
+// Вывод каждого задания собирается целиком и выводится после его завершения,
+// с -k - в порядке строк входа.
+// --halt-on-error не запускает новые задания после первого неудачного.
+// Неудачные задания (с --summary - все) перечисляются в stderr вместе с кодами возврата.
+// Код возврата - число неудачных заданий, но не больше 101,
+// с --halt-on-error - код возврата первого неудачного задания.
+func (cmd ParallelCommand) Execute(ctx context.Context) error {
+	var opts parallelOptions
+	template, err := cmd.parseArgs(&opts)
+	if err != nil {
+		return err
+	}
+	runner := cmd.factory.PipelineRunner()
+	if runner == nil {
+		return fmt.Errorf("parallel: pipelines cannot be run in this context")
+	}
+	if opts.Jobs < 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	jobs, err := cmd.readJobs(template)
+	if err != nil {
+		return err
+	}
+
+	var mutex sync.Mutex
+	halted := false
+	next := 0
+	// finish выводит результат завершенного задания, с -k - всех
+	// завершенных подряд заданий, начиная с первого еще не выведенного
+	finish := func(job *parallelJob) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		job.done = true
+		if job.started && job.status != 0 && opts.HaltOnError {
+			halted = true
+		}
+		if !opts.KeepOrder {
+			_, err := cmd.output.Write(job.output.Bytes())
+			return err
+		}
+		for ; next < len(jobs) && jobs[next].done; next++ {
+			if _, err := cmd.output.Write(jobs[next].output.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var eg errgroup.Group
+	if opts.Jobs > 0 {
+		eg.SetLimit(opts.Jobs)
+	}
+	for _, job := range jobs {
+		eg.Go(func() error {
+			mutex.Lock()
+			job.started = !halted && ctx.Err() == nil
+			mutex.Unlock()
+			if job.started {
+				// Запущенные пайплайны не должны читать вход самого parallel
+				job.status, job.err = runner.RunPipeline(ctx, strings.NewReader(""), &job.output, job.metas)
+			}
+			return finish(job)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cmd.summary(jobs, opts)
+}
+
+// readJobs читает строки входа и разбирает для каждой из них пайплайн по шаблону.
+// Переменные подставляются из снимка переменных сессии на момент запуска.
+func (cmd ParallelCommand) readJobs(template string) ([]*parallelJob, error) {
+	data, err := io.ReadAll(cmd.input)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := cmd.factory.Session()
+	env := snapshotEnv(sess)
+
+	var jobs []*parallelJob
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		script := template + " " + parallelPlaceholder
+		if strings.Contains(template, parallelPlaceholder) {
+			script = template
+		}
+		script = strings.ReplaceAll(script, parallelPlaceholder, ShellQuote(line))
+
+		metas, err := parsePipeline(script, env, sess)
+		if err != nil {
+			return nil, fmt.Errorf("parallel: %s: %v", script, err)
+		}
+		jobs = append(jobs, &parallelJob{arg: line, metas: metas})
+	}
+	return jobs, nil
+}
+
+// summary выводит в stderr коды возврата заданий и возвращает код возврата parallel
+func (cmd ParallelCommand) summary(jobs []*parallelJob, opts parallelOptions) error {
+	stderr := cmd.factory.Session().Stderr
+	failed, skipped := 0, 0
+	var firstFailed *parallelJob
+	for i, job := range jobs {
+		switch {
+		case !job.started:
+			skipped++
+			continue
+		case job.status != 0:
+			failed++
+			if firstFailed == nil {
+				firstFailed = job
+			}
+		case !opts.Summary:
+			continue
+		}
+
+		fmt.Fprintf(stderr, "parallel: job %d (%s): exit status %d", i+1, job.arg, job.status)
+		if job.err != nil && !IsSilent(job.err) {
+			fmt.Fprintf(stderr, ": %v", job.err)
+		}
+		fmt.Fprintln(stderr)
+	}
+	if opts.Summary || failed != 0 {
+		fmt.Fprintf(stderr, "parallel: %d jobs: %d succeeded, %d failed, %d not started\n",
+			len(jobs), len(jobs)-failed-skipped, failed, skipped)
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case opts.HaltOnError:
+		return ExitStatusError{Status: firstFailed.status}
+	}
+	return ExitStatusError{Status: min(failed, parallelMaxFailed)}
+}
+
+// parseArgs разбирает флаги parallel, стоящие до шаблона команды.
+// Возвращает текст шаблона.
+func (cmd ParallelCommand) parseArgs(opts *parallelOptions) (string, error) {
+	flagArgs, template, err := splitLeadingFlags("parallel", cmd.meta.Args, "-j", "--jobs")
+	if err != nil {
+		return "", err
+	}
+	if err := arg_parse(opts, flagArgs); err != nil {
+		return "", err
+	}
+	if len(template) == 0 {
+		return "", fmt.Errorf("parallel: usage: parallel [-j N] [-k] [--halt-on-error] [--summary] COMMAND [ARG]...")
+	}
+	return strings.Join(template, " "), nil
+}
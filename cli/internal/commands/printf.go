@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrintfCommand выводит аргументы согласно строке формата.
+// Потоками ввода-вывода данная структура не владеет.
+type PrintfCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+var _ Command = PrintfCommand{}
+
+// Execute форматирует аргументы по первому аргументу-формату.
+// Поддерживаются спецификаторы %s %b %q %c %d %i %u %o %x %X %e %E %f %F %g %G и %%
+// с флагами, шириной и точностью, а также escape-последовательности в формате.
+// Если аргументов больше, чем спецификаторов, формат применяется повторно.
+func (cmd PrintfCommand) Execute(ctx context.Context) error {
+	if len(cmd.meta.Args) == 0 {
+		return fmt.Errorf("printf: usage: printf format [arguments]")
+	}
+
+	format := cmd.meta.Args[0]
+	args := cmd.meta.Args[1:]
+
+	var result strings.Builder
+	var conversionErr error
+	for {
+		consumed, stop, err := printfOnce(&result, format, args)
+		if err != nil && conversionErr == nil {
+			conversionErr = err
+		}
+		args = args[consumed:]
+		if stop || consumed == 0 || len(args) == 0 {
+			break
+		}
+	}
+
+	if _, err := io.WriteString(cmd.output, result.String()); err != nil {
+		return err
+	}
+	return conversionErr
+}
+
+// printfOnce применяет формат один раз.
+// Возвращает количество использованных аргументов и признак остановки вывода (\c).
+// Ошибка преобразования числа не прерывает вывод, как и в bash.
+func printfOnce(result *strings.Builder, format string, args []string) (int, bool, error) {
+	consumed := 0
+	nextArg := func() (string, bool) {
+		if consumed < len(args) {
+			consumed++
+			return args[consumed-1], true
+		}
+		return "", false
+	}
+
+	var conversionErr error
+	for i := 0; i < len(format); i++ {
+		// Текст до следующего спецификатора выводится с раскрытием escape-последовательностей
+		if format[i] != '%' {
+			end := strings.IndexByte(format[i:], '%')
+			if end < 0 {
+				end = len(format)
+			} else {
+				end += i
+			}
+			expanded, stop := expand_escapes(format[i:end], octalPlain)
+			result.WriteString(expanded)
+			if stop {
+				return consumed, true, conversionErr
+			}
+			i = end - 1
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			result.WriteByte('%')
+			i++
+			continue
+		}
+
+		// Спецификатор: %[флаги][ширина][.точность]глагол
+		end := i + 1
+		for end < len(format) && strings.IndexByte("-+ #0", format[end]) >= 0 {
+			end++
+		}
+		for end < len(format) && (format[end] >= '0' && format[end] <= '9' || format[end] == '.') {
+			end++
+		}
+		if end >= len(format) {
+			result.WriteString(format[i:])
+			break
+		}
+		spec := format[i:end]
+		verb := format[end]
+		i = end
+
+		arg, _ := nextArg()
+		switch verb {
+		case 's':
+			result.WriteString(fmt.Sprintf(spec+"s", arg))
+		case 'b':
+			expanded, stop := expand_escapes(arg, octalAny)
+			result.WriteString(fmt.Sprintf(spec+"s", expanded))
+			if stop {
+				return consumed, true, conversionErr
+			}
+		case 'q':
+			result.WriteString(fmt.Sprintf(spec+"s", ShellQuote(arg)))
+		case 'c':
+			r, _ := utf8.DecodeRuneInString(arg)
+			if arg == "" {
+				r = 0
+			}
+			result.WriteString(fmt.Sprintf(spec+"c", r))
+		case 'd', 'i', 'u', 'o', 'x', 'X':
+			value, err := printfInteger(arg)
+			if err != nil && conversionErr == nil {
+				conversionErr = err
+			}
+			goVerb := verb
+			if verb == 'i' || verb == 'u' {
+				goVerb = 'd'
+			}
+			result.WriteString(fmt.Sprintf(spec+string(goVerb), value))
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			value, err := printfFloat(arg)
+			if err != nil && conversionErr == nil {
+				conversionErr = err
+			}
+			result.WriteString(fmt.Sprintf(spec+string(verb), value))
+		default:
+			return consumed, true, fmt.Errorf("printf: %c: invalid format character", verb)
+		}
+	}
+	return consumed, false, conversionErr
+}
+
+// printfInteger разбирает целочисленный аргумент printf.
+// Аргумент вида 'c или "c означает код символа c.
+func printfInteger(arg string) (int64, error) {
+	if arg == "" {
+		return 0, nil
+	}
+	if arg[0] == '\'' || arg[0] == '"' {
+		r, _ := utf8.DecodeRuneInString(arg[1:])
+		return int64(r), nil
+	}
+	value, err := strconv.ParseInt(arg, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("printf: %s: invalid number", arg)
+	}
+	return value, nil
+}
+
+func printfFloat(arg string) (float64, error) {
+	if arg == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, fmt.Errorf("printf: %s: invalid number", arg)
+	}
+	return value, nil
+}
+
+// ShellQuote экранирует строку так, чтобы ее можно было повторно ввести в shell
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"shell/internal/command_meta"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintf(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"plain", []string{`hello\n`}, "hello\n"},
+		{"string", []string{`%s-%s\n`, "a", "b"}, "a-b\n"},
+		{"width", []string{`[%5s][%-5s]`, "ab", "cd"}, "[   ab][cd   ]"},
+		{"integers", []string{`%d %i %x %X %o %05d`, "42", "-7", "255", "255", "8", "42"}, "42 -7 ff FF 10 00042"},
+		{"char code", []string{`%d`, "'A"}, "65"},
+		{"floats", []string{`%.2f %e`, "3.14159", "1000"}, "3.14 1.000000e+03"},
+		{"char", []string{`%c%c`, "hello", "w"}, "hw"},
+		{"percent", []string{`100%%\n`}, "100%\n"},
+		{"reuse format", []string{`<%s>`, "a", "b", "c"}, "<a><b><c>"},
+		{"missing args", []string{`%s|%d|`}, "|0|"},
+		{"escapes", []string{`a\tb\\c\101\x42`}, "a\tb\\cAB"},
+		{"b verb", []string{`%b|`, `x\ny\0101`}, "x\nyA|"},
+		{"b verb octal without zero", []string{`%b|%b|%b`, `x\101`, `\0`, `\08`}, "xA|\x00|\x008"},
+		{"stop", []string{`%b-after`, `before\cignored`}, "before"},
+		{"quote", []string{`%q %q`, "simple", "it's here"}, `simple 'it'\''s here'`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "printf", Args: tc.args}
+			actual := runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+				return PrintfCommand{in, out, meta}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestPrintfInvalidNumber(t *testing.T) {
+	rp, wp, err := os.Pipe()
+	require.NoError(t, err)
+	defer rp.Close()
+
+	meta := command_meta.CommandMeta{Name: "printf", Args: []string{"%d", "abc"}}
+	err = PrintfCommand{nil, wp, meta}.Execute(context.Background())
+	wp.Close()
+	require.Error(t, err)
+}
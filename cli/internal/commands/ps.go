@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Директория файловой системы procfs
+const procDir = "/proc"
+
+// PsCommand выводит список процессов системы.
+// Потоками ввода-вывода данная структура не владеет.
+type PsCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+var _ Command = PsCommand{}
+
+// Execute выводит по записи на процесс с полями pid, ppid, state,
+// rss (резидентная память в байтах), name и command в порядке возрастания pid.
+// Сведения читаются из /proc, поэтому команда работает только в Linux.
+// Процессы, завершившиеся во время чтения, пропускаются.
+func (cmd PsCommand) Execute(ctx context.Context) error {
+	if len(cmd.meta.Args) != 0 {
+		return fmt.Errorf("ps: too many arguments")
+	}
+
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return fmt.Errorf("ps: %w", err)
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+
+	sink := newRecordSink(cmd.output)
+	for _, pid := range pids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := readProcess(pid)
+		if err != nil {
+			continue
+		}
+		if err := sink.Write(record); err != nil {
+			return err
+		}
+	}
+	return sink.Close()
+}
+
+// readProcess читает сведения о процессе pid из /proc/PID/stat и /proc/PID/cmdline
+func readProcess(pid int) (Record, error) {
+	dir := filepath.Join(procDir, strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return Record{}, err
+	}
+
+	// Имя процесса в скобках может содержать пробелы и скобки,
+	// поэтому поля после него ищутся от последней закрывающей скобки
+	text := string(stat)
+	start, end := strings.IndexByte(text, '('), strings.LastIndexByte(text, ')')
+	if start < 0 || end < start {
+		return Record{}, fmt.Errorf("ps: %s: malformed stat", dir)
+	}
+	name := text[start+1 : end]
+	fields := strings.Fields(text[end+1:])
+	if len(fields) < 22 {
+		return Record{}, fmt.Errorf("ps: %s: malformed stat", dir)
+	}
+	ppid, _ := strconv.ParseInt(fields[1], 10, 64)
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+	command := strings.TrimRight(strings.ReplaceAll(string(cmdline), "\x00", " "), " ")
+
+	record := NewRecord()
+	record.Set("pid", int64(pid))
+	record.Set("ppid", ppid)
+	record.Set("state", fields[0])
+	record.Set("rss", rss*int64(os.Getpagesize()))
+	record.Set("name", name)
+	record.Set("command", command)
+	return record, nil
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/session"
+	"strings"
+)
+
+// ReadCommand читает строку из входного потока в переменные окружения.
+// Потоками ввода-вывода данная структура не владеет.
+type ReadCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type readOptions struct {
+	Raw    bool   `short:"r"`
+	Prompt string `short:"p"`
+	Array  string `short:"a"`
+
+	Positional struct {
+		Names []string
+	} `positional-args:"true"`
+}
+
+// Переменная, в которую попадает строка, если имена не переданы
+const readDefaultName = "REPLY"
+
+var _ Command = ReadCommand{}
+
+// Execute читает одну строку из input и разбивает ее на поля по символам IFS.
+// Поля по очереди присваиваются переданным переменным, последняя переменная
+// получает остаток строки. С флагом -a поля записываются в элементы массива
+// name[0], name[1], ... Без флага -r обратная косая черта экранирует следующий символ.
+// Если входной поток закончился до перевода строки, команда завершается с кодом 1.
+func (cmd ReadCommand) Execute(ctx context.Context) error {
+	var opts readOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	if opts.Prompt != "" {
+		fmt.Fprint(cmd.sess.Stderr, opts.Prompt)
+	}
+
+	names := opts.Positional.Names
+	if err := CheckAssignment(cmd.sess, append(names, opts.Array)...); err != nil {
+		return err
+	}
+
+	line, escaped, eof, err := cmd.readLine(opts.Raw)
+	if err != nil {
+		return err
+	}
+
+	ifs, ok := cmd.sess.Get(envsholder.IFSKey)
+	if !ok {
+		ifs = envsholder.DefaultIFS
+	}
+
+	cmd.sess.WithEnv(func(env *envsholder.Env) {
+		switch {
+		case opts.Array != "":
+			for key := range env.Vars {
+				if strings.HasPrefix(key, opts.Array+"[") {
+					env.Unset(key)
+				}
+			}
+			fields := splitFields(line, escaped, ifs, -1)
+			for i, field := range fields {
+				env.Set(fmt.Sprintf("%s[%d]", opts.Array, i), field)
+			}
+			// Как и в bash, имя массива без индекса ссылается на нулевой элемент
+			if len(fields) > 0 {
+				env.Set(opts.Array, fields[0])
+			} else {
+				env.Unset(opts.Array)
+			}
+		case len(names) == 0:
+			env.Set(readDefaultName, line)
+		default:
+			fields := splitFields(line, escaped, ifs, len(names))
+			for i, name := range names {
+				value := ""
+				if i < len(fields) {
+					value = fields[i]
+				}
+				env.Set(name, value)
+			}
+		}
+	})
+
+	if eof {
+		return ExitStatusError{Status: 1}
+	}
+	return nil
+}
+
+// readLine побайтово читает строку из input, чтобы не захватить лишние данные
+// из потока, которые могут понадобиться следующим командам.
+// Возвращает строку без перевода строки, маску экранированных символов
+// и признак конца потока.
+func (cmd ReadCommand) readLine(raw bool) (string, []bool, bool, error) {
+	var line []byte
+	var escaped []bool
+	buffer := make([]byte, 1)
+	escape := false
+	for {
+		n, err := cmd.input.Read(buffer)
+		if n == 0 {
+			if err == io.EOF {
+				return string(line), escaped, true, nil
+			}
+			if err != nil {
+				return "", nil, false, err
+			}
+			continue
+		}
+
+		c := buffer[0]
+		switch {
+		case escape:
+			escape = false
+			// Экранированный перевод строки продолжает строку
+			if c == '\n' {
+				continue
+			}
+			line = append(line, c)
+			escaped = append(escaped, true)
+		case c == '\\' && !raw:
+			escape = true
+		case c == '\n':
+			return string(line), escaped, false, nil
+		default:
+			line = append(line, c)
+			escaped = append(escaped, false)
+		}
+	}
+}
+
+// splitFields разбивает строку на поля по символам ifs.
+// Пробельные символы ifs схлопываются и обрезаются по краям строки,
+// каждый непробельный символ ifs отделяет ровно одно поле.
+// Экранированные символы разделителями не считаются.
+// Если limit > 0, то последнее поле содержит остаток строки.
+func splitFields(line string, escaped []bool, ifs string, limit int) []string {
+	isSpace := func(i int) bool {
+		return !escaped[i] && strings.IndexByte(ifs, line[i]) >= 0 && strings.IndexByte(" \t\n", line[i]) >= 0
+	}
+	isDelimiter := func(i int) bool {
+		return !escaped[i] && strings.IndexByte(ifs, line[i]) >= 0
+	}
+
+	var fields []string
+	i := 0
+	for i < len(line) && isSpace(i) {
+		i++
+	}
+	for i < len(line) {
+		if limit > 0 && len(fields) == limit-1 {
+			end := len(line)
+			for end > i && isSpace(end-1) {
+				end--
+			}
+			fields = append(fields, line[i:end])
+			break
+		}
+
+		start := i
+		for i < len(line) && !isDelimiter(i) {
+			i++
+		}
+		fields = append(fields, line[start:i])
+
+		// Пропускаем разделитель: пробельные символы вокруг
+		// и не более одного непробельного символа
+		for i < len(line) && isSpace(i) {
+			i++
+		}
+		if i < len(line) && isDelimiter(i) {
+			i++
+			for i < len(line) && isSpace(i) {
+				i++
+			}
+		}
+	}
+	return fields
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"shell/internal/command_meta"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		args     []string
+		ifs      string
+		expected map[string]string
+	}{
+		{
+			name:     "reply",
+			input:    "  whole line  \nnext\n",
+			expected: map[string]string{"REPLY": "  whole line  "},
+		},
+		{
+			name:     "fields",
+			input:    "  one two   three four  \n",
+			args:     []string{"a", "b", "c"},
+			expected: map[string]string{"a": "one", "b": "two", "c": "three four"},
+		},
+		{
+			name:     "fewer fields",
+			input:    "one\n",
+			args:     []string{"a", "b"},
+			expected: map[string]string{"a": "one", "b": ""},
+		},
+		{
+			name:     "custom ifs",
+			input:    "x:y::z\n",
+			args:     []string{"a", "b", "c", "d"},
+			ifs:      ":",
+			expected: map[string]string{"a": "x", "b": "y", "c": "", "d": "z"},
+		},
+		{
+			name:     "escapes",
+			input:    `a\ b c\` + "\nd\n",
+			args:     []string{"a", "b"},
+			expected: map[string]string{"a": "a b", "b": "cd"},
+		},
+		{
+			name:     "raw",
+			input:    `a\ b` + "\n",
+			args:     []string{"-r", "a", "b"},
+			expected: map[string]string{"a": `a\`, "b": "b"},
+		},
+		{
+			name:     "array",
+			input:    "x y z\n",
+			args:     []string{"-a", "arr"},
+			expected: map[string]string{"arr": "x", "arr[0]": "x", "arr[1]": "y", "arr[2]": "z"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sess := newTestSession(t, "")
+			if tc.ifs != "" {
+				sess.Set("IFS", tc.ifs)
+			}
+
+			meta := command_meta.CommandMeta{Name: "read", Args: tc.args}
+			runWithInput(t, tc.input, func(in io.Reader, out io.Writer) Command {
+				return ReadCommand{in, out, meta, sess}
+			})
+
+			for name, value := range tc.expected {
+				actual, _ := sess.Get(name)
+				require.Equal(t, value, actual, name)
+			}
+		})
+	}
+}
+
+func TestReadEOF(t *testing.T) {
+	rp, wp, err := os.Pipe()
+	require.NoError(t, err)
+	defer rp.Close()
+	_, err = wp.WriteString("partial")
+	require.NoError(t, err)
+	wp.Close()
+
+	meta := command_meta.CommandMeta{Name: "read", Args: []string{"value"}}
+	sess := newTestSession(t, "")
+	err = ReadCommand{rp, nil, meta, sess}.Execute(context.Background())
+	require.Equal(t, 1, ExitStatus(err))
+	value, _ := sess.Get("value")
+	require.Equal(t, "partial", value)
+}
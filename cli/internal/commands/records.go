@@ -0,0 +1,361 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Record - запись, которой обмениваются встроенные команды в структурированном режиме
+// (опция structured). Поля хранятся в порядке добавления, чтобы вывод таблицей
+// и в JSON повторял порядок полей источника. Значения полей - string, int64, float64,
+// bool, nil, []any и вложенные Record.
+type Record struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewRecord создает пустую запись
+func NewRecord() Record {
+	return Record{values: map[string]any{}}
+}
+
+// Keys возвращает имена полей записи в порядке их добавления
+func (r Record) Keys() []string {
+	return r.keys
+}
+
+// Get возвращает значение поля key
+func (r Record) Get(key string) (any, bool) {
+	value, ok := r.values[key]
+	return value, ok
+}
+
+// Set задает значение поля key. Новое поле добавляется в конец записи.
+func (r *Record) Set(key string, value any) {
+	if r.values == nil {
+		r.values = map[string]any{}
+	}
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = value
+}
+
+// MarshalJSON кодирует запись объектом JSON с сохранением порядка полей
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// RecordWriter реализует выход, который принимает записи вместо текста.
+// Такой выход команда получает, только если следующая команда пайплайна читает записи.
+type RecordWriter interface {
+	WriteRecord(record Record) error
+}
+
+// RecordReader реализует вход, с которого читаются записи вместо текста.
+// После последней записи ReadRecord возвращает io.EOF.
+type RecordReader interface {
+	ReadRecord() (Record, error)
+}
+
+// Встроенные команды, которые выводят записи, если их выход это допускает
+var recordProducers = map[string]bool{
+	"from-json": true,
+	"ls":        true,
+	"ps":        true,
+	"select":    true,
+	"sort-by":   true,
+	"where":     true,
+}
+
+// Встроенные команды, которые читают записи, если их вход это допускает
+var recordConsumers = map[string]bool{
+	"select":  true,
+	"sort-by": true,
+	"to-json": true,
+	"wc":      true,
+	"where":   true,
+}
+
+// ProducesRecords сообщает, что команда умеет выводить записи
+func (f *CommandFactory) ProducesRecords(meta command_meta.CommandMeta) bool {
+	return recordProducers[meta.Name] && checkRestricted(f.sess, meta) == nil
+}
+
+// ConsumesRecords сообщает, что команда умеет читать записи
+func (f *CommandFactory) ConsumesRecords(meta command_meta.CommandMeta) bool {
+	return recordConsumers[meta.Name] && checkRestricted(f.sess, meta) == nil
+}
+
+// readRecords читает записи со входа in и передает их в handle.
+// Если вход текстовый, текст разбирается как JSON.
+func readRecords(in io.Reader, handle func(Record) error) error {
+	if reader, ok := in.(RecordReader); ok {
+		for {
+			record, err := reader.ReadRecord()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := handle(record); err != nil {
+				return err
+			}
+		}
+	}
+	return decodeRecords(in, handle)
+}
+
+// decodeRecords разбирает поток JSON: последовательность объектов (в том числе JSON lines)
+// или массивов объектов. Каждый объект становится записью, прочие значения
+// в массиве - записью с единственным полем value.
+func decodeRecords(in io.Reader, handle func(Record) error) error {
+	decoder := json.NewDecoder(bufio.NewReader(in))
+	decoder.UseNumber()
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+
+		if token != json.Delim('[') {
+			value, err := decodeValue(decoder, token)
+			if err != nil {
+				return err
+			}
+			if err := handle(asRecord(value)); err != nil {
+				return err
+			}
+			continue
+		}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return fmt.Errorf("invalid JSON: %w", err)
+			}
+			value, err := decodeValue(decoder, token)
+			if err != nil {
+				return err
+			}
+			if err := handle(asRecord(value)); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+}
+
+// asRecord превращает значение верхнего уровня в запись
+func asRecord(value any) Record {
+	if record, ok := value.(Record); ok {
+		return record
+	}
+	record := NewRecord()
+	record.Set("value", value)
+	return record
+}
+
+// decodeValue декодирует значение JSON, первый токен которого уже прочитан.
+// Объекты декодируются в Record, чтобы сохранить порядок полей.
+func decodeValue(decoder *json.Decoder, token json.Token) (any, error) {
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			record := NewRecord()
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSON: %w", err)
+				}
+				value, err := decodeNext(decoder)
+				if err != nil {
+					return nil, err
+				}
+				record.Set(key.(string), value)
+			}
+			_, err := decoder.Token()
+			return record, err
+		case '[':
+			values := []any{}
+			for decoder.More() {
+				value, err := decodeNext(decoder)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+			_, err := decoder.Token()
+			return values, err
+		}
+		return nil, fmt.Errorf("invalid JSON: unexpected %v", token)
+	case json.Number:
+		if n, err := token.Int64(); err == nil {
+			return n, nil
+		}
+		return token.Float64()
+	default:
+		return token, nil
+	}
+}
+
+// decodeNext декодирует следующее значение JSON
+func decodeNext(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return decodeValue(decoder, token)
+}
+
+// formatValue возвращает текстовое представление значения поля.
+// Отсутствующее значение выводится пустой строкой, составные - в виде JSON.
+func formatValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+}
+
+// numericValue возвращает значение поля как число, если оно числовое
+func numericValue(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+//////////////////////////////////
+
+// recordSink - получатель записей, которые выводит команда.
+// Если выход принимает записи, они передаются сразу, иначе накапливаются
+// и при закрытии выводятся текстом в виде таблицы.
+type recordSink struct {
+	output  io.Writer
+	writer  RecordWriter
+	records []Record
+}
+
+// newRecordSink создает получатель записей для выхода output
+func newRecordSink(output io.Writer) *recordSink {
+	writer, _ := output.(RecordWriter)
+	return &recordSink{output: output, writer: writer}
+}
+
+// Write передает запись получателю
+func (s *recordSink) Write(record Record) error {
+	if s.writer != nil {
+		return s.writer.WriteRecord(record)
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Close выводит накопленные записи таблицей
+func (s *recordSink) Close() error {
+	if s.writer != nil {
+		return nil
+	}
+	_, err := io.WriteString(s.output, formatTable(s.records))
+	return err
+}
+
+// formatTable выводит записи таблицей с заголовком. Столбцы идут в порядке
+// первого появления полей, отсутствующие значения остаются пустыми.
+func formatTable(records []Record) string {
+	if len(records) == 0 {
+		return ""
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	for _, record := range records {
+		for _, key := range record.Keys() {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+
+	rows := [][]string{columns}
+	for _, record := range records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			value, _ := record.Get(column)
+			row[i] = formatValue(value)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	var builder strings.Builder
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(cell)
+			line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		builder.WriteString(strings.TrimRight(line.String(), " "))
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"shell/internal/command_meta"
+	"shell/internal/parser"
+	"shell/internal/session"
+	"slices"
+	"strings"
+)
+
+// Ошибка действия, запрещенного в ограниченном режиме
+type RestrictedError struct {
+	// Переменная или файл, к которым относится запрет; пусто, если запрещена сама команда
+	Subject string
+	Reason  string
+}
+
+func (e *RestrictedError) Error() string {
+	message := "restricted"
+	if e.Reason != "" {
+		message += ": " + e.Reason
+	}
+	if e.Subject != "" {
+		message = e.Subject + ": " + message
+	}
+	return message
+}
+
+// Встроенные команды, которые меняют текущую директорию и запрещены в ограниченном режиме
+var restrictedBuiltins = []string{"cd", "popd", "pushd"}
+
+// CheckAssignment проверяет, что переменные names можно изменять в сессии sess
+func CheckAssignment(sess *session.Session, names ...string) error {
+	if !sess.Restricted() {
+		return nil
+	}
+	return checkRestrictedVariables(names...)
+}
+
+// checkRestrictedVariables проверяет, что среди names нет переменных,
+// которые нельзя изменять в ограниченном режиме
+func checkRestrictedVariables(names ...string) error {
+	for _, name := range names {
+		if slices.Contains(session.RestrictedVariables, name) {
+			return &RestrictedError{Subject: name, Reason: "cannot change variable"}
+		}
+	}
+	return nil
+}
+
+// ArithmeticSetter возвращает функцию записи присваиваний из арифметических подстановок
+// в переменные сессии, которая, как и обычные присваивания, учитывает ограниченный режим
+func ArithmeticSetter(sess *session.Session) parser.ArithmeticSetter {
+	return func(name string, value string) error {
+		if err := CheckAssignment(sess, name); err != nil {
+			return err
+		}
+		sess.Set(name, value)
+		return nil
+	}
+}
+
+// checkRestricted проверяет, что команду можно исполнить в сессии sess:
+// в ограниченном режиме имя команды не может содержать /, запрещены смена
+// директории и изменение PATH, SHELL, ENV, а из внешних программ разрешены только
+// перечисленные в списке сессии
+func checkRestricted(sess *session.Session, meta command_meta.CommandMeta) error {
+	if !sess.Restricted() {
+		return nil
+	}
+
+	names := make([]string, 0, len(meta.Envs.Vars))
+	for name := range meta.Envs.Vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if err := CheckAssignment(sess, names...); err != nil {
+		return err
+	}
+
+	switch {
+	case meta.Name == "":
+		return nil
+	case strings.Contains(meta.Name, "/"):
+		return &RestrictedError{Reason: "cannot specify `/' in command names"}
+	case slices.Contains(restrictedBuiltins, meta.Name):
+		return &RestrictedError{}
+	case !IsBuiltin(meta.Name) && !sess.ProgramAllowed(meta.Name):
+		return &RestrictedError{Reason: "command not allowed"}
+	}
+	return nil
+}
+
+// Команда, исполнение которой запрещено: вместо исполнения возвращает ошибку
+type restrictedCommand struct {
+	err error
+}
+
+var _ Command = restrictedCommand{}
+
+func (cmd restrictedCommand) Execute(ctx context.Context) error {
+	return cmd.err
+}
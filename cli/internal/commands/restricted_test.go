@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/parser"
+	"shell/internal/session"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestrictedCommands(t *testing.T) {
+	sess, _ := newPathSession(t, "allowed", "forbidden")
+	sess.Restrict([]string{"allowed"})
+	dir := sess.Dir()
+
+	cases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "cd", args: []string{"/"}, err: "restricted"},
+		{name: "pushd", args: []string{"/"}, err: "restricted"},
+		{name: "./allowed", err: "restricted: cannot specify `/' in command names"},
+		{name: "forbidden", err: "restricted: command not allowed"},
+		{name: "command", args: []string{"forbidden"}, err: "restricted: command not allowed"},
+		{name: "read", args: []string{"PATH"}, err: "PATH: restricted: cannot change variable"},
+	}
+	for _, tc := range cases {
+		_, err := runBuiltin(sess, tc.name, tc.args...)
+		var restricted *RestrictedError
+		require.True(t, errors.As(err, &restricted), tc.name)
+		require.EqualError(t, err, tc.err, tc.name)
+	}
+	require.Equal(t, dir, sess.Dir())
+
+	output, err := runBuiltin(sess, "allowed", "x")
+	require.NoError(t, err)
+	require.Equal(t, "x\n", output)
+	output, err = runBuiltin(sess, "echo", "builtin")
+	require.NoError(t, err)
+	require.Equal(t, "builtin\n", output)
+}
+
+// assignment создает метаданные присваивания name=value
+func assignment(name string, value string) command_meta.CommandMeta {
+	return command_meta.CommandMeta{Envs: envsholder.Env{Vars: map[string]string{name: value}}}
+}
+
+func TestRestrictedAssignments(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.Restrict(nil)
+	factory := NewCommandFactory(sess)
+
+	for _, line := range []string{"PATH=/tmp", "SHELL=/bin/sh", "ENV=x", "OTHER=1"} {
+		name, value, _ := strings.Cut(line, "=")
+		meta := assignment(name, value)
+		err := factory.CommandFromMeta(meta, nil, nil).Execute(context.Background())
+		if name == "OTHER" {
+			require.NoError(t, err)
+			continue
+		}
+		require.EqualError(t, err, name+": restricted: cannot change variable")
+		_, ok := sess.Get(name)
+		require.False(t, ok, name)
+	}
+
+	// Локальные переменные команды тоже проверяются
+	meta := assignment(session.PathKey, "/tmp")
+	meta.Name = "echo"
+	require.Error(t, factory.CommandFromMeta(meta, nil, nil).Execute(context.Background()))
+}
+
+func TestRestrictedArithmetic(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.Restrict(nil)
+	var restricted *RestrictedError
+
+	for _, expr := range []string{"PATH=5", "x=1, SHELL=3", "ENV++"} {
+		_, err := runBuiltin(sess, "let", expr)
+		require.ErrorAs(t, err, &restricted, expr)
+	}
+	_, err := runBuiltin(sess, "let", "x=1")
+	require.NoError(t, err)
+
+	// Присваивания в $(( )) записываются через ArithmeticSetter
+	tokenizer := parser.NewTokenizer(strings.NewReader("echo $((SHELL=3)) $SHELL\n"), sess.Env())
+	tokenizer.SetArithmeticSetter(ArithmeticSetter(sess))
+	_, err = parser.NewParser(tokenizer).Parse()
+	require.ErrorAs(t, err, &restricted)
+
+	tokenizer = parser.NewTokenizer(strings.NewReader("echo $((y=2))\n"), sess.Env())
+	tokenizer.SetArithmeticSetter(ArithmeticSetter(sess))
+	_, err = parser.NewParser(tokenizer).Parse()
+	require.NoError(t, err)
+
+	for _, name := range []string{"PATH", "SHELL", "ENV"} {
+		_, ok := sess.Get(name)
+		require.False(t, ok, name)
+	}
+	value, _ := sess.Get("y")
+	require.Equal(t, "2", value)
+}
+
+func TestRestrictedTee(t *testing.T) {
+	sess := newMemSession(t, nil)
+	sess.Restrict(nil)
+
+	var output bytes.Buffer
+	meta := command_meta.CommandMeta{Name: "tee", Args: []string{"-a", "out.txt"}}
+	err := TeeCommand{strings.NewReader("data\n"), &output, meta, sess}.Execute(context.Background())
+	require.EqualError(t, err, "out.txt: restricted: cannot redirect output")
+	_, err = sess.FS().Stat(sess.Path("out.txt"))
+	require.Error(t, err)
+
+	// Без файлов tee только копирует ввод в вывод
+	meta.Args = nil
+	err = TeeCommand{strings.NewReader("data\n"), &output, meta, sess}.Execute(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "data\n", output.String())
+}
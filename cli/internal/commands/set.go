@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetCommand включает и выключает опции оболочки и задает позиционные параметры.
+// Потоками ввода-вывода данная структура не владеет.
+type SetCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = SetCommand{}
+
+// Однобуквенные флаги set и соответствующие им опции
+var setShortOptions = map[rune]string{
+	'e': session.OptionErrexit,
+	'u': session.OptionNounset,
+	'x': session.OptionXtrace,
+	'f': session.OptionNoglob,
+	'C': session.OptionNoclobber,
+}
+
+// Execute разбирает аргументы вида -e/+e и -o name/+o name.
+// Флаг с минусом включает опцию, с плюсом - выключает.
+// set -o без имени выводит состояние опций, set +o - команды для их восстановления,
+// set без аргументов - переменные сессии.
+// Аргументы после -- или первого аргумента, не являющегося флагом, становятся
+// позиционными параметрами; set -- без аргументов очищает их.
+// Аргументы разбираются вручную, так как go-flags не поддерживает флаги с плюсом.
+func (cmd SetCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) == 0 {
+		return cmd.printVariables()
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			cmd.sess.SetParams(args[i+1:])
+			return nil
+		}
+		if arg == "-" {
+			if i+1 < len(args) {
+				cmd.sess.SetParams(args[i+1:])
+			}
+			return nil
+		}
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			cmd.sess.SetParams(args[i:])
+			return nil
+		}
+
+		enable := arg[0] == '-'
+		for _, flag := range arg[1:] {
+			if flag != 'o' {
+				name, ok := setShortOptions[flag]
+				if !ok {
+					return fmt.Errorf("set: %c%c: invalid option", arg[0], flag)
+				}
+				cmd.sess.SetOption(name, enable)
+				continue
+			}
+
+			if i+1 >= len(args) {
+				return cmd.printOptions(enable)
+			}
+			i++
+			if !isOptionName(args[i]) {
+				return fmt.Errorf("set: %s: invalid option name", args[i])
+			}
+			cmd.sess.SetOption(args[i], enable)
+		}
+	}
+	return nil
+}
+
+// printOptions выводит состояние опций: в виде таблицы (set -o)
+// или в виде команд set, восстанавливающих его (set +o)
+func (cmd SetCommand) printOptions(table bool) error {
+	for _, name := range session.OptionNames {
+		enabled := cmd.sess.Option(name)
+		var err error
+		if table {
+			state := "off"
+			if enabled {
+				state = "on"
+			}
+			_, err = fmt.Fprintf(cmd.output, "%-15s\t%s\n", name, state)
+		} else {
+			sign := "+"
+			if enabled {
+				sign = "-"
+			}
+			_, err = fmt.Fprintf(cmd.output, "set %so %s\n", sign, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printVariables выводит переменные сессии, отсортированные по имени
+func (cmd SetCommand) printVariables() error {
+	vars := cmd.sess.Environ()
+	sort.Strings(vars)
+	for _, v := range vars {
+		name, value, _ := strings.Cut(v, "=")
+		if _, err := fmt.Fprintf(cmd.output, "%s=%s\n", name, ShellQuote(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isOptionName(name string) bool {
+	for _, option := range session.OptionNames {
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
+
+//////////////////////////////////
+
+// ShiftCommand сдвигает позиционные параметры влево.
+// Потоками ввода-вывода данная структура не владеет.
+type ShiftCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = ShiftCommand{}
+
+// Execute удаляет первые n позиционных параметров, по умолчанию один.
+// Если параметров меньше n, они не изменяются, а команда завершается с ошибкой.
+func (cmd ShiftCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) > 1 {
+		return fmt.Errorf("shift: too many arguments")
+	}
+
+	n := 1
+	if len(args) == 1 {
+		value, err := strconv.Atoi(args[0])
+		if err != nil || value < 0 {
+			return fmt.Errorf("shift: %s: numeric argument required", args[0])
+		}
+		n = value
+	}
+
+	params := cmd.sess.Params()
+	if n > len(params) {
+		return fmt.Errorf("shift: shift count out of range")
+	}
+	cmd.sess.SetParams(params[n:])
+	return nil
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOptions(t *testing.T) {
+	sess := newTestSession(t, "")
+
+	_, err := runBuiltin(sess, "set", "-eu", "-o", "pipefail", "-C")
+	require.NoError(t, err)
+	require.True(t, sess.Option(session.OptionErrexit))
+	require.True(t, sess.Option(session.OptionNounset))
+	require.True(t, sess.Option(session.OptionPipefail))
+	require.True(t, sess.Option(session.OptionNoclobber))
+	require.False(t, sess.Option(session.OptionXtrace))
+
+	_, err = runBuiltin(sess, "set", "+e", "+o", "pipefail", "-xf")
+	require.NoError(t, err)
+	require.False(t, sess.Option(session.OptionErrexit))
+	require.False(t, sess.Option(session.OptionPipefail))
+	require.True(t, sess.Option(session.OptionXtrace))
+	require.True(t, sess.Option(session.OptionNoglob))
+}
+
+func TestSetListOptions(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.SetOption(session.OptionPipefail, true)
+
+	output, err := runBuiltin(sess, "set", "-o")
+	require.NoError(t, err)
+	require.Equal(t, "errexit        \toff\n"+
+		"noclobber      \toff\n"+
+		"noglob         \toff\n"+
+		"nounset        \toff\n"+
+		"pipefail       \ton\n"+
+		"structured     \toff\n"+
+		"xtrace         \toff\n", output)
+
+	output, err = runBuiltin(sess, "set", "+o")
+	require.NoError(t, err)
+	require.Contains(t, output, "set -o pipefail\n")
+	require.Contains(t, output, "set +o errexit\n")
+}
+
+func TestSetErrors(t *testing.T) {
+	sess := newTestSession(t, "")
+
+	_, err := runBuiltin(sess, "set", "-k")
+	require.EqualError(t, err, "set: -k: invalid option")
+
+	_, err = runBuiltin(sess, "set", "-o", "vi")
+	require.EqualError(t, err, "set: vi: invalid option name")
+}
+
+func TestSetParams(t *testing.T) {
+	sess := newTestSession(t, "")
+
+	_, err := runBuiltin(sess, "set", "-e", "--", "a", "-b", "")
+	require.NoError(t, err)
+	require.True(t, sess.Option(session.OptionErrexit))
+	require.Equal(t, []string{"a", "-b", ""}, sess.Params())
+
+	_, err = runBuiltin(sess, "set", "x", "y")
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, sess.Params())
+
+	shift := func(args ...string) error {
+		meta := command_meta.CommandMeta{Name: "shift", Args: args}
+		return ShiftCommand{nil, nil, meta, sess}.Execute(context.Background())
+	}
+	require.NoError(t, shift())
+	require.Equal(t, []string{"y"}, sess.Params())
+	require.EqualError(t, shift("2"), "shift: shift count out of range")
+	require.Equal(t, []string{"y"}, sess.Params())
+
+	_, err = runBuiltin(sess, "set", "--")
+	require.NoError(t, err)
+	require.Empty(t, sess.Params())
+}
+
+func TestSetPrintsVariables(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.Set("greeting", "hello world")
+
+	output, err := runBuiltin(sess, "set")
+	require.NoError(t, err)
+	require.Contains(t, output, "greeting='hello world'\n")
+}
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SortCommand сортирует строки файла или входного потока.
+// Потоками ввода-вывода данная структура не владеет.
+type SortCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type sortOptions struct {
+	Numeric   bool   `short:"n"`
+	Reverse   bool   `short:"r"`
+	Unique    bool   `short:"u"`
+	Key       string `short:"k"`
+	Separator string `short:"t"`
+
+	Positional struct {
+		Filename string
+	} `positional-args:"true" maximum:"1"`
+}
+
+var _ Command = SortCommand{}
+
+// Execute выводит отсортированные строки.
+// Ключ сортировки задается флагом -k в виде N или N,M (номера полей с 1),
+// разделитель полей - флагом -t (по умолчанию поля разделяются пробельными символами).
+func (cmd SortCommand) Execute(ctx context.Context) error {
+	var opts sortOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	keyFrom, keyTo, err := parseSortKey(opts.Key)
+	if err != nil {
+		return err
+	}
+	if len([]rune(opts.Separator)) > 1 {
+		return fmt.Errorf("sort: multi-character tab %q", opts.Separator)
+	}
+
+	in, closeInput, err := open_input(cmd.sess, opts.Positional.Filename, cmd.input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	lines, err := read_lines(in)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = sortKey(line, opts.Separator, keyFrom, keyTo)
+	}
+
+	compareKeys := func(a, b string) int {
+		if opts.Numeric {
+			return compareNumeric(a, b)
+		}
+		return strings.Compare(a, b)
+	}
+
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		res := compareKeys(keys[a], keys[b])
+		// При равенстве ключей строки сравниваются целиком, как в GNU sort
+		if res == 0 && !opts.Unique {
+			res = strings.Compare(lines[a], lines[b])
+		}
+		if opts.Reverse {
+			return res > 0
+		}
+		return res < 0
+	})
+
+	for i, idx := range order {
+		if opts.Unique && i > 0 && compareKeys(keys[order[i-1]], keys[idx]) == 0 {
+			continue
+		}
+		if _, err := io.WriteString(cmd.output, lines[idx]+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSortKey разбирает описание ключа вида N или N,M.
+// Нулевое значение верхней границы означает "до конца строки".
+func parseSortKey(key string) (int, int, error) {
+	if key == "" {
+		return 0, 0, nil
+	}
+
+	from, to, hasTo := strings.Cut(key, ",")
+	keyFrom, err := strconv.Atoi(from)
+	if err != nil || keyFrom < 1 {
+		return 0, 0, fmt.Errorf("sort: invalid key specification: %s", key)
+	}
+	keyTo := 0
+	if hasTo {
+		keyTo, err = strconv.Atoi(to)
+		if err != nil || keyTo < keyFrom {
+			return 0, 0, fmt.Errorf("sort: invalid key specification: %s", key)
+		}
+	}
+	return keyFrom, keyTo, nil
+}
+
+// sortKey выделяет из строки поля с номерами [from, to]
+func sortKey(line string, separator string, from int, to int) string {
+	if from == 0 {
+		return line
+	}
+
+	var fields []string
+	joiner := separator
+	if separator == "" {
+		fields = strings.Fields(line)
+		joiner = " "
+	} else {
+		fields = strings.Split(line, separator)
+	}
+
+	if from > len(fields) {
+		return ""
+	}
+	if to == 0 || to > len(fields) {
+		to = len(fields)
+	}
+	return strings.Join(fields[from-1:to], joiner)
+}
+
+// compareNumeric сравнивает строки по числовому префиксу.
+// Строки без числового префикса считаются равными нулю.
+func compareNumeric(a, b string) int {
+	x, y := numericPrefix(a), numericPrefix(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func numericPrefix(s string) float64 {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	end := 0
+	for end < len(s) {
+		c := s[end]
+		if (c >= '0' && c <= '9') || c == '.' || (end == 0 && (c == '-' || c == '+')) {
+			end++
+			continue
+		}
+		break
+	}
+	for ; end > 0; end-- {
+		if value, err := strconv.ParseFloat(s[:end], 64); err == nil {
+			return value
+		}
+	}
+	return 0
+}
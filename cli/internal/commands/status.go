@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ExitStatusError означает, что команда завершилась с ненулевым кодом возврата.
+// В отличие от прочих ошибок, такая ошибка не сопровождается сообщением пользователю.
+type ExitStatusError struct {
+	Status int
+}
+
+func (e ExitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Status)
+}
+
+// StatusError - ошибка команды с кодом возврата Status.
+// В отличие от ExitStatusError, сообщение об ошибке выводится.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e StatusError) Unwrap() error {
+	return e.Err
+}
+
+// ExitError означает, что в сессии была исполнена команда exit.
+// Сессия, получившая такую ошибку, завершается с кодом Status.
+type ExitError struct {
+	Status int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit %d", e.Status)
+}
+
+// ExitStatus возвращает код возврата, соответствующий результату исполнения команды
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var statusErr ExitStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+
+	var withStatus StatusError
+	if errors.As(err, &withStatus) {
+		return withStatus.Status
+	}
+
+	var exitCmdErr ExitError
+	if errors.As(err, &exitCmdErr) {
+		return exitCmdErr.Status
+	}
+
+	var notFound *CommandNotFoundError
+	if errors.As(err, &notFound) {
+		return NotFoundStatus
+	}
+
+	var notExecutable *CommandNotExecutableError
+	if errors.As(err, &notExecutable) {
+		return NotExecutableStatus
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// IsSilent сообщает, что ошибка означает только код возврата
+// и не требует вывода сообщения
+func IsSilent(err error) bool {
+	var statusErr ExitStatusError
+	var exitErr ExitError
+	return errors.As(err, &statusErr) || errors.As(err, &exitErr)
+}
@@ -0,0 +1,348 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"sort"
+	"strconv"
+)
+
+// FromJsonCommand разбирает JSON из файла или входного потока в записи.
+// Потоками ввода-вывода данная структура не владеет.
+type FromJsonCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type fromJsonOptions struct {
+	Positional struct {
+		Filename string
+	} `positional-args:"true" maximum:"1"`
+}
+
+var _ Command = FromJsonCommand{}
+
+// Execute выводит записи, по одной на каждый объект JSON.
+// Если следующая команда не читает записи, они выводятся таблицей.
+func (cmd FromJsonCommand) Execute(ctx context.Context) error {
+	var opts fromJsonOptions
+	if err := arg_parse(&opts, cmd.meta.Args); err != nil {
+		return err
+	}
+
+	in, closeInput, err := open_input(cmd.sess, opts.Positional.Filename, cmd.input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	sink := newRecordSink(cmd.output)
+	if err := decodeRecords(in, sink.Write); err != nil {
+		return err
+	}
+	return sink.Close()
+}
+
+//////////////////////////////////
+
+// ToJsonCommand выводит записи в виде JSON.
+// Потоками ввода-вывода данная структура не владеет.
+type ToJsonCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+type toJsonOptions struct {
+	Lines bool `short:"l" long:"lines"`
+}
+
+var _ Command = ToJsonCommand{}
+
+// Execute выводит записи массивом JSON, по одной записи на строку,
+// а с флагом -l - в формате JSON lines.
+func (cmd ToJsonCommand) Execute(ctx context.Context) error {
+	var opts toJsonOptions
+	if err := arg_parse(&opts, cmd.meta.Args); err != nil {
+		return err
+	}
+
+	count := 0
+	err := readRecords(cmd.input, func(record Record) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		prefix := ""
+		switch {
+		case opts.Lines:
+		case count == 0:
+			prefix = "[\n  "
+		default:
+			prefix = ",\n  "
+		}
+		count++
+		if opts.Lines {
+			data = append(data, '\n')
+		}
+		_, err = io.WriteString(cmd.output, prefix+string(data))
+		return err
+	})
+	if err != nil || opts.Lines {
+		return err
+	}
+
+	suffix := "\n]\n"
+	if count == 0 {
+		suffix = "[]\n"
+	}
+	_, err = io.WriteString(cmd.output, suffix)
+	return err
+}
+
+//////////////////////////////////
+
+// WhereCommand пропускает записи, поле которых удовлетворяет условию.
+// Потоками ввода-вывода данная структура не владеет.
+type WhereCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+var _ Command = WhereCommand{}
+
+// Execute принимает условие вида ПОЛЕ ОПЕРАТОР ЗНАЧЕНИЕ.
+// Операторы ==, !=, <, <=, >, >= (или -eq, -ne, -lt, -le, -gt, -ge) сравнивают числа,
+// если значение поля и операнд - числа, иначе строки; =~ и !~ сопоставляют поле
+// с регулярным выражением.
+// Записи без указанного поля не проходят фильтр.
+// Аргументы разбираются вручную, чтобы операнд мог начинаться с минуса.
+func (cmd WhereCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) != 3 {
+		return fmt.Errorf("where: usage: where FIELD OPERATOR VALUE")
+	}
+	field, op, operand := args[0], args[1], args[2]
+
+	match, err := whereCondition(op, operand)
+	if err != nil {
+		return err
+	}
+
+	sink := newRecordSink(cmd.output)
+	err = readRecords(cmd.input, func(record Record) error {
+		value, ok := record.Get(field)
+		if !ok || !match(value) {
+			return nil
+		}
+		return sink.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	return sink.Close()
+}
+
+// Операторы сравнения в стиле test, которые не нужно экранировать от разбора
+// как перенаправления, и соответствующие им символьные операторы
+var whereTestOperators = map[string]string{
+	"-eq": "==",
+	"-ne": "!=",
+	"-lt": "<",
+	"-le": "<=",
+	"-gt": ">",
+	"-ge": ">=",
+}
+
+// whereCondition создает проверку значения поля для оператора op
+func whereCondition(op string, operand string) (func(any) bool, error) {
+	if symbol, ok := whereTestOperators[op]; ok {
+		op = symbol
+	}
+	switch op {
+	case "=~", "!~":
+		re, err := regexp.Compile(operand)
+		if err != nil {
+			return nil, fmt.Errorf("where: %w", err)
+		}
+		return func(value any) bool {
+			return re.MatchString(formatValue(value)) == (op == "=~")
+		}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("where: %s: unknown operator", op)
+	}
+
+	number, numErr := strconv.ParseFloat(operand, 64)
+	return func(value any) bool {
+		var cmp int
+		if n, ok := numericValue(value); ok && numErr == nil {
+			cmp = compareNumbers(n, number)
+		} else {
+			cmp = compareStrings(formatValue(value), operand)
+		}
+		switch op {
+		case "==":
+			return cmp == 0
+		case "!=":
+			return cmp != 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	}, nil
+}
+
+func compareNumbers(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a string, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+//////////////////////////////////
+
+// SelectCommand оставляет в записях только указанные поля.
+// Потоками ввода-вывода данная структура не владеет.
+type SelectCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+var _ Command = SelectCommand{}
+
+// Execute выводит записи с полями из аргументов в порядке аргументов.
+// Отсутствующее в записи поле получает значение null.
+func (cmd SelectCommand) Execute(ctx context.Context) error {
+	fields := cmd.meta.Args
+	if len(fields) == 0 {
+		return fmt.Errorf("select: no fields given")
+	}
+
+	sink := newRecordSink(cmd.output)
+	err := readRecords(cmd.input, func(record Record) error {
+		selected := NewRecord()
+		for _, field := range fields {
+			value, _ := record.Get(field)
+			selected.Set(field, value)
+		}
+		return sink.Write(selected)
+	})
+	if err != nil {
+		return err
+	}
+	return sink.Close()
+}
+
+//////////////////////////////////
+
+// SortByCommand сортирует записи по значениям полей.
+// Потоками ввода-вывода данная структура не владеет.
+type SortByCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+type sortByOptions struct {
+	Reverse bool `short:"r" long:"reverse"`
+
+	Positional struct {
+		Fields []string `required:"1"`
+	} `positional-args:"true"`
+}
+
+var _ Command = SortByCommand{}
+
+// Execute выводит записи, упорядоченные по первому полю из аргументов,
+// при равенстве - по следующему. Числа сравниваются как числа, прочие значения -
+// как строки; записи без поля идут последними. Сортировка устойчивая.
+func (cmd SortByCommand) Execute(ctx context.Context) error {
+	var opts sortByOptions
+	if err := arg_parse(&opts, cmd.meta.Args); err != nil {
+		return err
+	}
+
+	var records []Record
+	err := readRecords(cmd.input, func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, field := range opts.Positional.Fields {
+			cmp := compareFields(records[i], records[j], field, opts.Reverse)
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+
+	sink := newRecordSink(cmd.output)
+	for _, record := range records {
+		if err := sink.Write(record); err != nil {
+			return err
+		}
+	}
+	return sink.Close()
+}
+
+// compareFields сравнивает значения поля field двух записей, в обратном
+// порядке при reverse. Отсутствующее значение в обоих случаях идет последним.
+func compareFields(a Record, b Record, field string, reverse bool) int {
+	x, okX := a.Get(field)
+	y, okY := b.Get(field)
+	okX, okY = okX && x != nil, okY && y != nil
+	switch {
+	case !okX || !okY:
+		if okX == okY {
+			return 0
+		}
+		if okX {
+			return -1
+		}
+		return 1
+	}
+
+	cmp := compareStrings(formatValue(x), formatValue(y))
+	n, numX := numericValue(x)
+	m, numY := numericValue(y)
+	if numX && numY {
+		cmp = compareNumbers(n, m)
+	}
+	if reverse {
+		return -cmp
+	}
+	return cmp
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordBuffer - выход, который принимает записи, как пайп записей исполнителя
+type recordBuffer struct {
+	records []Record
+}
+
+func (b *recordBuffer) Write(data []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func (b *recordBuffer) WriteRecord(record Record) error {
+	b.records = append(b.records, record)
+	return nil
+}
+
+const people = `[{"name":"bob","age":31,"tags":["a"]},{"name":"al","age":5},{"name":"eve"}]`
+
+func TestDecodeRecords(t *testing.T) {
+	var records []Record
+	input := `{"b":1,"a":{"y":2.5,"x":null}} [true, "s"]` + "\n" + `{"c":"d"}`
+	err := decodeRecords(strings.NewReader(input), func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	require.Equal(t, []string{"b", "a"}, records[0].Keys())
+	value, _ := records[0].Get("b")
+	require.Equal(t, int64(1), value)
+	require.Equal(t, `{"y":2.5,"x":null}`, formatValue(records[0].values["a"]))
+	require.Equal(t, []string{"value"}, records[1].Keys())
+	require.Equal(t, "s", records[2].values["value"])
+	require.Equal(t, "d", records[3].values["c"])
+
+	err = decodeRecords(strings.NewReader(`{"a":`), func(Record) error { return nil })
+	require.Error(t, err)
+}
+
+func TestStructuredCommandsText(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		create   func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command
+		expected string
+	}{
+		{"from-json", nil, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return FromJsonCommand{in, out, meta, newTestSession(t, "")}
+		}, "name  age  tags\nbob   31   [\"a\"]\nal    5\neve\n"},
+		{"where", []string{"age", "-gt", "4"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return WhereCommand{in, out, meta}
+		}, "name  age  tags\nbob   31   [\"a\"]\nal    5\n"},
+		{"where regexp", []string{"name", "!~", "^b"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return WhereCommand{in, out, meta}
+		}, "name  age\nal    5\neve\n"},
+		{"where string", []string{"name", "<=", "bob"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return WhereCommand{in, out, meta}
+		}, "name  age  tags\nbob   31   [\"a\"]\nal    5\n"},
+		{"select", []string{"age", "name"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return SelectCommand{in, out, meta}
+		}, "age  name\n31   bob\n5    al\n     eve\n"},
+		{"sort-by", []string{"age"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return SortByCommand{in, out, meta}
+		}, "name  age  tags\nal    5\nbob   31   [\"a\"]\neve\n"},
+		{"sort-by reverse", []string{"-r", "age"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return SortByCommand{in, out, meta}
+		}, "name  age  tags\nbob   31   [\"a\"]\nal    5\neve\n"},
+		{"to-json", nil, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return ToJsonCommand{in, out, meta}
+		}, "[\n  {\"name\":\"bob\",\"age\":31,\"tags\":[\"a\"]},\n  {\"name\":\"al\",\"age\":5},\n  {\"name\":\"eve\"}\n]\n"},
+		{"to-json lines", []string{"-l"}, func(in io.Reader, out io.Writer, meta command_meta.CommandMeta) Command {
+			return ToJsonCommand{in, out, meta}
+		}, "{\"name\":\"bob\",\"age\":31,\"tags\":[\"a\"]}\n{\"name\":\"al\",\"age\":5}\n{\"name\":\"eve\"}\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: tc.name, Args: tc.args}
+			actual := runWithInput(t, people, func(in io.Reader, out io.Writer) Command {
+				return tc.create(in, out, meta)
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	empty := runWithInput(t, "", func(in io.Reader, out io.Writer) Command {
+		return ToJsonCommand{in, out, command_meta.CommandMeta{Name: "to-json"}}
+	})
+	require.Equal(t, "[]\n", empty)
+}
+
+func TestStructuredCommandsRecords(t *testing.T) {
+	var output recordBuffer
+	meta := command_meta.CommandMeta{Name: "where", Args: []string{"age", "==", "5"}}
+	err := WhereCommand{strings.NewReader(people), &output, meta}.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output.records, 1)
+	require.Equal(t, "al", output.records[0].values["name"])
+
+	err = WhereCommand{strings.NewReader(people), &output, command_meta.CommandMeta{Args: []string{"age", "~", "5"}}}.Execute(context.Background())
+	require.Error(t, err)
+	err = SelectCommand{strings.NewReader(people), &output, command_meta.CommandMeta{}}.Execute(context.Background())
+	require.Error(t, err)
+}
+
+func TestListDirRecords(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte("abc"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	var output recordBuffer
+	err := ListDirCommand{&output, command_meta.CommandMeta{Name: "ls"}, newTestSession(t, dir)}.Execute(context.Background())
+	require.NoError(t, err)
+	require.Len(t, output.records, 2)
+	require.Equal(t, []string{"name", "type", "size", "mode", "modified"}, output.records[0].Keys())
+	require.Equal(t, "file", output.records[0].values["name"])
+	require.Equal(t, int64(3), output.records[0].values["size"])
+	require.Equal(t, "dir", output.records[1].values["type"])
+}
+
+func TestPs(t *testing.T) {
+	if _, err := os.Stat(procDir); err != nil {
+		t.Skip("no procfs")
+	}
+
+	var output recordBuffer
+	err := PsCommand{nil, &output, command_meta.CommandMeta{Name: "ps"}}.Execute(context.Background())
+	require.NoError(t, err)
+
+	found := false
+	for _, record := range output.records {
+		if record.values["pid"] == int64(os.Getpid()) {
+			found = true
+			require.Equal(t, int64(os.Getppid()), record.values["ppid"])
+		}
+	}
+	require.True(t, found)
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Период опроса файла в режиме tail -f
+const tailFollowInterval = 200 * time.Millisecond
+
+// TailCommand выводит последние строки файла или входного потока.
+// Потоками ввода-вывода данная структура не владеет.
+type TailCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type tailOptions struct {
+	// Количество строк. Значение вида +N означает "начиная с N-ой строки"
+	Lines  string `short:"n" default:"10"`
+	Follow bool   `short:"f"`
+
+	Positional struct {
+		Filename string
+	} `positional-args:"true" maximum:"1"`
+}
+
+var _ Command = TailCommand{}
+
+// Execute выводит последние -n строк (по умолчанию 10).
+// С флагом -f после вывода команда продолжает следить за файлом
+// и выводит дописываемые в него данные.
+func (cmd TailCommand) Execute(ctx context.Context) error {
+	var opts tailOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	fromStart := strings.HasPrefix(opts.Lines, "+")
+	count, err := strconv.Atoi(strings.TrimPrefix(opts.Lines, "+"))
+	if err != nil || count < 0 {
+		return fmt.Errorf("tail: invalid number of lines: %s", opts.Lines)
+	}
+
+	in, closeInput, err := open_input(cmd.sess, opts.Positional.Filename, cmd.input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	reader := bufio.NewReader(in)
+	if fromStart {
+		err = cmd.writeFrom(reader, count)
+	} else {
+		err = cmd.writeLast(reader, count)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Следить имеет смысл только за обычным файлом
+	if opts.Follow && opts.Positional.Filename != "" {
+		return cmd.follow(ctx, reader)
+	}
+	return nil
+}
+
+// writeFrom выводит все строки, начиная со строки с номером from (нумерация с 1)
+func (cmd TailCommand) writeFrom(reader *bufio.Reader, from int) error {
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 && lineNum >= from {
+			if _, err := cmd.output.Write(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeLast выводит последние count строк, храня их в кольцевом буфере
+func (cmd TailCommand) writeLast(reader *bufio.Reader, count int) error {
+	if count == 0 {
+		_, err := io.Copy(io.Discard, reader)
+		return err
+	}
+
+	ring := make([][]byte, count)
+	total := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) != 0 {
+			ring[total%count] = line
+			total++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	start := 0
+	if total > count {
+		start = total - count
+	}
+	for i := start; i < total; i++ {
+		if _, err := cmd.output.Write(ring[i%count]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// follow периодически дочитывает новые данные из файла и выводит их,
+// пока не будет отменен контекст
+func (cmd TailCommand) follow(ctx context.Context, reader *bufio.Reader) error {
+	buffer := make([]byte, 4096)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			if _, err := cmd.output.Write(buffer[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(tailFollowInterval):
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+)
+
+// TeeCommand копирует входной поток в выходной и в переданные файлы.
+// Потоками input и output данная структура не владеет.
+type TeeCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type teeOptions struct {
+	Append bool `short:"a"`
+
+	Positional struct {
+		Files []string
+	} `positional-args:"true"`
+}
+
+var _ Command = TeeCommand{}
+
+// Execute дублирует входной поток в output и в каждый из файлов.
+// С флагом -a данные дописываются в конец файлов, иначе файлы перезаписываются.
+// В ограниченном режиме запись в файлы запрещена, как и перенаправление вывода.
+func (cmd TeeCommand) Execute(ctx context.Context) error {
+	var opts teeOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+	if files := opts.Positional.Files; len(files) != 0 && cmd.sess.Restricted() {
+		return &RestrictedError{Subject: files[0], Reason: "cannot redirect output"}
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if opts.Append {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	writers := []io.Writer{cmd.output}
+	for _, filename := range opts.Positional.Files {
+		file, err := cmd.sess.FS().OpenFile(cmd.sess.Path(filename), flag, 0666)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writers = append(writers, file)
+	}
+
+	_, err = io.Copy(io.MultiWriter(writers...), cmd.input)
+	return err
+}
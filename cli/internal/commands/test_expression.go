@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestCommand вычисляет условное выражение (команды test и [).
+// Потоками ввода-вывода данная структура не владеет.
+type TestCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+// Разбор выражения test методом рекурсивного спуска
+type testParser struct {
+	args []string
+	pos  int
+	// Сессия, относительно директории которой разрешаются пути файлов
+	sess *session.Session
+}
+
+var _ Command = TestCommand{}
+
+// Код возврата test при ошибке в выражении
+const TestErrorStatus = 2
+
+// Execute завершается успешно, если выражение истинно, с кодом 1, если ложно,
+// и с кодом TestErrorStatus, если в выражении ошибка.
+// Поддерживаются файловые предикаты (-e -f -d -h -L -p -S -s -r -w -x, -nt -ot -ef),
+// сравнения строк (-z -n = == != < >) и целых чисел (-eq -ne -lt -le -gt -ge),
+// а также отрицание !, связки -a и -o и скобки.
+func (cmd TestCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if cmd.meta.Name == "[" {
+		if len(args) == 0 || args[len(args)-1] != "]" {
+			return StatusError{Status: TestErrorStatus, Err: fmt.Errorf("[: missing `]'")}
+		}
+		args = args[:len(args)-1]
+	}
+
+	// Пустое выражение ложно
+	if len(args) == 0 {
+		return ExitStatusError{Status: 1}
+	}
+
+	p := testParser{args: args, sess: cmd.sess}
+	result, err := p.parseOr()
+	if err != nil {
+		return StatusError{Status: TestErrorStatus, Err: fmt.Errorf("%s: %v", cmd.meta.Name, err)}
+	}
+	if p.pos != len(p.args) {
+		return StatusError{Status: TestErrorStatus, Err: fmt.Errorf("%s: %s: unexpected argument", cmd.meta.Name, p.args[p.pos])}
+	}
+
+	if !result {
+		return ExitStatusError{Status: 1}
+	}
+	return nil
+}
+
+func (p *testParser) peek(offset int) (string, bool) {
+	if p.pos+offset < len(p.args) {
+		return p.args[p.pos+offset], true
+	}
+	return "", false
+}
+
+func (p *testParser) parseOr() (bool, error) {
+	result, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for {
+		if arg, ok := p.peek(0); !ok || arg != "-o" {
+			return result, nil
+		}
+		p.pos++
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		result = result || rhs
+	}
+}
+
+func (p *testParser) parseAnd() (bool, error) {
+	result, err := p.parseNot()
+	if err != nil {
+		return false, err
+	}
+	for {
+		if arg, ok := p.peek(0); !ok || arg != "-a" {
+			return result, nil
+		}
+		p.pos++
+		rhs, err := p.parseNot()
+		if err != nil {
+			return false, err
+		}
+		result = result && rhs
+	}
+}
+
+func (p *testParser) parseNot() (bool, error) {
+	if arg, ok := p.peek(0); ok && arg == "!" {
+		// Одиночный ! - это непустая строка
+		if _, hasNext := p.peek(1); hasNext {
+			p.pos++
+			result, err := p.parseNot()
+			return !result, err
+		}
+	}
+	return p.parsePrimary()
+}
+
+func (p *testParser) parsePrimary() (bool, error) {
+	arg, ok := p.peek(0)
+	if !ok {
+		return false, fmt.Errorf("argument expected")
+	}
+
+	// Бинарный оператор имеет приоритет: "test -f = -f" сравнивает строки
+	if op, ok := p.peek(1); ok && isTestBinary(op) {
+		if rhs, ok := p.peek(2); ok {
+			p.pos += 3
+			return evalTestBinary(p.sess, arg, op, rhs)
+		}
+	}
+
+	if arg == "(" {
+		p.pos++
+		result, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if closing, ok := p.peek(0); !ok || closing != ")" {
+			return false, fmt.Errorf("`)' expected")
+		}
+		p.pos++
+		return result, nil
+	}
+
+	if isTestUnary(arg) {
+		if operand, ok := p.peek(1); ok {
+			p.pos += 2
+			return evalTestUnary(p.sess, arg, operand), nil
+		}
+	}
+
+	p.pos++
+	return arg != "", nil
+}
+
+func isTestUnary(op string) bool {
+	switch op {
+	case "-e", "-f", "-d", "-h", "-L", "-p", "-S", "-s", "-r", "-w", "-x", "-z", "-n":
+		return true
+	}
+	return false
+}
+
+func isTestBinary(op string) bool {
+	switch op {
+	case "=", "==", "!=", "<", ">", "-eq", "-ne", "-lt", "-le", "-gt", "-ge", "-nt", "-ot", "-ef":
+		return true
+	}
+	return false
+}
+
+func evalTestUnary(sess *session.Session, op string, operand string) bool {
+	switch op {
+	case "-z":
+		return operand == ""
+	case "-n":
+		return operand != ""
+	case "-h", "-L":
+		info, err := sess.FS().Lstat(sess.Path(operand))
+		return err == nil && info.Mode()&os.ModeSymlink != 0
+	}
+
+	path := sess.Path(operand)
+	info, err := sess.FS().Stat(path)
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	switch op {
+	case "-f":
+		return mode.IsRegular()
+	case "-d":
+		return mode.IsDir()
+	case "-p":
+		return mode&os.ModeNamedPipe != 0
+	case "-S":
+		return mode&os.ModeSocket != 0
+	case "-s":
+		return info.Size() > 0
+	case "-r", "-w", "-x":
+		return testAccess(sess, path, mode, op)
+	}
+	// -e
+	return true
+}
+
+// Права доступа, которые проверяют -r, -w и -x: для access(2) и биты владельца
+var testAccessModes = map[string]struct {
+	access uint32
+	owner  os.FileMode
+}{
+	"-r": {unix.R_OK, 0400},
+	"-w": {unix.W_OK, 0200},
+	"-x": {unix.X_OK, 0100},
+}
+
+// testAccess сообщает, что у оболочки есть право доступа op к файлу path.
+// Для файлов ОС права проверяет система с эффективными uid и gid процесса,
+// учитывая владельца, группу и права root. В других файловых системах
+// владельца нет, поэтому проверяются права владельца файла.
+func testAccess(sess *session.Session, path string, mode os.FileMode, op string) bool {
+	modes := testAccessModes[op]
+	if sess.FS() == vfs.OS {
+		return unix.Faccessat(unix.AT_FDCWD, path, modes.access, unix.AT_EACCESS) == nil
+	}
+	return mode.Perm()&modes.owner != 0
+}
+
+func evalTestBinary(sess *session.Session, lhs string, op string, rhs string) (bool, error) {
+	switch op {
+	case "=", "==":
+		return lhs == rhs, nil
+	case "!=":
+		return lhs != rhs, nil
+	case "<":
+		return lhs < rhs, nil
+	case ">":
+		return lhs > rhs, nil
+	case "-nt", "-ot", "-ef":
+		return evalTestFiles(sess, lhs, op, rhs), nil
+	}
+
+	a, err := strconv.ParseInt(lhs, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", lhs)
+	}
+	b, err := strconv.ParseInt(rhs, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", rhs)
+	}
+	switch op {
+	case "-eq":
+		return a == b, nil
+	case "-ne":
+		return a != b, nil
+	case "-lt":
+		return a < b, nil
+	case "-le":
+		return a <= b, nil
+	case "-gt":
+		return a > b, nil
+	}
+	// -ge
+	return a >= b, nil
+}
+
+// evalTestFiles сравнивает два файла.
+// Несуществующий файл считается старее любого существующего.
+func evalTestFiles(sess *session.Session, lhs string, op string, rhs string) bool {
+	a, errA := sess.FS().Stat(sess.Path(lhs))
+	b, errB := sess.FS().Stat(sess.Path(rhs))
+	switch op {
+	case "-nt":
+		return errA == nil && (errB != nil || a.ModTime().After(b.ModTime()))
+	case "-ot":
+		return errB == nil && (errA != nil || a.ModTime().Before(b.ModTime()))
+	}
+	// -ef
+	return errA == nil && errB == nil && os.SameFile(a, b)
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/pkg/vfs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestExpression(t *testing.T) {
+	baseDir := t.TempDir()
+	file := filepath.Join(baseDir, "file")
+	script := filepath.Join(baseDir, "script")
+	older := filepath.Join(baseDir, "older")
+	require.NoError(t, os.WriteFile(file, []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(script, nil, 0755))
+	require.NoError(t, os.WriteFile(older, nil, 0644))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(older, past, past))
+
+	cases := []struct {
+		name     string
+		cmd      string
+		args     []string
+		expected bool
+	}{
+		{"file", "test", []string{"-f", file}, true},
+		{"file is not dir", "test", []string{"-d", file}, false},
+		{"dir", "test", []string{"-d", baseDir}, true},
+		{"missing", "test", []string{"-e", filepath.Join(baseDir, "missing")}, false},
+		{"executable", "test", []string{"-x", script}, true},
+		{"not executable", "test", []string{"-x", file}, false},
+		{"non empty file", "test", []string{"-s", file}, true},
+		{"newer", "test", []string{file, "-nt", older}, true},
+		{"older", "test", []string{file, "-ot", older}, false},
+		{"string equal", "test", []string{"abc", "=", "abc"}, true},
+		{"string not equal", "test", []string{"abc", "!=", "abc"}, false},
+		{"empty string", "test", []string{"-z", ""}, true},
+		{"non empty string", "test", []string{"-n", "x"}, true},
+		{"single argument", "test", []string{"x"}, true},
+		{"single empty argument", "test", []string{""}, false},
+		{"no arguments", "test", []string{}, false},
+		{"integers", "test", []string{"10", "-gt", "9"}, true},
+		{"integers le", "test", []string{"-3", "-le", "-4"}, false},
+		{"negation", "test", []string{"!", "-f", file}, false},
+		{"and", "test", []string{"-f", file, "-a", "1", "-eq", "2"}, false},
+		{"or", "test", []string{"-f", file, "-o", "1", "-eq", "2"}, true},
+		{"precedence", "test", []string{"a", "=", "b", "-o", "c", "=", "c", "-a", "d", "=", "d"}, true},
+		{"parentheses", "test", []string{"!", "(", "a", "=", "b", "-o", "c", "=", "c", ")"}, false},
+		{"bracket", "[", []string{"-f", file, "]"}, true},
+		{"bracket false", "[", []string{"1", "-ne", "1", "]"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: tc.cmd, Args: tc.args}
+			err := TestCommand{nil, nil, meta, newTestSession(t, "")}.Execute(context.Background())
+			if tc.expected {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, 1, ExitStatus(err))
+				require.True(t, IsSilent(err))
+			}
+		})
+	}
+}
+
+func TestTestExpressionErrors(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		args []string
+	}{
+		{"[", []string{"-f", "x"}},
+		{"test", []string{"a", "-eq", "1"}},
+		{"test", []string{"(", "a"}},
+		{"test", []string{"a", "b"}},
+	}
+
+	for _, tc := range cases {
+		meta := command_meta.CommandMeta{Name: tc.cmd, Args: tc.args}
+		err := TestCommand{nil, nil, meta, newTestSession(t, "")}.Execute(context.Background())
+		require.Error(t, err)
+		require.False(t, IsSilent(err), "%v", tc.args)
+		require.Equal(t, TestErrorStatus, ExitStatus(err), "%v", tc.args)
+	}
+}
+
+func TestTestAccess(t *testing.T) {
+	// На диске права проверяет система: файл без прав на исполнение
+	// не исполняемый даже для root
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	sess := newTestSession(t, dir)
+	run := func(args ...string) int {
+		meta := command_meta.CommandMeta{Name: "test", Args: args}
+		return ExitStatus(TestCommand{nil, nil, meta, sess}.Execute(context.Background()))
+	}
+	require.Equal(t, 0, run("-r", "file"))
+	require.Equal(t, 0, run("-w", "file"))
+	require.Equal(t, 1, run("-x", "file"))
+	require.NoError(t, os.Chmod(file, 0711))
+	require.Equal(t, 0, run("-x", "file"))
+
+	// В файловой системе в памяти проверяются права владельца
+	fsys := vfs.NewMem()
+	require.NoError(t, vfs.WriteFile(fsys, "/group-only", nil, 0070))
+	require.NoError(t, vfs.WriteFile(fsys, "/owner", nil, 0600))
+	sess.SetFS(fsys)
+	require.NoError(t, sess.Chdir("/"))
+	require.Equal(t, 1, run("-r", "group-only"))
+	require.Equal(t, 0, run("-r", "owner"))
+	require.Equal(t, 0, run("-w", "owner"))
+	require.Equal(t, 1, run("-x", "owner"))
+}
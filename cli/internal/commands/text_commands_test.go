@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runWithInput исполняет команду, подавая ей на вход строку input,
+// и возвращает все, что команда вывела
+func runWithInput(t *testing.T, input string, create func(in io.Reader, out io.Writer) Command) string {
+	var output bytes.Buffer
+	err := create(strings.NewReader(input), &output).Execute(context.Background())
+	require.NoError(t, err)
+	return output.String()
+}
+
+func TestHead(t *testing.T) {
+	input := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"default", []string{}, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"},
+		{"three lines", []string{"-n", "3"}, "1\n2\n3\n"},
+		{"more than input", []string{"-n20"}, input},
+		{"zero", []string{"-n", "0"}, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "head", Args: tc.args}
+			actual := runWithInput(t, input, func(in io.Reader, out io.Writer) Command {
+				return HeadCommand{in, out, meta, newTestSession(t, "")}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	input := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"default", []string{}, "3\n4\n5\n6\n7\n8\n9\n10\n11\n12"},
+		{"two lines", []string{"-n", "2"}, "11\n12"},
+		{"from line", []string{"-n", "+11"}, "11\n12"},
+		{"zero", []string{"-n", "0"}, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "tail", Args: tc.args}
+			actual := runWithInput(t, input, func(in io.Reader, out io.Writer) Command {
+				return TailCommand{in, out, meta, newTestSession(t, "")}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "log")
+	require.NoError(t, os.WriteFile(filename, []byte("first\n"), 0644))
+
+	rp, wp, err := os.Pipe()
+	require.NoError(t, err)
+	defer rp.Close()
+	defer wp.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	meta := command_meta.CommandMeta{Name: "tail", Args: []string{"-f", filename}}
+	go func() {
+		done <- TailCommand{nil, wp, meta, newTestSession(t, "")}.Execute(ctx)
+	}()
+
+	buf := make([]byte, 128)
+	n, err := rp.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "first\n", string(buf[:n]))
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString("second\n")
+	require.NoError(t, err)
+	file.Close()
+
+	n, err = rp.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "second\n", string(buf[:n]))
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestSort(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		args     []string
+		expected string
+	}{
+		{"lexicographic", "b\nc\na\n", []string{}, "a\nb\nc\n"},
+		{"reverse", "b\nc\na\n", []string{"-r"}, "c\nb\na\n"},
+		{"numeric", "10\n9\n100\n-1\n", []string{"-n"}, "-1\n9\n10\n100\n"},
+		{"unique", "b\na\nb\na\n", []string{"-u"}, "a\nb\n"},
+		{"key", "x 3\ny 1\nz 2\n", []string{"-k", "2"}, "y 1\nz 2\nx 3\n"},
+		{"key with separator", "a:10\nb:9\nc:11\n", []string{"-t", ":", "-k", "2", "-n"}, "b:9\na:10\nc:11\n"},
+		{"numeric reverse by count", "      2 b\n     10 a\n      1 c\n", []string{"-nr"}, "     10 a\n      2 b\n      1 c\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "sort", Args: tc.args}
+			actual := runWithInput(t, tc.input, func(in io.Reader, out io.Writer) Command {
+				return SortCommand{in, out, meta, newTestSession(t, "")}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestUniq(t *testing.T) {
+	input := "a\na\nb\nc\nc\nc\na\n"
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"default", []string{}, "a\nb\nc\na\n"},
+		{"count", []string{"-c"}, "      2 a\n      1 b\n      3 c\n      1 a\n"},
+		{"duplicates", []string{"-d"}, "a\nc\n"},
+		{"count duplicates", []string{"-c", "-d"}, "      2 a\n      3 c\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "uniq", Args: tc.args}
+			actual := runWithInput(t, input, func(in io.Reader, out io.Writer) Command {
+				return UniqCommand{in, out, meta, newTestSession(t, "")}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCut(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		args     []string
+		expected string
+	}{
+		{"default delimiter", "a\tb\tc\n", []string{"-f", "2"}, "b\n"},
+		{"list", "a,b,c,d,e\n", []string{"-d", ",", "-f", "1,3"}, "a,c\n"},
+		{"ranges", "a,b,c,d,e\n", []string{"-d,", "-f", "1-2,4-"}, "a,b,d,e\n"},
+		{"no delimiter", "abc\n", []string{"-d", ",", "-f", "2"}, "abc\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "cut", Args: tc.args}
+			actual := runWithInput(t, tc.input, func(in io.Reader, out io.Writer) Command {
+				return CutCommand{in, out, meta, newTestSession(t, "")}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTr(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		args     []string
+		expected string
+	}{
+		{"range", "Hello\n", []string{"a-z", "A-Z"}, "HELLO\n"},
+		{"class", "Hello\n", []string{"[:upper:]", "[:lower:]"}, "hello\n"},
+		{"case non-ASCII", "Straße Ärger ÿ привет\n", []string{"[:lower:]", "[:upper:]"}, "STRAßE ÄRGER Ÿ ПРИВЕТ\n"},
+		{"class ASCII only", "aé1ß\n", []string{"-d", "[:alpha:]"}, "é1ß\n"},
+		{"class to range", "abcé\n", []string{"[:lower:]", "A-Z"}, "ABCé\n"},
+		{"short second set", "abcd", []string{"abcd", "xy"}, "xyyy"},
+		{"delete", "a1b2c3\n", []string{"-d", "0-9"}, "abc\n"},
+		{"squeeze", "a   b  c\n", []string{"-s", " "}, "a b c\n"},
+		{"escape", "a b c", []string{" ", `\n`}, "a\nb\nc"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "tr", Args: tc.args}
+			actual := runWithInput(t, tc.input, func(in io.Reader, out io.Writer) Command {
+				return TrCommand{in, out, meta}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTee(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	require.NoError(t, os.WriteFile(second, []byte("old\n"), 0644))
+
+	meta := command_meta.CommandMeta{Name: "tee", Args: []string{first}}
+	actual := runWithInput(t, "data\n", func(in io.Reader, out io.Writer) Command {
+		return TeeCommand{in, out, meta, newTestSession(t, "")}
+	})
+	require.Equal(t, "data\n", actual)
+
+	meta = command_meta.CommandMeta{Name: "tee", Args: []string{"-a", second}}
+	runWithInput(t, "data\n", func(in io.Reader, out io.Writer) Command {
+		return TeeCommand{in, out, meta, newTestSession(t, "")}
+	})
+
+	content, err := os.ReadFile(first)
+	require.NoError(t, err)
+	require.Equal(t, "data\n", string(content))
+
+	content, err = os.ReadFile(second)
+	require.NoError(t, err)
+	require.Equal(t, "old\ndata\n", string(content))
+}
@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"shell/internal/command_meta"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Код возврата timeout, если время исполнения команды истекло
+const TimeoutStatus = 124
+
+// TimeoutCommand исполняет команду, ограничивая время ее работы.
+// Команда создается той же фабрикой, что и остальные, поэтому может быть
+// как встроенной командой, так и внешней программой.
+// Потоками ввода-вывода данная структура не владеет.
+type TimeoutCommand struct {
+	input   io.Reader
+	output  io.Writer
+	meta    command_meta.CommandMeta
+	factory *CommandFactory
+}
+
+var _ Command = TimeoutCommand{}
+
+// Execute разбирает аргументы DURATION COMMAND [ARG...] и исполняет команду.
+// По истечении времени контекст команды отменяется: внешний процесс получает SIGTERM,
+// у встроенной команды прерываются ожидающие чтение и запись.
+// Команда возвращается только после завершения запущенной команды.
+// В этом случае возвращается код TimeoutStatus, и пайплайн, как при любой ошибке, останавливается.
+// Нулевая длительность означает отсутствие ограничения.
+func (cmd TimeoutCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("timeout: usage: timeout DURATION COMMAND [ARG]...")
+	}
+
+	duration, err := parseTimeoutDuration(args[0])
+	if err != nil {
+		return err
+	}
+
+	meta := command_meta.CommandMeta{Name: args[1], Args: args[2:], Envs: cmd.meta.Envs}
+	if duration == 0 {
+		return cmd.factory.ChildCommand(meta, cmd.input, cmd.output).Execute(ctx)
+	}
+
+	childCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	in, out := cmd.input, cmd.output
+	external := cmd.factory.IsExternal(meta)
+	if !external && in != nil {
+		in = contextReader{childCtx, in}
+	}
+	if !external && out != nil {
+		out = contextWriter{childCtx, out}
+	}
+	child := cmd.factory.ChildCommand(meta, in, out)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- child.Execute(childCtx)
+	}()
+
+	select {
+	case err := <-done:
+		if childCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return ExitStatusError{Status: TimeoutStatus}
+		}
+		return err
+	case <-childCtx.Done():
+	}
+
+	// Внешний процесс завершается после сигнала, встроенная команда - на ближайшем
+	// чтении или записи, которые прерываются отменой. Команду нужно дождаться,
+	// чтобы она не читала ввод оболочки и не писала в вывод после возврата.
+	<-done
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ExitStatusError{Status: TimeoutStatus}
+}
+
+// parseTimeoutDuration разбирает длительность в секундах с необязательным
+// суффиксом s, m, h или d, как в GNU timeout, либо в формате time.ParseDuration
+func parseTimeoutDuration(arg string) (time.Duration, error) {
+	units := map[string]time.Duration{"": time.Second, "s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+	number, suffix := arg, ""
+	if n := len(arg); n > 0 && strings.ContainsRune("smhd", rune(arg[n-1])) {
+		number, suffix = arg[:n-1], arg[n-1:]
+	}
+
+	if value, err := strconv.ParseFloat(number, 64); err == nil && value >= 0 {
+		return time.Duration(value * float64(units[suffix])), nil
+	}
+	if duration, err := time.ParseDuration(arg); err == nil && duration >= 0 {
+		return duration, nil
+	}
+	return 0, fmt.Errorf("timeout: invalid time interval '%s'", arg)
+}
+
+// Интервал, с которым ожидание готовности файла проверяет отмену контекста
+const cancelPollInterval = 50 * time.Millisecond
+
+// Поток чтения, ожидание данных в котором прерывается отменой контекста.
+// Из файла (терминала, пайпа) данные читаются, только когда они уже готовы,
+// поэтому после отмены ввод, предназначенный оболочке, остается непрочитанным.
+// Из других потоков данные читаются в отдельной горутине во внутренний буфер:
+// прерванное чтение завершится само, и его результат будет отброшен.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if file, ok := r.reader.(*os.File); ok {
+		if err := waitFile(r.ctx, file, unix.POLLIN); err != nil {
+			return 0, err
+		}
+		return file.Read(p)
+	}
+
+	type readResult struct {
+		data []byte
+		err  error
+	}
+	result := make(chan readResult, 1)
+	go func() {
+		buffer := make([]byte, len(p))
+		n, err := r.reader.Read(buffer)
+		result <- readResult{buffer[:n], err}
+	}()
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	case res := <-result:
+		return copy(p, res.data), res.err
+	}
+}
+
+// Поток записи, который перестает принимать данные после отмены контекста.
+// Запись в файл дожидается его готовности, и это ожидание прерывается отменой.
+type contextWriter struct {
+	ctx    context.Context
+	writer io.Writer
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if file, ok := w.writer.(*os.File); ok {
+		if err := waitFile(w.ctx, file, unix.POLLOUT); err != nil {
+			return 0, err
+		}
+	}
+	return w.writer.Write(p)
+}
+
+// waitFile ждет, пока файл будет готов к чтению или записи (events),
+// или отмены ctx. Дескриптор берется через SyscallConn, чтобы не переводить
+// файл в блокирующий режим.
+func waitFile(ctx context.Context, file *os.File, events int16) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	for {
+		ready := false
+		var pollErr error
+		err := conn.Control(func(fd uintptr) {
+			fds := []unix.PollFd{{Fd: int32(fd), Events: events}}
+			var n int
+			n, pollErr = unix.Poll(fds, int(cancelPollInterval/time.Millisecond))
+			ready = n > 0
+		})
+		switch {
+		case err != nil:
+			return nil
+		case pollErr != nil && pollErr != unix.EINTR:
+			return nil
+		case ready:
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"shell/internal/command_meta"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutExpires(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	start := time.Now()
+	_, err := runBuiltin(newTestSession(t, ""), "timeout", "0.1", "sleep", "5")
+	require.Equal(t, TimeoutStatus, ExitStatus(err))
+	require.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestTimeoutCompletes(t *testing.T) {
+	output, err := runBuiltin(newTestSession(t, ""), "timeout", "5s", "echo", "done")
+	require.NoError(t, err)
+	require.Equal(t, "done\n", output)
+
+	output, err = runBuiltin(newTestSession(t, ""), "timeout", "0", "echo", "unlimited")
+	require.NoError(t, err)
+	require.Equal(t, "unlimited\n", output)
+
+	_, err = runBuiltin(newTestSession(t, ""), "timeout", "1")
+	require.Error(t, err)
+}
+
+func TestParseTimeoutDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"2":     2 * time.Second,
+		"1.5":   1500 * time.Millisecond,
+		"3m":    3 * time.Minute,
+		"1h":    time.Hour,
+		"1d":    24 * time.Hour,
+		"250ms": 250 * time.Millisecond,
+	}
+	for arg, expected := range cases {
+		duration, err := parseTimeoutDuration(arg)
+		require.NoError(t, err, arg)
+		require.Equal(t, expected, duration, arg)
+	}
+
+	for _, arg := range []string{"", "abc", "-1", "5x"} {
+		_, err := parseTimeoutDuration(arg)
+		require.EqualError(t, err, "timeout: invalid time interval '"+arg+"'")
+	}
+}
+
+func TestTimeoutStopsBuiltin(t *testing.T) {
+	sess := newTestSession(t, "")
+	in, writer, err := os.Pipe()
+	require.NoError(t, err)
+	defer in.Close()
+	defer writer.Close()
+
+	meta := command_meta.CommandMeta{Name: "timeout", Args: []string{"0.1", "read", "x"}}
+	err = TimeoutCommand{in, io.Discard, meta, NewCommandFactory(sess)}.Execute(context.Background())
+	require.Equal(t, TimeoutStatus, ExitStatus(err))
+
+	// read завершилась вместе с timeout: строка, введенная после этого,
+	// не попадает в переменную и остается во вводе оболочки
+	_, err = writer.WriteString("next line\n")
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, ok := sess.Get("x")
+	require.False(t, ok)
+	buf := make([]byte, 64)
+	n, err := in.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "next line\n", string(buf[:n]))
+}
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"unicode"
+)
+
+// TrCommand заменяет или удаляет символы входного потока.
+// Потоками ввода-вывода данная структура не владеет.
+type TrCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+}
+
+type trOptions struct {
+	Delete  bool `short:"d"`
+	Squeeze bool `short:"s"`
+
+	Positional struct {
+		Set1 string `required:"true"`
+		Set2 string
+	} `positional-args:"true"`
+}
+
+// Классы символов, поддерживаемые в наборах tr.
+// Классы раскрываются только в символы ASCII, как в локали POSIX:
+// иначе, например, строчные и прописные буквы Latin-1 не соответствуют
+// друг другу по позициям в наборах.
+var trClasses = map[string]func(rune) bool{
+	"alnum": func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha": unicode.IsLetter,
+	"digit": unicode.IsDigit,
+	"lower": unicode.IsLower,
+	"upper": unicode.IsUpper,
+	"space": unicode.IsSpace,
+	"punct": unicode.IsPunct,
+}
+
+// Замены регистра, которые применяются ко всем буквам Unicode,
+// а не только к символам классов
+var trCaseConversions = map[[2]string]func(rune) rune{
+	{"[:lower:]", "[:upper:]"}: unicode.ToUpper,
+	{"[:upper:]", "[:lower:]"}: unicode.ToLower,
+}
+
+var _ Command = TrCommand{}
+
+// Execute переводит символы из первого набора в соответствующие символы второго.
+// Наборы поддерживают диапазоны (a-z), классы ([:lower:]) и экранирование (\n, \t, \\).
+// Пара наборов [:lower:] и [:upper:] меняет регистр любых букв, в том числе не ASCII.
+// С флагом -d символы первого набора удаляются, с флагом -s повторы символов
+// последнего набора схлопываются в один.
+func (cmd TrCommand) Execute(ctx context.Context) error {
+	var opts trOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	set1, err := expandTrSet(opts.Positional.Set1)
+	if err != nil {
+		return err
+	}
+	set2, err := expandTrSet(opts.Positional.Set2)
+	if err != nil {
+		return err
+	}
+	if !opts.Delete && !opts.Squeeze && len(set2) == 0 {
+		return fmt.Errorf("tr: missing operand after %q", opts.Positional.Set1)
+	}
+
+	var convert func(rune) rune
+	if !opts.Delete {
+		convert = trCaseConversions[[2]string{opts.Positional.Set1, opts.Positional.Set2}]
+	}
+	translation := make(map[rune]rune)
+	if !opts.Delete && len(set2) != 0 {
+		for i, r := range set1 {
+			// Если второй набор короче, он дополняется своим последним символом
+			translation[r] = set2[min(i, len(set2)-1)]
+		}
+	}
+
+	deleted := make(map[rune]bool)
+	if opts.Delete {
+		for _, r := range set1 {
+			deleted[r] = true
+		}
+	}
+
+	squeezeSet := set1
+	if len(set2) != 0 {
+		squeezeSet = set2
+	}
+	squeezed := make(map[rune]bool)
+	if opts.Squeeze {
+		for _, r := range squeezeSet {
+			squeezed[r] = true
+		}
+	}
+
+	reader := bufio.NewReader(cmd.input)
+	writer := bufio.NewWriter(cmd.output)
+	var last rune = -1
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if deleted[r] {
+			continue
+		}
+		if convert != nil {
+			r = convert(r)
+		} else if mapped, ok := translation[r]; ok {
+			r = mapped
+		}
+		if squeezed[r] && r == last {
+			continue
+		}
+		last = r
+
+		if _, err := writer.WriteRune(r); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// expandTrSet раскрывает описание набора символов в список символов
+func expandTrSet(set string) ([]rune, error) {
+	runes := []rune(set)
+	result := make([]rune, 0, len(runes))
+
+	for i := 0; i < len(runes); i++ {
+		// Класс символов вида [:lower:]
+		if runes[i] == '[' && i+1 < len(runes) && runes[i+1] == ':' {
+			end := i + 2
+			for end+1 < len(runes) && !(runes[end] == ':' && runes[end+1] == ']') {
+				end++
+			}
+			if end+1 >= len(runes) {
+				return nil, fmt.Errorf("tr: unterminated character class in %q", set)
+			}
+			name := string(runes[i+2 : end])
+			class, ok := trClasses[name]
+			if !ok {
+				return nil, fmt.Errorf("tr: invalid character class %q", name)
+			}
+			for r := rune(0); r <= unicode.MaxASCII; r++ {
+				if class(r) {
+					result = append(result, r)
+				}
+			}
+			i = end + 1
+			continue
+		}
+
+		r := runes[i]
+		if r == '\\' && i+1 < len(runes) {
+			i++
+			r = unescapeRune(runes[i])
+		}
+
+		// Диапазон вида a-z
+		if i+2 < len(runes) && runes[i+1] == '-' {
+			to := runes[i+2]
+			if to < r {
+				return nil, fmt.Errorf("tr: range-endpoints of '%c-%c' are in reverse collating sequence order", r, to)
+			}
+			for c := r; c <= to; c++ {
+				result = append(result, c)
+			}
+			i += 2
+			continue
+		}
+
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// unescapeRune возвращает символ, соответствующий escape-последовательности \r
+func unescapeRune(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'a':
+		return '\a'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	}
+	return r
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// TrapCommand задает, выводит и сбрасывает обработчики сигналов и событий EXIT, ERR.
+// Потоками ввода-вывода данная структура не владеет.
+type TrapCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+var _ Command = TrapCommand{}
+
+// Сигналы, для которых можно задать обработчик, в порядке номеров
+var trapSignals = []struct {
+	name   string
+	signal syscall.Signal
+}{
+	{"HUP", syscall.SIGHUP},
+	{"INT", syscall.SIGINT},
+	{"QUIT", syscall.SIGQUIT},
+	{"USR1", syscall.SIGUSR1},
+	{"USR2", syscall.SIGUSR2},
+	{"TERM", syscall.SIGTERM},
+}
+
+// TrapSignals возвращает сигналы, для которых можно задать обработчик
+func TrapSignals() []syscall.Signal {
+	signals := make([]syscall.Signal, len(trapSignals))
+	for i, s := range trapSignals {
+		signals[i] = s.signal
+	}
+	return signals
+}
+
+// SignalTrapName возвращает имя события trap, соответствующее сигналу
+func SignalTrapName(signal syscall.Signal) (string, bool) {
+	for _, s := range trapSignals {
+		if s.signal == signal {
+			return s.name, true
+		}
+	}
+	return "", false
+}
+
+// parseTrapName приводит имя события к виду, в котором обработчики хранятся в сессии.
+// Имена сигналов принимаются в любом регистре, с префиксом SIG или без него, а также номером.
+func parseTrapName(spec string) (string, error) {
+	name := strings.TrimPrefix(strings.ToUpper(spec), "SIG")
+	if number, err := strconv.Atoi(spec); err == nil {
+		if number == 0 {
+			return session.TrapExit, nil
+		}
+		if name, ok := SignalTrapName(syscall.Signal(number)); ok {
+			return name, nil
+		}
+	}
+	switch name {
+	case session.TrapExit, session.TrapErr:
+		return name, nil
+	}
+	for _, s := range trapSignals {
+		if s.name == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("trap: %s: invalid signal specification", spec)
+}
+
+// displayTrapName возвращает имя события в том виде, в котором его выводит trap -p
+func displayTrapName(name string) string {
+	if name == session.TrapExit || name == session.TrapErr {
+		return name
+	}
+	return "SIG" + name
+}
+
+// Execute разбирает аргументы в форме trap [-lp] [[код] событие ...].
+// Код "-" или отсутствие кода при единственном событии сбрасывает обработчики,
+// пустой код означает, что событие игнорируется.
+// Без аргументов и с флагом -p выводит обработчики в виде, пригодном для повторного ввода.
+// Аргументы разбираются вручную, так как код обработчика "-" go-flags принимает за флаг.
+func (cmd TrapCommand) Execute(ctx context.Context) error {
+	args := cmd.meta.Args
+	switch {
+	case len(args) > 0 && args[0] == "-l":
+		return cmd.listSignals()
+	case len(args) > 0 && args[0] == "-p":
+		return cmd.printTraps(args[1:])
+	case len(args) > 0 && args[0] == "--":
+		args = args[1:]
+	case len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-':
+		return fmt.Errorf("trap: %s: invalid option", args[0])
+	}
+	if len(args) == 0 {
+		return cmd.printTraps(nil)
+	}
+
+	code, specs := args[0], args[1:]
+	reset := code == "-"
+	if len(specs) == 0 {
+		code, specs, reset = "", args, true
+	}
+
+	for _, spec := range specs {
+		name, err := parseTrapName(spec)
+		if err != nil {
+			return err
+		}
+		if reset {
+			cmd.sess.ResetTrap(name)
+		} else {
+			cmd.sess.SetTrap(name, code)
+		}
+	}
+	return nil
+}
+
+// printTraps выводит обработчики переданных событий, а без аргументов - все обработчики
+func (cmd TrapCommand) printTraps(specs []string) error {
+	names := cmd.sess.Traps()
+	if len(specs) != 0 {
+		names = names[:0]
+		for _, spec := range specs {
+			name, err := parseTrapName(spec)
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		code, ok := cmd.sess.Trap(name)
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Fprintf(cmd.output, "trap -- %s %s\n", ShellQuote(code), displayTrapName(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSignals выводит номера и имена сигналов, для которых можно задать обработчик
+func (cmd TrapCommand) listSignals() error {
+	for _, s := range trapSignals {
+		if _, err := fmt.Fprintf(cmd.output, "%2d) SIG%s\n", int(s.signal), s.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrapSetAndPrint(t *testing.T) {
+	sess := newTestSession(t, "")
+
+	_, err := runBuiltin(sess, "trap", "rm -f $tmp", "EXIT", "sigterm", "2")
+	require.NoError(t, err)
+	_, err = runBuiltin(sess, "trap", "", "HUP")
+	require.NoError(t, err)
+
+	code, ok := sess.Trap("TERM")
+	require.True(t, ok)
+	require.Equal(t, "rm -f $tmp", code)
+
+	output, err := runBuiltin(sess, "trap")
+	require.NoError(t, err)
+	require.Equal(t, "trap -- 'rm -f $tmp' EXIT\n"+
+		"trap -- '' SIGHUP\n"+
+		"trap -- 'rm -f $tmp' SIGINT\n"+
+		"trap -- 'rm -f $tmp' SIGTERM\n", output)
+
+	output, err = runBuiltin(sess, "trap", "-p", "INT", "USR1")
+	require.NoError(t, err)
+	require.Equal(t, "trap -- 'rm -f $tmp' SIGINT\n", output)
+}
+
+func TestTrapReset(t *testing.T) {
+	sess := newTestSession(t, "")
+	sess.SetTrap("INT", "echo int")
+	sess.SetTrap("TERM", "echo term")
+	sess.SetTrap("EXIT", "echo exit")
+
+	_, err := runBuiltin(sess, "trap", "-", "INT", "TERM")
+	require.NoError(t, err)
+	_, err = runBuiltin(sess, "trap", "0")
+	require.NoError(t, err)
+	require.Empty(t, sess.Traps())
+}
+
+func TestTrapErrors(t *testing.T) {
+	sess := newTestSession(t, "")
+
+	_, err := runBuiltin(sess, "trap", "echo", "NOPE")
+	require.EqualError(t, err, "trap: NOPE: invalid signal specification")
+
+	_, err = runBuiltin(sess, "trap", "-x")
+	require.EqualError(t, err, "trap: -x: invalid option")
+}
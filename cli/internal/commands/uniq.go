@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+)
+
+// UniqCommand схлопывает подряд идущие одинаковые строки.
+// Потоками ввода-вывода данная структура не владеет.
+type UniqCommand struct {
+	input  io.Reader
+	output io.Writer
+	meta   command_meta.CommandMeta
+	sess   *session.Session
+}
+
+type uniqOptions struct {
+	Count          bool `short:"c"`
+	OnlyDuplicates bool `short:"d"`
+
+	Positional struct {
+		Filename string
+	} `positional-args:"true" maximum:"1"`
+}
+
+var _ Command = UniqCommand{}
+
+// Execute выводит по одной строке из каждой группы одинаковых соседних строк.
+// С флагом -c перед строкой выводится размер группы,
+// с флагом -d выводятся только группы из нескольких строк.
+func (cmd UniqCommand) Execute(ctx context.Context) error {
+	var opts uniqOptions
+	err := arg_parse(&opts, cmd.meta.Args)
+	if err != nil {
+		return err
+	}
+
+	in, closeInput, err := open_input(cmd.sess, opts.Positional.Filename, cmd.input)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	lines, err := read_lines(in)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for end < len(lines) && lines[end] == lines[start] {
+			end++
+		}
+
+		count := end - start
+		if !opts.OnlyDuplicates || count > 1 {
+			line := lines[start] + "\n"
+			if opts.Count {
+				line = fmt.Sprintf("%7d %s", count, line)
+			}
+			if _, err := io.WriteString(cmd.output, line); err != nil {
+				return err
+			}
+		}
+		start = end
+	}
+	return nil
+}
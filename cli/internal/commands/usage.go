@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Usage накапливает процессорное время внешних процессов, запущенных командами.
+// Используется ключевым словом time: ProcessCommand добавляет в Usage из своего контекста
+// время каждого завершившегося процесса.
+type Usage struct {
+	mutex sync.Mutex
+	user  time.Duration
+	sys   time.Duration
+}
+
+// Add добавляет время завершившегося процесса
+func (u *Usage) Add(state *os.ProcessState) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.user += state.UserTime()
+	u.sys += state.SystemTime()
+}
+
+// Times возвращает суммарное время в пользовательском режиме и в режиме ядра
+func (u *Usage) Times() (user time.Duration, sys time.Duration) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.user, u.sys
+}
+
+type usageKey struct{}
+
+// WithUsage возвращает контекст, в котором команды учитывают время процессов в usage
+func WithUsage(ctx context.Context, usage *Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, usage)
+}
+
+// usageFrom возвращает Usage из контекста или nil
+func usageFrom(ctx context.Context) *Usage {
+	usage, _ := ctx.Value(usageKey{}).(*Usage)
+	return usage
+}
@@ -1,6 +1,18 @@
 package commands
 
-import "github.com/jessevdk/go-flags"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/parser"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
 
 // arg_parse парсит аргументы команды в переданную структуру
 func arg_parse[Rcv any, PtrRcv *Rcv](rcv PtrRcv, args []string) error {
@@ -13,3 +25,100 @@ func arg_parse[Rcv any, PtrRcv *Rcv](rcv PtrRcv, args []string) error {
 	}
 	return nil
 }
+
+// open_input открывает файл с переданным именем для чтения.
+// Относительный путь разрешается относительно директории сессии sess.
+// Если имя файла пустое, то возвращается дескриптор fallback.
+// Возвращаемую функцию закрытия необходимо вызвать после окончания чтения.
+func open_input(sess *session.Session, filename string, fallback io.Reader) (io.Reader, func(), error) {
+	if filename == "" {
+		return fallback, func() {}, nil
+	}
+
+	file, err := vfs.Open(sess.FS(), sess.Path(filename))
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// read_lines читает все строки из потока, отбрасывая символы перевода строки
+func read_lines(in io.Reader) ([]string, error) {
+	var lines []string
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) != 0 {
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+// splitLeadingFlags отделяет флаги, стоящие до имени запускаемой команды, от самой команды.
+// Флаги из withValue забирают следующий аргумент как значение, -- завершает флаги.
+func splitLeadingFlags(name string, args []string, withValue ...string) ([]string, []string, error) {
+	end := 0
+	for end < len(args) && strings.HasPrefix(args[end], "-") {
+		if args[end] == "--" {
+			return args[:end], args[end+1:], nil
+		}
+		for _, flag := range withValue {
+			if args[end] == flag {
+				end++
+				break
+			}
+		}
+		end++
+	}
+	if end > len(args) {
+		return nil, nil, fmt.Errorf("%s: option requires an argument: %s", name, args[len(args)-1])
+	}
+	return args[:end], args[end:], nil
+}
+
+// snapshotEnv возвращает копию переменных и позиционных параметров сессии.
+// Копию можно передать токенизатору, пока команды сессии исполняются.
+func snapshotEnv(sess *session.Session) *envsholder.Env {
+	env := &envsholder.Env{}
+	env.Init()
+	sess.WithEnv(func(vars *envsholder.Env) {
+		for name, value := range vars.Vars {
+			env.Set(name, value)
+		}
+		env.Params = append([]string(nil), vars.Params...)
+	})
+	return env
+}
+
+// parsePipeline разбирает строку оболочки, содержащую ровно один пайплайн
+func parsePipeline(script string, env *envsholder.Env, options parser.ExpansionOptions) ([]command_meta.CommandMeta, error) {
+	tokenizer := parser.NewTokenizer(strings.NewReader(script+"\n"), env)
+	tokenizer.SetOptions(options)
+	p := parser.NewParser(tokenizer)
+
+	var result []command_meta.CommandMeta
+	for {
+		metas, err := p.Parse()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(metas) != 0 {
+			if result != nil {
+				return nil, fmt.Errorf("more than one pipeline")
+			}
+			result = metas
+		}
+		if err == io.EOF {
+			if result == nil {
+				return nil, fmt.Errorf("empty command")
+			}
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WatchCommand периодически исполняет пайплайн в той же сессии
+// и перерисовывает его вывод на месте.
+// Потоками ввода-вывода данная структура не владеет.
+type WatchCommand struct {
+	input   io.Reader
+	output  io.Writer
+	meta    command_meta.CommandMeta
+	factory *CommandFactory
+}
+
+type watchOptions struct {
+	Interval    string `short:"n" long:"interval" default:"2"`
+	Differences bool   `short:"d" long:"differences"`
+}
+
+var _ Command = WatchCommand{}
+
+// Наименьший интервал между запусками пайплайна
+const watchMinInterval = 100 * time.Millisecond
+
+// Управляющие последовательности терминала, которыми watch перерисовывает экран
+const (
+	termHome        = "\x1b[H"
+	termClearLine   = "\x1b[K"
+	termClearScreen = "\x1b[J"
+	termHideCursor  = "\x1b[?25l"
+	termShowCursor  = "\x1b[?25h"
+	termReverse     = "\x1b[7m"
+	termReset       = "\x1b[0m"
+)
+
+// Execute исполняет пайплайн каждые -n секунд (по умолчанию 2, допускаются дробные)
+// до отмены контекста (Ctrl-C). Аргументы команды объединяются пробелами
+// и разбираются как строка оболочки перед каждым запуском, поэтому пайплайн
+// видит текущие значения переменных сессии.
+// Экран перерисовывается с верхнего левого угла без очистки, чтобы не мерцать:
+// заголовок с интервалом, командой и временем, затем вывод пайплайна.
+// С -d символы, изменившиеся с прошлого запуска, выделяются инверсией цвета.
+// Прерывание не считается ошибкой: курсор возвращается, и команда завершается.
+func (cmd WatchCommand) Execute(ctx context.Context) error {
+	var opts watchOptions
+	script, err := cmd.parseArgs(&opts)
+	if err != nil {
+		return err
+	}
+	seconds, err := strconv.ParseFloat(opts.Interval, 64)
+	if err != nil || seconds < 0 {
+		return fmt.Errorf("watch: invalid interval: %s", opts.Interval)
+	}
+	interval := max(time.Duration(seconds*float64(time.Second)), watchMinInterval)
+
+	runner := cmd.factory.PipelineRunner()
+	if runner == nil {
+		return fmt.Errorf("watch: pipelines cannot be run in this context")
+	}
+
+	if _, err := io.WriteString(cmd.output, termHideCursor); err != nil {
+		return err
+	}
+	defer io.WriteString(cmd.output, termShowCursor)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	sess := cmd.factory.Session()
+	previous, drawn := "", false
+	for {
+		metas, err := parsePipeline(script, snapshotEnv(sess), sess)
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+
+		var result bytes.Buffer
+		// Пайплайн не должен читать вход самого watch
+		status, err := runner.RunPipeline(ctx, strings.NewReader(""), &result, metas)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !IsSilent(err) {
+			fmt.Fprintf(&result, "%v\n", err)
+		}
+
+		header := fmt.Sprintf("Every %ss: %s    %s", strconv.FormatFloat(interval.Seconds(), 'f', -1, 64),
+			script, time.Now().Format(time.DateTime))
+		if status != 0 {
+			header += fmt.Sprintf("    (exit status %d)", status)
+		}
+		text := result.String()
+		if err := cmd.draw(header, text, previous, opts.Differences && drawn); err != nil {
+			return err
+		}
+		previous, drawn = text, true
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw перерисовывает экран: каждая строка дополняется очисткой до конца строки,
+// а остаток экрана после вывода очищается
+func (cmd WatchCommand) draw(header string, text string, previous string, differences bool) error {
+	if differences {
+		text = highlightChanges(previous, text)
+	}
+
+	var screen strings.Builder
+	screen.WriteString(termHome)
+	screen.WriteString(header + termClearLine + "\n" + termClearLine + "\n")
+	for _, line := range strings.SplitAfter(text, "\n") {
+		screen.WriteString(strings.TrimSuffix(line, "\n") + termClearLine)
+		if strings.HasSuffix(line, "\n") {
+			screen.WriteString("\n")
+		}
+	}
+	screen.WriteString(termClearScreen)
+	_, err := io.WriteString(cmd.output, screen.String())
+	return err
+}
+
+// highlightChanges выделяет в тексте current символы, которые отличаются
+// от символов в той же строке и позиции текста previous
+func highlightChanges(previous string, current string) string {
+	previousLines := strings.Split(previous, "\n")
+	var result strings.Builder
+	for i, line := range strings.Split(current, "\n") {
+		if i > 0 {
+			result.WriteString("\n")
+		}
+		var old []rune
+		if i < len(previousLines) {
+			old = []rune(previousLines[i])
+		}
+
+		highlighted := false
+		for j, r := range []rune(line) {
+			changed := j >= len(old) || old[j] != r
+			if changed != highlighted {
+				if changed {
+					result.WriteString(termReverse)
+				} else {
+					result.WriteString(termReset)
+				}
+				highlighted = changed
+			}
+			result.WriteRune(r)
+		}
+		if highlighted {
+			result.WriteString(termReset)
+		}
+	}
+	return result.String()
+}
+
+// parseArgs разбирает флаги watch, стоящие до пайплайна.
+// Возвращает текст пайплайна.
+func (cmd WatchCommand) parseArgs(opts *watchOptions) (string, error) {
+	flagArgs, pipeline, err := splitLeadingFlags("watch", cmd.meta.Args, "-n", "--interval")
+	if err != nil {
+		return "", err
+	}
+	if err := arg_parse(opts, flagArgs); err != nil {
+		return "", err
+	}
+	if len(pipeline) == 0 {
+		return "", fmt.Errorf("watch: usage: watch [-n SECONDS] [-d] COMMAND [ARG]...")
+	}
+	return strings.Join(pipeline, " "), nil
+}
@@ -0,0 +1,17 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlightChanges(t *testing.T) {
+	previous := "ready 1/3\nweb\n"
+	current := "ready 3/3\nweb-2\nnew\n"
+	expected := "ready " + termReverse + "3" + termReset + "/3\n" +
+		"web" + termReverse + "-2" + termReset + "\n" +
+		termReverse + "new" + termReset + "\n"
+	require.Equal(t, expected, highlightChanges(previous, current))
+	require.Equal(t, current, highlightChanges(current, current))
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// XargsCommand строит и запускает команды из аргументов, прочитанных со входа.
+// Команды создаются той же фабрикой, что и остальные, поэтому xargs
+// умеет запускать как встроенные команды, так и внешние программы.
+// Потоками ввода-вывода данная структура не владеет.
+type XargsCommand struct {
+	input   io.Reader
+	output  io.Writer
+	meta    command_meta.CommandMeta
+	factory *CommandFactory
+}
+
+type xargsOptions struct {
+	MaxArgs  int    `short:"n" default:"0"`
+	NullSep  bool   `short:"0"`
+	Replace  string `short:"I"`
+	MaxProcs int    `short:"P" default:"1"`
+}
+
+var _ Command = XargsCommand{}
+
+// Execute читает аргументы из input и запускает с ними команду-шаблон
+// (по умолчанию echo).
+// Флаг -n ограничивает число аргументов на один запуск,
+// -0 означает, что аргументы разделены нулевым байтом,
+// -I STR запускает команду для каждой строки входа, подставляя ее вместо STR,
+// -P N запускает до N команд параллельно (0 - без ограничений).
+func (cmd XargsCommand) Execute(ctx context.Context) error {
+	var opts xargsOptions
+	template, err := cmd.parseArgs(&opts)
+	if err != nil {
+		return err
+	}
+	if opts.MaxArgs < 0 || opts.MaxProcs < 0 {
+		return fmt.Errorf("xargs: invalid number of arguments or processes")
+	}
+
+	data, err := io.ReadAll(cmd.input)
+	if err != nil {
+		return err
+	}
+
+	var items []string
+	switch {
+	case opts.NullSep:
+		items = strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+		if len(items) == 1 && items[0] == "" {
+			items = nil
+		}
+	case opts.Replace != "":
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) != "" {
+				items = append(items, line)
+			}
+		}
+	default:
+		items = strings.Fields(string(data))
+	}
+
+	var metas []command_meta.CommandMeta
+	if opts.Replace != "" {
+		for _, item := range items {
+			meta := command_meta.CommandMeta{Name: strings.ReplaceAll(template[0], opts.Replace, item)}
+			for _, arg := range template[1:] {
+				meta.Args = append(meta.Args, strings.ReplaceAll(arg, opts.Replace, item))
+			}
+			metas = append(metas, meta)
+		}
+	} else {
+		batch := opts.MaxArgs
+		if batch == 0 {
+			batch = max(len(items), 1)
+		}
+		for start := 0; start < len(items) || start == 0; start += batch {
+			end := min(start+batch, len(items))
+			args := append(append([]string{}, template[1:]...), items[start:end]...)
+			metas = append(metas, command_meta.CommandMeta{Name: template[0], Args: args})
+			if end == len(items) {
+				break
+			}
+		}
+	}
+
+	// Ошибка одной из команд не останавливает остальные, как и в GNU xargs,
+	// но после отмены контекста новые команды не запускаются
+	var eg errgroup.Group
+	if opts.MaxProcs > 0 {
+		eg.SetLimit(opts.MaxProcs)
+	}
+	// Параллельно запущенные команды пишут в общий поток вывода
+	output := cmd.output
+	if opts.MaxProcs != 1 {
+		output = session.NewSyncWriter(output)
+	}
+	for _, meta := range metas {
+		if ctx.Err() != nil {
+			break
+		}
+		// Запущенные команды не должны читать вход самого xargs
+		child := cmd.factory.ChildCommand(meta, strings.NewReader(""), output)
+		eg.Go(func() error {
+			return child.Execute(ctx)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// parseArgs разбирает флаги xargs, стоящие до имени команды.
+// Возвращает шаблон команды: имя и начальные аргументы.
+func (cmd XargsCommand) parseArgs(opts *xargsOptions) ([]string, error) {
+	flags, template, err := splitLeadingFlags("xargs", cmd.meta.Args, "-n", "-I", "-P")
+	if err != nil {
+		return nil, err
+	}
+	if err := arg_parse(opts, flags); err != nil {
+		return nil, err
+	}
+
+	if len(template) == 0 {
+		template = []string{"echo"}
+	}
+	return template, nil
+}
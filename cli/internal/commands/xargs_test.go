@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"shell/internal/command_meta"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXargs(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		args     []string
+		expected string
+	}{
+		{"default echo", "a b\nc\n", []string{}, "a b c\n"},
+		{"template", "a b c\n", []string{"echo", "x"}, "x a b c\n"},
+		{"max args", "a b c\n", []string{"-n", "2", "echo"}, "a b\nc\n"},
+		{"null separated", "a b\x00c\x00", []string{"-0", "-n", "1"}, "a b\nc\n"},
+		{"replace", "one\ntwo\n", []string{"-I", "{}", "echo", "<{}>"}, "<one>\n<two>\n"},
+		{"empty input", "", []string{"echo", "x"}, "x\n"},
+		{"end of options", "a b\n", []string{"-n", "1", "--", "echo"}, "a\nb\n"},
+		{"child input", "a\nb\n", []string{"-I", "{}", "head", "-n", "1"}, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := command_meta.CommandMeta{Name: "xargs", Args: tc.args}
+			actual := runWithInput(t, tc.input, func(in io.Reader, out io.Writer) Command {
+				return XargsCommand{in, out, meta, NewCommandFactory(newTestSession(t, ""))}
+			})
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestXargsParallel(t *testing.T) {
+	meta := command_meta.CommandMeta{Name: "xargs", Args: []string{"-P", "4", "-n", "1", "echo"}}
+	actual := runWithInput(t, "1 2 3 4 5 6 7 8\n", func(in io.Reader, out io.Writer) Command {
+		return XargsCommand{in, out, meta, NewCommandFactory(newTestSession(t, ""))}
+	})
+
+	lines := strings.Split(strings.TrimSpace(actual), "\n")
+	sort.Strings(lines)
+	require.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8"}, lines)
+}
+
+func TestXargsMissingValue(t *testing.T) {
+	meta := command_meta.CommandMeta{Name: "xargs", Args: []string{"-n"}}
+	err := XargsCommand{strings.NewReader(""), io.Discard, meta, NewCommandFactory(newTestSession(t, ""))}.Execute(context.Background())
+	require.EqualError(t, err, "xargs: option requires an argument: -n")
+}
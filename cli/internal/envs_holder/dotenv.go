@@ -0,0 +1,272 @@
+package envsholder
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Переменная, прочитанная из файла .env
+type DotenvVar struct {
+	Name  string
+	Value string
+}
+
+// ParseDotenv разбирает файл .env: строки вида [export] ИМЯ=значение,
+// пустые строки и комментарии, начинающиеся с #.
+// Значение в одинарных кавычках берется как есть, в двойных кавычках
+// обрабатываются экранирования \n, \t, \r, \", \\, \$ и может занимать несколько строк.
+// Значение без кавычек обрезается по пробелам и комментарию " #".
+// В значениях без кавычек и в двойных кавычках подставляются $ИМЯ, ${ИМЯ}
+// и ${ИМЯ:-значение по умолчанию}: сначала из переменных, заданных выше в файле,
+// затем из lookup (может быть nil). Переменные возвращаются в порядке появления в файле.
+func ParseDotenv(r io.Reader, lookup func(name string) (string, bool)) ([]DotenvVar, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := dotenvParser{text: []rune(string(data)), line: 1, lookup: lookup, values: map[string]string{}}
+
+	var vars []DotenvVar
+	for {
+		p.skipSpaces()
+		switch {
+		case p.eof():
+			return vars, nil
+		case p.peek() == '\n':
+			p.advance()
+			continue
+		case p.peek() == '#':
+			p.skipComment()
+			continue
+		}
+
+		variable, err := p.parseVar()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", p.line, err)
+		}
+		p.values[variable.Name] = variable.Value
+		vars = append(vars, variable)
+	}
+}
+
+type dotenvParser struct {
+	text []rune
+	pos  int
+	// Номер текущей строки для сообщений об ошибках
+	line   int
+	lookup func(name string) (string, bool)
+	// Переменные, уже прочитанные из файла
+	values map[string]string
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *dotenvParser) peek() rune {
+	return p.text[p.pos]
+}
+
+func (p *dotenvParser) advance() rune {
+	r := p.text[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func (p *dotenvParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\r') {
+		p.advance()
+	}
+}
+
+// skipComment пропускает комментарий до конца строки, не включая перевод строки
+func (p *dotenvParser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.advance()
+	}
+}
+
+// parseVar разбирает строку [export] ИМЯ=значение вместе с переводом строки
+func (p *dotenvParser) parseVar() (DotenvVar, error) {
+	name := p.parseName()
+	if name == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipSpaces()
+		name = p.parseName()
+	}
+	if name == "" {
+		return DotenvVar{}, fmt.Errorf("invalid variable name")
+	}
+	p.skipSpaces()
+	if p.eof() || p.peek() != '=' {
+		return DotenvVar{}, fmt.Errorf("expected %s=VALUE", name)
+	}
+	p.advance()
+	p.skipSpaces()
+
+	var value string
+	var err error
+	quoted := !p.eof() && (p.peek() == '\'' || p.peek() == '"')
+	switch {
+	case !quoted:
+		value, err = p.parseUnquoted()
+	case p.peek() == '\'':
+		value, err = p.parseSingleQuoted()
+	default:
+		value, err = p.parseDoubleQuoted()
+	}
+	if err != nil {
+		return DotenvVar{}, err
+	}
+
+	if quoted {
+		p.skipSpaces()
+		if !p.eof() && p.peek() == '#' {
+			p.skipComment()
+		}
+		if !p.eof() && p.peek() != '\n' {
+			return DotenvVar{}, fmt.Errorf("unexpected characters after quoted value")
+		}
+	}
+	if !p.eof() {
+		p.advance()
+	}
+	return DotenvVar{Name: name, Value: value}, nil
+}
+
+// parseName читает имя переменной оболочки: буквы, цифры и _, не с цифры
+func (p *dotenvParser) parseName() string {
+	start := p.pos
+	for !p.eof() && isNameRune(p.peek(), p.pos == start) {
+		p.advance()
+	}
+	return string(p.text[start:p.pos])
+}
+
+func isNameRune(r rune, first bool) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || !first && r >= '0' && r <= '9'
+}
+
+// parseUnquoted читает значение без кавычек до конца строки или комментария
+func (p *dotenvParser) parseUnquoted() (string, error) {
+	var value strings.Builder
+	for !p.eof() && p.peek() != '\n' {
+		r := p.peek()
+		if r == '#' && (value.Len() == 0 || strings.ContainsRune(" \t", p.text[p.pos-1])) {
+			p.skipComment()
+			break
+		}
+		if r == '$' {
+			if err := p.interpolate(&value); err != nil {
+				return "", err
+			}
+			continue
+		}
+		value.WriteRune(p.advance())
+	}
+	return strings.TrimRight(value.String(), " \t\r"), nil
+}
+
+func (p *dotenvParser) parseSingleQuoted() (string, error) {
+	p.advance()
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' {
+		p.advance()
+	}
+	if p.eof() {
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+	value := string(p.text[start:p.pos])
+	p.advance()
+	return value, nil
+}
+
+// Экранирования, которые обрабатываются в значениях в двойных кавычках
+var dotenvEscapes = map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', '"': '"', '\\': '\\', '$': '$'}
+
+func (p *dotenvParser) parseDoubleQuoted() (string, error) {
+	p.advance()
+	var value strings.Builder
+	for !p.eof() && p.peek() != '"' {
+		switch p.peek() {
+		case '\\':
+			p.advance()
+			if p.eof() {
+				break
+			}
+			if escaped, ok := dotenvEscapes[p.peek()]; ok {
+				p.advance()
+				value.WriteRune(escaped)
+			} else {
+				value.WriteRune('\\')
+			}
+		case '$':
+			if err := p.interpolate(&value); err != nil {
+				return "", err
+			}
+		default:
+			value.WriteRune(p.advance())
+		}
+	}
+	if p.eof() {
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+	p.advance()
+	return value.String(), nil
+}
+
+// interpolate подставляет в value переменную, начинающуюся с $ в текущей позиции.
+// $, за которым не следует имя или {, остается как есть.
+func (p *dotenvParser) interpolate(value *strings.Builder) error {
+	p.advance()
+	if p.eof() || p.peek() != '{' {
+		name := p.parseName()
+		if name == "" {
+			value.WriteRune('$')
+		} else {
+			value.WriteString(p.resolve(name))
+		}
+		return nil
+	}
+
+	p.advance()
+	name := p.parseName()
+	if name == "" {
+		return fmt.Errorf("invalid variable name in ${...}")
+	}
+	var fallback *strings.Builder
+	if p.pos+1 < len(p.text) && p.peek() == ':' && p.text[p.pos+1] == '-' {
+		p.pos += 2
+		fallback = &strings.Builder{}
+		for !p.eof() && p.peek() != '}' && p.peek() != '\n' {
+			fallback.WriteRune(p.advance())
+		}
+	}
+	if p.eof() || p.peek() != '}' {
+		return fmt.Errorf("unterminated ${%s", name)
+	}
+	p.advance()
+
+	resolved := p.resolve(name)
+	if resolved == "" && fallback != nil {
+		resolved = fallback.String()
+	}
+	value.WriteString(resolved)
+	return nil
+}
+
+// resolve возвращает значение переменной, заданной выше в файле или найденной lookup
+func (p *dotenvParser) resolve(name string) string {
+	if value, ok := p.values[name]; ok {
+		return value
+	}
+	if p.lookup != nil {
+		if value, ok := p.lookup(name); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -7,6 +7,8 @@ import (
 // Ассоциативный контейнер - хранилище переменных окружения
 type Env struct {
 	Vars map[string]string
+	// Позиционные параметры $1, $2, ..., которые задает set --
+	Params []string
 }
 
 // Получить все переменные окружения в виде набора строк вида "ключ=значение"
@@ -23,6 +25,11 @@ func (e *Env) Set(key string, value string) {
 	e.Vars[key] = value
 }
 
+// Удалить переменную окружения
+func (e *Env) Unset(key string) {
+	delete(e.Vars, key)
+}
+
 // Очистить все переменные окружения
 func (e *Env) Clear() {
 	e.Vars = make(map[string]string)
@@ -40,11 +47,9 @@ func (e *Env) Init() {
 const (
 	ExecStatusKey = "?"
 	OkStatusValue = "0"
-)
 
-//////////////////////////////////
-
-// Хранилище переменных окружения
-var GlobalEnv = Env{
-	Vars: map[string]string{ExecStatusKey: OkStatusValue},
-}
+	// Переменная с разделителями полей
+	IFSKey = "IFS"
+	// Разделители полей, используемые при отсутствии переменной IFS
+	DefaultIFS = " \t\n"
+)
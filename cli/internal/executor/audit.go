@@ -0,0 +1,213 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/internal/commands"
+	"shell/internal/session"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Значение, которым в журнале заменяются значения чувствительных переменных
+const Redacted = "[REDACTED]"
+
+// AuditLog - журнал исполненных пайплайнов в формате JSON lines.
+// Один журнал может использоваться несколькими сессиями одновременно,
+// каждая запись выводится в поток целиком одной строкой.
+type AuditLog struct {
+	mutex  sync.Mutex
+	output io.Writer
+	// Шаблоны имен переменных (в синтаксисе filepath.Match), значения которых скрываются
+	redact []string
+}
+
+// Запись журнала об одном пайплайне
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"session"`
+	Dir     string    `json:"cwd"`
+	// Команды после подстановок и раскрытия алиасов
+	Commands []AuditCommand `json:"commands"`
+	Duration float64        `json:"duration_ms"`
+	// Коды возврата команд пайплайна в порядке их следования
+	Statuses []int `json:"statuses"`
+	// Код возврата пайплайна
+	Status int `json:"status"`
+}
+
+// Команда пайплайна в записи журнала.
+// Значения локальных переменных команды не записываются, только их имена.
+type AuditCommand struct {
+	Name      string          `json:"name"`
+	Args      []string        `json:"args"`
+	Envs      []string        `json:"envs,omitempty"`
+	Redirects []AuditRedirect `json:"redirects,omitempty"`
+}
+
+// Перенаправление ввода-вывода команды в записи журнала
+type AuditRedirect struct {
+	Op     string `json:"op"`
+	Target string `json:"target"`
+}
+
+// NewAuditLog создает журнал, который пишет записи в output.
+// Значения переменных, имена которых подходят под один из шаблонов redact,
+// заменяются в записях на Redacted.
+func NewAuditLog(output io.Writer, redact []string) (*AuditLog, error) {
+	for _, pattern := range redact {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("audit: invalid redact pattern %q", pattern)
+		}
+	}
+	return &AuditLog{output: output, redact: redact}, nil
+}
+
+// Write записывает в журнал запись о пайплайне
+func (log *AuditLog) Write(record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	_, err = log.output.Write(append(data, '\n'))
+	return err
+}
+
+// finish дописывает в запись длительность и коды возврата и записывает ее в журнал.
+// Ошибка записи выводится в stderr сессии и не влияет на результат команд.
+func (log *AuditLog) finish(sess *session.Session, record AuditRecord, statuses []int, status int) {
+	record.Duration = float64(time.Since(record.Time).Microseconds()) / 1000
+	record.Statuses = statuses
+	record.Status = status
+	if err := log.Write(record); err != nil {
+		fmt.Fprintf(sess.Stderr, "audit: %s\n", err)
+	}
+}
+
+// sensitive сообщает, что значение переменной name нужно скрыть
+func (log *AuditLog) sensitive(name string) bool {
+	for _, pattern := range log.redact {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRecord составляет запись о пайплайне metas, исполненном в сессии sess.
+// Значения чувствительных переменных сессии и локальных переменных команд
+// скрываются там, где они встречаются после подстановки целым словом (не внутри
+// других букв и цифр), а в аргументах вида ИМЯ=ЗНАЧЕНИЕ с чувствительным
+// именем скрывается значение.
+func (log *AuditLog) NewRecord(sess *session.Session, metas []command_meta.CommandMeta) AuditRecord {
+	var secrets []string
+	addSecret := func(name string, value string) {
+		if value != "" && log.sensitive(name) {
+			secrets = append(secrets, value)
+		}
+	}
+	for _, variable := range sess.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		addSecret(name, value)
+	}
+	for _, meta := range metas {
+		for name, value := range meta.Envs.Vars {
+			addSecret(name, value)
+		}
+	}
+	// Более длинные значения заменяются первыми, чтобы не оставлять их части
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	hide := func(word string) string {
+		if name, _, ok := strings.Cut(word, "="); ok && name != "" && log.sensitive(name) {
+			return name + "=" + Redacted
+		}
+		for _, secret := range secrets {
+			word = redactWhole(word, secret)
+		}
+		return word
+	}
+
+	record := AuditRecord{Session: sess.ID, Dir: sess.Dir(), Commands: []AuditCommand{}}
+	for _, meta := range metas {
+		command := AuditCommand{Name: hide(meta.Name), Args: []string{}}
+		for _, arg := range meta.Args {
+			command.Args = append(command.Args, hide(arg))
+		}
+		for name := range meta.Envs.Vars {
+			command.Envs = append(command.Envs, name)
+		}
+		sort.Strings(command.Envs)
+		for _, redirect := range meta.Redirects {
+			command.Redirects = append(command.Redirects, AuditRedirect{Op: redirect.Op, Target: hide(redirect.Target)})
+		}
+		record.Commands = append(record.Commands, command)
+	}
+	return record
+}
+
+// redactWhole заменяет на Redacted вхождения secret в word, которые не являются
+// частью более длинного слова: соседние символы - не буквы, не цифры и не _
+func redactWhole(word string, secret string) string {
+	var result strings.Builder
+	for {
+		i := strings.Index(word, secret)
+		if i < 0 {
+			result.WriteString(word)
+			return result.String()
+		}
+		end := i + len(secret)
+		before, _ := utf8.DecodeLastRuneInString(word[:i])
+		after, _ := utf8.DecodeRuneInString(word[end:])
+		if isWordRune(before) || isWordRune(after) {
+			// Вхождение внутри слова пропускается, следующее ищется со следующего символа
+			_, size := utf8.DecodeRuneInString(word[i:])
+			result.WriteString(word[:i+size])
+			word = word[i+size:]
+			continue
+		}
+		result.WriteString(word[:i])
+		result.WriteString(Redacted)
+		word = word[end:]
+	}
+}
+
+// isWordRune сообщает, что r - буква, цифра или _
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+//////////////////////////////////
+
+// auditedCommand исполняет команду, которую запустила встроенная команда
+// (xargs, find -exec, timeout, command), и записывает ее в журнал
+type auditedCommand struct {
+	cmd   commands.Command
+	meta  command_meta.CommandMeta
+	audit *AuditLog
+	sess  *session.Session
+}
+
+var _ commands.Command = auditedCommand{}
+
+func (c auditedCommand) Execute(ctx context.Context) error {
+	metas := []command_meta.CommandMeta{c.meta}
+	record := c.audit.NewRecord(c.sess, metas)
+	record.Time = time.Now()
+
+	err := c.cmd.Execute(ctx)
+	status := commands.ExitStatus(err)
+	c.audit.finish(c.sess, record, []int{status}, status)
+	return err
+}
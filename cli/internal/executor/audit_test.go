@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	var log bytes.Buffer
+	audit, err := NewAuditLog(&log, []string{"*TOKEN*", "PASSWORD"})
+	require.NoError(t, err)
+
+	sess := newTestSession(t)
+	sess.Set("API_TOKEN", "s3cr3t")
+	pf := NewPipelineFactory(sess)
+	pf.SetAuditLog(audit)
+
+	metas := []command_meta.CommandMeta{
+		{Name: "test", Args: []string{"1", "-eq", "2"}},
+		{
+			Name: "echo",
+			Args: []string{"token=s3cr3t", "PASSWORD=hunter2"},
+			Envs: envsholder.Env{Vars: map[string]string{"PASSWORD": "hunter2", "LANG": "C"}},
+		},
+	}
+	p := pf.CreatePipeline(nil, io.Discard, metas)
+	err = p.Execute(context.Background())
+	require.Equal(t, 0, p.Status(err))
+	require.NotContains(t, log.String(), "s3cr3t")
+	require.NotContains(t, log.String(), "hunter2")
+
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	require.Len(t, lines, 1)
+
+	var record AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, sess.ID, record.Session)
+	require.Equal(t, sess.Dir(), record.Dir)
+	require.False(t, record.Time.IsZero())
+	require.Equal(t, []int{1, 0}, record.Statuses)
+	require.Equal(t, 0, record.Status)
+	require.Equal(t, []AuditCommand{
+		{Name: "test", Args: []string{"1", "-eq", "2"}},
+		{Name: "echo", Args: []string{"token=" + Redacted, "PASSWORD=" + Redacted}, Envs: []string{"LANG", "PASSWORD"}},
+	}, record.Commands)
+}
+
+func TestAuditLogInvalidPattern(t *testing.T) {
+	_, err := NewAuditLog(io.Discard, []string{"[TOKEN"})
+	require.Error(t, err)
+}
+
+func TestAuditLogRedactsWholeWords(t *testing.T) {
+	var log bytes.Buffer
+	audit, err := NewAuditLog(&log, []string{"*TOKEN*"})
+	require.NoError(t, err)
+
+	sess := newTestSession(t)
+	sess.Set("SHORT_TOKEN", "at")
+	record := audit.NewRecord(sess, []command_meta.CommandMeta{
+		{Name: "cat", Args: []string{"at", "path/at", "-H", "Bearer at", "cat", "at_x", "flat", "ät"}},
+	})
+	require.Equal(t, []string{Redacted, "path/" + Redacted, "-H", "Bearer " + Redacted, "cat", "at_x", "flat", "ät"}, record.Commands[0].Args)
+	require.Equal(t, "cat", record.Commands[0].Name)
+}
+
+func TestAuditLogChildCommands(t *testing.T) {
+	var log bytes.Buffer
+	audit, err := NewAuditLog(&log, nil)
+	require.NoError(t, err)
+
+	sess := newTestSession(t)
+	pf := NewPipelineFactory(sess)
+	pf.SetAuditLog(audit)
+
+	metas := []command_meta.CommandMeta{
+		{Name: "echo", Args: []string{"a b"}},
+		{Name: "xargs", Args: []string{"-n", "1", "timeout", "5", "test", "b", "="}},
+	}
+	p := pf.CreatePipeline(nil, io.Discard, metas)
+	err = p.Execute(context.Background())
+	require.Equal(t, 1, p.Status(err))
+
+	var names [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n") {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		var pipeline []string
+		for _, command := range record.Commands {
+			pipeline = append(pipeline, strings.Join(append([]string{command.Name}, command.Args...), " "))
+		}
+		names = append(names, append(pipeline, strconv.Itoa(record.Status)))
+	}
+	// Дочерние команды записываются по завершении, раньше запустившей их команды
+	require.Equal(t, [][]string{
+		{"test b = a", "1"},
+		{"timeout 5 test b = a", "1"},
+		{"test b = b", "0"},
+		{"timeout 5 test b = b", "0"},
+		{"echo a b", "xargs -n 1 timeout 5 test b =", "1"},
+	}, names)
+}
@@ -1,85 +1,248 @@
 package executor
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"shell/internal/command_meta"
 	"shell/internal/commands"
+	"shell/internal/session"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
-// Пара дескрипторов на чтение и на запись
+// Пара концов пайпа на чтение и на запись
 type PipePair struct {
-	input  *os.File
-	output *os.File
+	input  io.ReadCloser
+	output io.WriteCloser
 }
 
 // Набор команд, соединенных пайпами
 type Pipeline struct {
 	cmds  []commands.Command
+	metas []command_meta.CommandMeta
 	pipes []PipePair
+	// Считать код возврата по последней неудачной команде (опция pipefail)
+	pipefail bool
+	// Журнал исполненных пайплайнов, nil - журнал не ведется
+	audit *AuditLog
+	sess  *session.Session
 }
 
+// Ошибка, которой завершилась одна из команд пайплайна
+type StageError struct {
+	// Номер команды в пайплайне, начиная с нуля
+	Stage int
+	// Количество команд в пайплайне
+	Total int
+	// Имя команды
+	Name string
+	Err  error
+}
+
+func (e *StageError) Error() string {
+	if e.Total > 1 {
+		return fmt.Sprintf("pipeline stage %d/%d (%s): %v", e.Stage+1, e.Total, e.Name, e.Err)
+	}
+	if e.Name == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Код возврата пайплайна, прерванного пользователем
+const InterruptedStatus = 130
+
+// Код возврата команды, завершенной из-за закрытия читающей стороны пайпа (128+SIGPIPE)
+const BrokenPipeStatus = 141
+
 // Выполнить пайплайн из команд.
-// В случае ошибки какой-либо из команд пайплайна, все остальные завершают свою работу.
-// Значение ошибки возвращается в вызывающую функцию.
-func (p Pipeline) Execute() error {
-	var eg errgroup.Group
-	chs := make([]chan bool, len(p.cmds))
-	for ch_i := range chs {
-		chs[ch_i] = make(chan bool)
+// В случае ошибки какой-либо из команд пайплайна, все остальные завершают свою работу:
+// контекст команд отменяется (внешние процессы получают сигнал), а ожидание на пайпах прерывается,
+// чтобы разблокировать встроенные команды, ожидающие чтения или записи.
+// Возвращается ошибка той команды, которая завершилась неудачно первой, в виде StageError.
+// Если пайплайн прерван отменой переданного контекста, возвращается код InterruptedStatus.
+// Если задан журнал, после завершения в него записываются команды пайплайна и их коды возврата.
+func (p Pipeline) Execute(ctx context.Context) error {
+	var record AuditRecord
+	if p.audit != nil {
+		record = p.audit.NewRecord(p.sess, p.metas)
+		record.Time = time.Now()
+	}
+	statuses := make([]int, len(p.cmds))
+
+	stagesCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eg, stagesCtx := errgroup.WithContext(stagesCtx)
+
+	var closeOnce sync.Once
+	closeAll := func() {
+		for _, pipe := range p.pipes {
+			unblock(pipe.input)
+			unblock(pipe.output)
+		}
 	}
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-stagesCtx.Done():
+			closeOnce.Do(closeAll)
+		case <-stopped:
+		}
+	}()
 
 	for cmd_i, cmd := range p.cmds {
 		cmd_ii := cmd_i
 		cmdd := cmd
 		eg.Go(func() error {
-			defer func() {
-				chs[cmd_ii] <- true
-			}()
-			res := cmdd.Execute()
-			return res
+			err := cmdd.Execute(stagesCtx)
+			statuses[cmd_ii] = commands.ExitStatus(err)
+			if isBrokenPipe(err) {
+				statuses[cmd_ii] = BrokenPipeStatus
+			}
+
+			// Завершившаяся команда закрывает свои концы пайпов:
+			// соседи получают EOF или ошибку записи
+			if cmd_ii > 0 {
+				p.pipes[cmd_ii-1].input.Close()
+			}
+			if cmd_ii < len(p.pipes) {
+				p.pipes[cmd_ii].output.Close()
+			}
+
+			// Ошибки, вызванные остановкой пайплайна или закрытием читающей стороны,
+			// не являются причиной сбоя
+			if err == nil || stagesCtx.Err() != nil || isBrokenPipe(err) {
+				return nil
+			}
+			return &StageError{Stage: cmd_ii, Total: len(p.cmds), Name: p.metas[cmd_ii].Name, Err: err}
 		})
 	}
 
-	for cmd_i := range p.cmds {
-		<-chs[cmd_i]
-		if cmd_i > 0 {
-			p.pipes[cmd_i-1].input.Close()
-		}
-		if cmd_i < len(p.pipes) {
-			p.pipes[cmd_i].output.Close()
-		}
+	err := eg.Wait()
+	if err == nil && ctx.Err() != nil {
+		err = commands.ExitStatusError{Status: InterruptedStatus}
+	}
+
+	if p.audit != nil {
+		p.audit.finish(p.sess, record, statuses, p.Status(err))
+	}
+	return err
+}
+
+// unblock прерывает ожидающие чтение и запись на конце пайпа.
+// Файловый дескриптор не закрывается, так как в этот момент он может
+// передаваться запускаемому внешнему процессу; закрывает его сама команда.
+func unblock(end io.Closer) {
+	if file, ok := end.(*os.File); ok {
+		file.SetDeadline(time.Now())
+		return
 	}
+	end.Close()
+}
 
-	return eg.Wait()
+// Status возвращает код возврата пайплайна по ошибке, которую вернул Execute.
+// Как и в bash, без опции pipefail код возврата определяется последней командой,
+// поэтому сбой одной из предыдущих команд дает код 0.
+func (p Pipeline) Status(err error) int {
+	var stageErr *StageError
+	if !p.pipefail && errors.As(err, &stageErr) && stageErr.Stage < stageErr.Total-1 {
+		return 0
+	}
+	return commands.ExitStatus(err)
+}
+
+// isBrokenPipe сообщает, что команда завершилась из-за того,
+// что читающая сторона пайпа была закрыта
+func isBrokenPipe(err error) bool {
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		return true
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		return ok && status.Signaled() && status.Signal() == syscall.SIGPIPE
+	}
+	return false
 }
 
 type PipelineFactory struct {
 	cmdFactory *commands.CommandFactory
+	audit      *AuditLog
+}
+
+// Создает фабрику пайплайнов, команды которых работают с сессией sess
+// Фабрика передает себя фабрике команд как исполнитель пайплайнов.
+func NewPipelineFactory(sess *session.Session) *PipelineFactory {
+	factory := &PipelineFactory{cmdFactory: commands.NewCommandFactory(sess)}
+	factory.cmdFactory.SetPipelineRunner(factory)
+	return factory
+}
+
+var _ commands.PipelineRunner = &PipelineFactory{}
+
+// RunPipeline создает и исполняет пайплайн так же, как пайплайн, введенный в сессии.
+// Через него встроенные команды (parallel) запускают собственные пайплайны.
+func (self *PipelineFactory) RunPipeline(ctx context.Context, input io.Reader, output io.Writer, metas []command_meta.CommandMeta) (int, error) {
+	if len(metas) == 0 {
+		return 0, nil
+	}
+	pipeline := self.CreatePipeline(input, output, metas)
+	if pipeline == nil {
+		return 1, fmt.Errorf("cannot create pipeline")
+	}
+	err := pipeline.Execute(ctx)
+	return pipeline.Status(err), err
+}
+
+// WrapCommand оборачивает команду, которую встроенная команда запускает сама,
+// чтобы записать ее в журнал так же, как пайплайн из одной команды
+func (self *PipelineFactory) WrapCommand(meta command_meta.CommandMeta, cmd commands.Command) commands.Command {
+	if self.audit == nil {
+		return cmd
+	}
+	return auditedCommand{cmd, meta, self.audit, self.cmdFactory.Session()}
 }
 
-func NewPipelineFactory() *PipelineFactory {
-	cmFactory := commands.CommandFactory{}
-	return &PipelineFactory{cmdFactory: &cmFactory}
+// SetAuditLog включает запись создаваемых пайплайнов в журнал, nil выключает ее
+func (self *PipelineFactory) SetAuditLog(audit *AuditLog) {
+	self.audit = audit
 }
 
-// Создает пайплайн исполнения на основе переданной информации о командах
-func (self *PipelineFactory) CreatePipeline(input *os.File, output *os.File, metas []command_meta.CommandMeta) *Pipeline {
+// Создает пайплайн исполнения на основе переданной информации о командах.
+// Встроенные команды соединяются буферизованными пайпами в памяти,
+// пайп ядра создается, только если хотя бы одна из соседних команд - внешний процесс.
+func (self *PipelineFactory) CreatePipeline(input io.Reader, output io.Writer, metas []command_meta.CommandMeta) *Pipeline {
 	if len(metas) <= 0 {
 		return nil
 	}
 
-	var pipeline *Pipeline = &Pipeline{}
+	metas = self.expandAliases(metas)
+
+	sess := self.cmdFactory.Session()
+	var pipeline *Pipeline = &Pipeline{pipefail: sess.Option(session.OptionPipefail), audit: self.audit, sess: sess}
 	fokgobak := false
 	for i := 0; i < len(metas); i++ {
 		if i < len(metas)-1 {
-			r, w, err := os.Pipe()
+			pipe, err := self.createPipe(metas[i], metas[i+1])
 			if err != nil {
 				fokgobak = true
 				break
 			}
-			pipeline.pipes = append(pipeline.pipes, PipePair{r, w})
+			pipeline.pipes = append(pipeline.pipes, pipe)
 		}
 		in := input
 		if i > 0 {
@@ -89,8 +252,8 @@ func (self *PipelineFactory) CreatePipeline(input *os.File, output *os.File, met
 		if i < len(metas)-1 {
 			out = pipeline.pipes[i].output
 		}
-		cmd := self.cmdFactory.CommandFromMeta(metas[i], in, out)
-		pipeline.cmds = append(pipeline.cmds, cmd)
+		pipeline.cmds = append(pipeline.cmds, self.command(metas[i], in, out))
+		pipeline.metas = append(pipeline.metas, metas[i])
 	}
 
 	if fokgobak {
@@ -103,3 +266,63 @@ func (self *PipelineFactory) CreatePipeline(input *os.File, output *os.File, met
 
 	return pipeline
 }
+
+// command создает команду пайплайна. Команды с подстановками процессов
+// и перенаправлениями оборачиваются, чтобы подготовить их при исполнении.
+func (self *PipelineFactory) command(meta command_meta.CommandMeta, input io.Reader, output io.Writer) commands.Command {
+	switch {
+	case len(meta.Substitutions) != 0:
+		return substitutedCommand{self, meta, input, output}
+	case len(meta.Redirects) != 0:
+		return redirectedCommand{self.cmdFactory, meta, input, output}
+	}
+	return self.cmdFactory.CommandFromMeta(meta, input, output)
+}
+
+// createPipe создает пайп между командами writer и reader.
+// С опцией structured две встроенные команды, которые умеют выводить и читать записи,
+// соединяются пайпом записей.
+func (self *PipelineFactory) createPipe(writer command_meta.CommandMeta, reader command_meta.CommandMeta) (PipePair, error) {
+	if self.cmdFactory.Session().Option(session.OptionStructured) &&
+		self.cmdFactory.ProducesRecords(writer) && self.cmdFactory.ConsumesRecords(reader) {
+		r, w := newRecordPipe()
+		return PipePair{r, w}, nil
+	}
+	if self.cmdFactory.IsExternal(writer) || self.cmdFactory.IsExternal(reader) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return PipePair{}, err
+		}
+		return PipePair{r, w}, nil
+	}
+
+	r, w := newMemPipe()
+	return PipePair{r, w}, nil
+}
+
+// expandAliases заменяет имена команд, для которых в сессии задан алиас,
+// на значение алиаса. Значение разбивается на слова по пробельным символам,
+// слова после первого становятся аргументами перед аргументами команды.
+// Алиас не раскрывается повторно внутри собственного значения.
+func (self *PipelineFactory) expandAliases(metas []command_meta.CommandMeta) []command_meta.CommandMeta {
+	sess := self.cmdFactory.Session()
+	result := make([]command_meta.CommandMeta, len(metas))
+	for i, meta := range metas {
+		expanded := map[string]bool{}
+		for !expanded[meta.Name] {
+			value, ok := sess.Alias(meta.Name)
+			if !ok {
+				break
+			}
+			expanded[meta.Name] = true
+			words := strings.Fields(value)
+			if len(words) == 0 {
+				break
+			}
+			meta.Name = words[0]
+			meta.Args = append(words[1:len(words):len(words)], meta.Args...)
+		}
+		result[i] = meta
+	}
+	return result
+}
@@ -2,15 +2,33 @@ package executor
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"shell/internal/command_meta"
+	"shell/internal/commands"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
+// newTestSession создает сессию в текущей директории процесса
+func newTestSession(t *testing.T) *session.Session {
+	sess, err := session.New("", io.Discard)
+	require.NoError(t, err)
+	return sess
+}
+
 func TestExecutorEmpty(t *testing.T) {
-	pf := NewPipelineFactory()
+	pf := NewPipelineFactory(newTestSession(t))
 	p := pf.CreatePipeline(os.Stdin, os.Stdout, []command_meta.CommandMeta{})
-  	if p != nil {
+	if p != nil {
 		t.Fatal("Empty pipeline is not nil")
 	}
 }
@@ -39,9 +57,9 @@ func TestExecutorEchoCatCat(t *testing.T) {
 	}
 	defer rp.Close()
 
-	pf := NewPipelineFactory()
+	pf := NewPipelineFactory(newTestSession(t))
 	p := pf.CreatePipeline(nil, wp, metas)
-	err = p.Execute()
+	err = p.Execute(context.Background())
 	wp.Close()
 	if err != nil {
 		t.Fatal("Can't execute pipe", err)
@@ -82,9 +100,9 @@ func TestExecutorEchoCatWc(t *testing.T) {
 	}
 	defer rp.Close()
 
-	pf := NewPipelineFactory()
+	pf := NewPipelineFactory(newTestSession(t))
 	p := pf.CreatePipeline(nil, wp, metas)
-	err = p.Execute()
+	err = p.Execute(context.Background())
 	wp.Close()
 	if err != nil {
 		t.Fatal("Can't execute pipe", err)
@@ -99,3 +117,361 @@ func TestExecutorEchoCatWc(t *testing.T) {
 		t.Fatalf(`Different outputs: %q != %q`, buf[:n-1], []byte(expected))
 	}
 }
+
+func TestExecutorStageFailure(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "log")
+	require.NoError(t, err)
+	file.Close()
+
+	metas := []command_meta.CommandMeta{
+		{Name: "tail", Args: []string{"-f", file.Name()}},
+		{Name: "grep", Args: []string{"["}},
+		{Name: "wc", Args: []string{}},
+	}
+
+	rp, wp, err := os.Pipe()
+	require.NoError(t, err)
+	defer rp.Close()
+	defer wp.Close()
+
+	pf := NewPipelineFactory(newTestSession(t))
+	p := pf.CreatePipeline(nil, wp, metas)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Execute(context.Background())
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pipeline was not cancelled after stage failure")
+	}
+
+	var stageErr *StageError
+	require.ErrorAs(t, err, &stageErr)
+	require.Equal(t, 1, stageErr.Stage)
+	require.Equal(t, "grep", stageErr.Name)
+}
+
+func TestExecutorCancelsProcesses(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	metas := []command_meta.CommandMeta{
+		{Name: "sleep", Args: []string{"30"}},
+		{Name: "grep", Args: []string{"["}},
+	}
+
+	pf := NewPipelineFactory(newTestSession(t))
+	p := pf.CreatePipeline(nil, os.Stdout, metas)
+
+	start := time.Now()
+	err := p.Execute(context.Background())
+	require.Less(t, time.Since(start), 10*time.Second)
+
+	var stageErr *StageError
+	require.ErrorAs(t, err, &stageErr)
+	require.Equal(t, 1, stageErr.Stage)
+}
+
+func TestExecutorInterrupt(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	metas := []command_meta.CommandMeta{
+		{Name: "sleep", Args: []string{"30"}},
+		{Name: "cat", Args: []string{}},
+	}
+
+	pf := NewPipelineFactory(newTestSession(t))
+	p := pf.CreatePipeline(nil, os.Stdout, metas)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := p.Execute(ctx)
+	require.Equal(t, InterruptedStatus, commands.ExitStatus(err))
+}
+
+func TestExecutorEarlyReaderExit(t *testing.T) {
+	if _, err := exec.LookPath("yes"); err != nil {
+		t.Skip("yes is not available")
+	}
+
+	metas := []command_meta.CommandMeta{
+		{Name: "yes", Args: []string{}},
+		{Name: "cat", Args: []string{}},
+		{Name: "head", Args: []string{"-n", "2"}},
+	}
+
+	rp, wp, err := os.Pipe()
+	require.NoError(t, err)
+	defer rp.Close()
+
+	pf := NewPipelineFactory(newTestSession(t))
+	p := pf.CreatePipeline(nil, wp, metas)
+	err = p.Execute(context.Background())
+	wp.Close()
+	require.NoError(t, err)
+
+	output, err := io.ReadAll(rp)
+	require.NoError(t, err)
+	require.Equal(t, "y\ny\n", string(output))
+}
+
+func TestExecutorBuiltinsInMemory(t *testing.T) {
+	metas := []command_meta.CommandMeta{
+		{Name: "cat", Args: []string{}},
+		{Name: "sort", Args: []string{}},
+		{Name: "uniq", Args: []string{"-c"}},
+	}
+
+	var output bytes.Buffer
+	pf := NewPipelineFactory(newTestSession(t))
+	p := pf.CreatePipeline(strings.NewReader("b\na\nb\n"), &output, metas)
+	require.NoError(t, p.Execute(context.Background()))
+	require.Equal(t, "      1 a\n      2 b\n", output.String())
+}
+
+func TestExecutorStructured(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big"), make([]byte, 100), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small"), make([]byte, 10), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	sess, err := session.New(dir, io.Discard)
+	require.NoError(t, err)
+	sess.SetOption(session.OptionStructured, true)
+	pf := NewPipelineFactory(sess)
+
+	run := func(metas ...command_meta.CommandMeta) string {
+		var output bytes.Buffer
+		p := pf.CreatePipeline(strings.NewReader(""), &output, metas)
+		require.NoError(t, p.Execute(context.Background()))
+		return output.String()
+	}
+
+	output := run(
+		command_meta.CommandMeta{Name: "ls"},
+		command_meta.CommandMeta{Name: "where", Args: []string{"type", "==", "file"}},
+		command_meta.CommandMeta{Name: "sort-by", Args: []string{"size"}},
+		command_meta.CommandMeta{Name: "select", Args: []string{"name", "size"}},
+	)
+	require.Equal(t, "name   size\nsmall  10\nbig    100\n", output)
+
+	output = run(
+		command_meta.CommandMeta{Name: "ls"},
+		command_meta.CommandMeta{Name: "select", Args: []string{"name"}},
+		command_meta.CommandMeta{Name: "to-json", Args: []string{"-l"}},
+	)
+	require.Equal(t, "{\"name\":\"big\"}\n{\"name\":\"small\"}\n{\"name\":\"sub\"}\n", output)
+
+	output = run(command_meta.CommandMeta{Name: "ls"}, command_meta.CommandMeta{Name: "wc"})
+	require.Regexp(t, `^\t3\t3\t\d+\n$`, output)
+
+	// Внешний процесс получает текстовый вывод ls
+	output = run(command_meta.CommandMeta{Name: "ls"}, command_meta.CommandMeta{Name: "od", Args: []string{"-An", "-c", "-w1000"}})
+	require.Contains(t, output, "s   m   a   l   l")
+}
+
+func TestExecutorMemFS(t *testing.T) {
+	fsys := vfs.NewMem()
+	require.NoError(t, fsys.Mkdir("/home", 0755))
+
+	sess, err := session.New("/", io.Discard)
+	require.NoError(t, err)
+	sess.SetFS(fsys)
+	pf := NewPipelineFactory(sess)
+
+	run := func(metas ...command_meta.CommandMeta) string {
+		var output bytes.Buffer
+		p := pf.CreatePipeline(strings.NewReader(""), &output, metas)
+		require.NoError(t, p.Execute(context.Background()))
+		return output.String()
+	}
+
+	run(command_meta.CommandMeta{Name: "cd", Args: []string{"home"}})
+	require.Equal(t, "/home", sess.Dir())
+	run(command_meta.CommandMeta{Name: "echo", Args: []string{"hello"}, Redirects: []command_meta.Redirect{
+		{Op: command_meta.RedirectOutput, Target: "greeting"},
+	}})
+	run(command_meta.CommandMeta{Name: "echo", Args: []string{"world"}}, command_meta.CommandMeta{Name: "tee", Args: []string{"-a", "greeting"}})
+
+	data, err := vfs.ReadFile(fsys, "/home/greeting")
+	require.NoError(t, err)
+	require.Equal(t, "hello\nworld\n", string(data))
+	require.Equal(t, "\t2\t2\t12\tgreeting\n", run(command_meta.CommandMeta{Name: "wc", Args: []string{"greeting"}}))
+
+	_, err = os.Stat("/home/greeting")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestExecutorAliases(t *testing.T) {
+	sess := newTestSession(t)
+	sess.SetAlias("greet", "echo hello")
+	sess.SetAlias("echo", "echo -n")
+
+	metas := []command_meta.CommandMeta{
+		{Name: "greet", Args: []string{"world"}},
+	}
+
+	var output bytes.Buffer
+	p := NewPipelineFactory(sess).CreatePipeline(nil, &output, metas)
+	require.NoError(t, p.Execute(context.Background()))
+	require.Equal(t, "hello world", output.String())
+}
+
+func TestExecutorRedirects(t *testing.T) {
+	dir := t.TempDir()
+	sess, err := session.New(dir, io.Discard)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "in.txt"), []byte("b\na\n"), 0644))
+
+	pf := NewPipelineFactory(sess)
+	run := func(metas ...command_meta.CommandMeta) error {
+		return pf.CreatePipeline(nil, io.Discard, metas).Execute(context.Background())
+	}
+
+	// Ввод и вывод перенаправляются относительно директории сессии
+	require.NoError(t, run(
+		command_meta.CommandMeta{Name: "sort", Redirects: []command_meta.Redirect{
+			{Op: command_meta.RedirectInput, Target: "in.txt"},
+			{Op: command_meta.RedirectOutput, Target: "out.txt"},
+		}},
+	))
+	require.NoError(t, run(
+		command_meta.CommandMeta{Name: "echo", Args: []string{"c"}},
+		command_meta.CommandMeta{Name: "cat", Redirects: []command_meta.Redirect{
+			{Op: command_meta.RedirectAppend, Target: "out.txt"},
+		}},
+	))
+	content, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\nc\n", string(content))
+
+	// С опцией noclobber > не перезаписывает файл, а >| перезаписывает
+	sess.SetOption(session.OptionNoclobber, true)
+	overwrite := func(op string) error {
+		return run(command_meta.CommandMeta{Name: "echo", Args: []string{"new"}, Redirects: []command_meta.Redirect{
+			{Op: op, Target: "out.txt"},
+		}})
+	}
+	require.EqualError(t, overwrite(command_meta.RedirectOutput), "echo: out.txt: cannot overwrite existing file")
+	content, err = os.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\nc\n", string(content))
+	// Новый файл создается, а в необычные файлы запись разрешена
+	require.NoError(t, run(command_meta.CommandMeta{Name: "echo", Args: []string{"x"}, Redirects: []command_meta.Redirect{
+		{Op: command_meta.RedirectOutput, Target: "created.txt"},
+		{Op: command_meta.RedirectOutput, Target: os.DevNull},
+	}}))
+	content, err = os.ReadFile(filepath.Join(dir, "created.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "", string(content))
+	require.NoError(t, overwrite(command_meta.RedirectClobber))
+	content, err = os.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "new\n", string(content))
+
+	require.Error(t, run(command_meta.CommandMeta{Name: "cat", Redirects: []command_meta.Redirect{
+		{Op: command_meta.RedirectInput, Target: "missing.txt"},
+	}}))
+}
+
+func TestPipelineStatus(t *testing.T) {
+	sess := newTestSession(t)
+	metas := []command_meta.CommandMeta{{Name: "false"}, {Name: "true"}}
+	first := &StageError{Stage: 0, Total: 2, Name: "false", Err: commands.ExitStatusError{Status: 3}}
+	last := &StageError{Stage: 1, Total: 2, Name: "true", Err: commands.ExitStatusError{Status: 4}}
+
+	// Без pipefail код возврата определяется последней командой
+	p := NewPipelineFactory(sess).CreatePipeline(nil, io.Discard, metas)
+	require.Equal(t, 0, p.Status(nil))
+	require.Equal(t, 0, p.Status(first))
+	require.Equal(t, 4, p.Status(last))
+
+	sess.SetOption(session.OptionPipefail, true)
+	p = NewPipelineFactory(sess).CreatePipeline(nil, io.Discard, metas)
+	require.Equal(t, 3, p.Status(first))
+	require.Equal(t, 4, p.Status(last))
+}
+
+func TestExecutorRestrictedRedirects(t *testing.T) {
+	dir := t.TempDir()
+	sess, err := session.New(dir, io.Discard)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "in.txt"), []byte("a\n"), 0644))
+	sess.Restrict(nil)
+
+	var output bytes.Buffer
+	pf := NewPipelineFactory(sess)
+	require.NoError(t, pf.CreatePipeline(nil, &output, []command_meta.CommandMeta{
+		{Name: "cat", Redirects: []command_meta.Redirect{{Op: command_meta.RedirectInput, Target: "in.txt"}}},
+	}).Execute(context.Background()))
+	require.Equal(t, "a\n", output.String())
+
+	for _, op := range []string{command_meta.RedirectOutput, command_meta.RedirectAppend, command_meta.RedirectClobber} {
+		err := pf.CreatePipeline(nil, io.Discard, []command_meta.CommandMeta{
+			{Name: "echo", Args: []string{"x"}, Redirects: []command_meta.Redirect{{Op: op, Target: "out.txt"}}},
+		}).Execute(context.Background())
+		require.EqualError(t, err, "echo: out.txt: restricted: cannot redirect output")
+	}
+	_, err = os.Stat(filepath.Join(dir, "out.txt"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestExecutorParallel(t *testing.T) {
+	var stderr bytes.Buffer
+	sess, err := session.New(t.TempDir(), &stderr)
+	require.NoError(t, err)
+	pf := NewPipelineFactory(sess)
+
+	run := func(input string, args ...string) (string, int) {
+		var output bytes.Buffer
+		meta := command_meta.CommandMeta{Name: "parallel", Args: args}
+		p := pf.CreatePipeline(strings.NewReader(input), &output, []command_meta.CommandMeta{meta})
+		status := p.Status(p.Execute(context.Background()))
+		return output.String(), status
+	}
+
+	output, status := run("b b\na\n\nc\n", "-j", "3", "-k", "echo", "x{}", "|", "tr", "a-z", "A-Z")
+	require.Equal(t, 0, status)
+	require.Equal(t, "XB B\nXA\nXC\n", output)
+	require.Empty(t, stderr.String())
+
+	output, status = run("1\n2\n3\n", "-j", "1", "--halt-on-error", "[", "{}", "-ne", "2", "]")
+	require.Equal(t, 1, status)
+	require.Empty(t, output)
+	require.Equal(t, "parallel: job 2 (2): exit status 1\nparallel: 3 jobs: 1 succeeded, 1 failed, 1 not started\n", stderr.String())
+
+	stderr.Reset()
+	output, status = run("1\n2\n3\n", "--summary", "-j0", "[ {} -eq 2 ]")
+	require.Equal(t, 2, status)
+	require.Contains(t, stderr.String(), "parallel: job 2 (2): exit status 0\n")
+	require.Contains(t, stderr.String(), "parallel: 3 jobs: 1 succeeded, 2 failed, 0 not started\n")
+
+	_, status = run("a\n", "echo", "'")
+	require.NotEqual(t, 0, status)
+}
+
+func TestExecutorWatch(t *testing.T) {
+	sess := newTestSession(t)
+	pf := NewPipelineFactory(sess)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	var output bytes.Buffer
+	meta := command_meta.CommandMeta{Name: "watch", Args: []string{"-n", "0.1", "-d", "echo", "ok", "|", "wc"}}
+	p := pf.CreatePipeline(strings.NewReader(""), &output, []command_meta.CommandMeta{meta})
+	require.Equal(t, InterruptedStatus, p.Status(p.Execute(ctx)))
+
+	screens := strings.Split(output.String(), "\x1b[H")
+	require.GreaterOrEqual(t, len(screens), 3)
+	require.Contains(t, screens[1], "Every 0.1s: echo ok | wc")
+	require.Contains(t, screens[1], "\t1\t1\t3\x1b[K\n")
+	require.NotContains(t, screens[2], "\x1b[7m\t1")
+	require.True(t, strings.HasSuffix(output.String(), "\x1b[?25h"))
+}
@@ -0,0 +1,240 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"shell/internal/commands"
+	"sync"
+)
+
+// Размер буфера пайпа в памяти, как у пайпа ядра Linux по умолчанию
+const memPipeCapacity = 64 * 1024
+
+// Буферизованный пайп в памяти для соединения двух встроенных команд.
+// В отличие от io.Pipe, запись не блокируется, пока в буфере есть место,
+// поэтому соседние команды не обязаны работать в лок-степе.
+type memPipe struct {
+	mutex        sync.Mutex
+	cond         *sync.Cond
+	buffer       []byte
+	readerClosed bool
+	writerClosed bool
+}
+
+// Читающий конец пайпа в памяти
+type memPipeReader struct {
+	pipe *memPipe
+}
+
+// Пишущий конец пайпа в памяти
+type memPipeWriter struct {
+	pipe *memPipe
+}
+
+// newMemPipe создает пару концов буферизованного пайпа в памяти
+func newMemPipe() (*memPipeReader, *memPipeWriter) {
+	pipe := &memPipe{}
+	pipe.cond = sync.NewCond(&pipe.mutex)
+	return &memPipeReader{pipe}, &memPipeWriter{pipe}
+}
+
+// Read блокируется, пока в буфере нет данных.
+// Возвращает io.EOF, когда пишущий конец закрыт и данные закончились,
+// и io.ErrClosedPipe, если читающий конец уже закрыт.
+func (r *memPipeReader) Read(data []byte) (int, error) {
+	p := r.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(p.buffer) == 0 && !p.writerClosed && !p.readerClosed {
+		p.cond.Wait()
+	}
+	if p.readerClosed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p.buffer) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(data, p.buffer)
+	p.buffer = p.buffer[n:]
+	if len(p.buffer) == 0 {
+		p.buffer = nil
+	}
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// Close закрывает читающий конец. Последующие записи вернут io.ErrClosedPipe.
+func (r *memPipeReader) Close() error {
+	p := r.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.readerClosed = true
+	p.buffer = nil
+	p.cond.Broadcast()
+	return nil
+}
+
+// Write копирует данные в буфер, блокируясь, пока буфер заполнен.
+// Возвращает io.ErrClosedPipe, если хотя бы один из концов пайпа закрыт.
+func (w *memPipeWriter) Write(data []byte) (int, error) {
+	p := w.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	written := 0
+	for written < len(data) {
+		for len(p.buffer) >= memPipeCapacity && !p.readerClosed && !p.writerClosed {
+			p.cond.Wait()
+		}
+		if p.readerClosed || p.writerClosed {
+			return written, io.ErrClosedPipe
+		}
+
+		n := min(len(data)-written, memPipeCapacity-len(p.buffer))
+		p.buffer = append(p.buffer, data[written:written+n]...)
+		written += n
+		p.cond.Broadcast()
+	}
+	return written, nil
+}
+
+// Close закрывает пишущий конец. Читатель получит io.EOF после оставшихся данных.
+func (w *memPipeWriter) Close() error {
+	p := w.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.writerClosed = true
+	p.cond.Broadcast()
+	return nil
+}
+
+//////////////////////////////////
+
+// Число записей, которые помещаются в пайп записей без блокировки писателя
+const recordPipeCapacity = 1024
+
+// Пайп записей между встроенными командами в структурированном режиме.
+// Пишущий конец реализует commands.RecordWriter, читающий - commands.RecordReader.
+type recordPipe struct {
+	mutex        sync.Mutex
+	cond         *sync.Cond
+	records      []commands.Record
+	readerClosed bool
+	writerClosed bool
+}
+
+// Читающий конец пайпа записей
+type recordPipeReader struct {
+	pipe *recordPipe
+	// Текст записи, прочитанной через Read, который еще не отдан читателю
+	pending []byte
+}
+
+// Пишущий конец пайпа записей
+type recordPipeWriter struct {
+	pipe *recordPipe
+}
+
+var _ commands.RecordReader = (*recordPipeReader)(nil)
+var _ commands.RecordWriter = (*recordPipeWriter)(nil)
+
+// newRecordPipe создает пару концов пайпа записей
+func newRecordPipe() (*recordPipeReader, *recordPipeWriter) {
+	pipe := &recordPipe{}
+	pipe.cond = sync.NewCond(&pipe.mutex)
+	return &recordPipeReader{pipe: pipe}, &recordPipeWriter{pipe}
+}
+
+// ReadRecord блокируется, пока в пайпе нет записей.
+// Возвращает io.EOF, когда пишущий конец закрыт и записи закончились,
+// и io.ErrClosedPipe, если читающий конец уже закрыт.
+func (r *recordPipeReader) ReadRecord() (commands.Record, error) {
+	p := r.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(p.records) == 0 && !p.writerClosed && !p.readerClosed {
+		p.cond.Wait()
+	}
+	if p.readerClosed {
+		return commands.Record{}, io.ErrClosedPipe
+	}
+	if len(p.records) == 0 {
+		return commands.Record{}, io.EOF
+	}
+
+	record := p.records[0]
+	p.records = p.records[1:]
+	p.cond.Broadcast()
+	return record, nil
+}
+
+// Read отдает записи текстом в формате JSON lines
+// на случай, если читатель все же читает текст
+func (r *recordPipeReader) Read(data []byte) (int, error) {
+	if len(r.pending) == 0 {
+		record, err := r.ReadRecord()
+		if err != nil {
+			return 0, err
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return 0, err
+		}
+		r.pending = append(line, '\n')
+	}
+	n := copy(data, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Close закрывает читающий конец. Последующие записи вернут io.ErrClosedPipe.
+func (r *recordPipeReader) Close() error {
+	p := r.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.readerClosed = true
+	p.records = nil
+	p.cond.Broadcast()
+	return nil
+}
+
+// WriteRecord добавляет запись в пайп, блокируясь, пока пайп заполнен.
+// Возвращает io.ErrClosedPipe, если хотя бы один из концов пайпа закрыт.
+func (w *recordPipeWriter) WriteRecord(record commands.Record) error {
+	p := w.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(p.records) >= recordPipeCapacity && !p.readerClosed && !p.writerClosed {
+		p.cond.Wait()
+	}
+	if p.readerClosed || p.writerClosed {
+		return io.ErrClosedPipe
+	}
+	p.records = append(p.records, record)
+	p.cond.Broadcast()
+	return nil
+}
+
+// Write всегда завершается ошибкой: в пайп записей нельзя писать текст
+func (w *recordPipeWriter) Write(data []byte) (int, error) {
+	return 0, errors.New("structured pipe: text output is not supported")
+}
+
+// Close закрывает пишущий конец. Читатель получит io.EOF после оставшихся записей.
+func (w *recordPipeWriter) Close() error {
+	p := w.pipe
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.writerClosed = true
+	p.cond.Broadcast()
+	return nil
+}
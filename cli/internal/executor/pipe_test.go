@@ -0,0 +1,138 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"shell/internal/command_meta"
+	"shell/internal/commands"
+	"shell/internal/session"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemPipeTransfersData(t *testing.T) {
+	r, w := newMemPipe()
+	data := strings.Repeat("0123456789", memPipeCapacity/5)
+
+	go func() {
+		_, err := io.WriteString(w, data)
+		require.NoError(t, err)
+		w.Close()
+	}()
+
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, string(output))
+}
+
+func TestMemPipeWriteAfterReaderClose(t *testing.T) {
+	r, w := newMemPipe()
+	r.Close()
+
+	_, err := w.Write([]byte("data"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestMemPipeUnblocksWriterOnReaderClose(t *testing.T) {
+	r, w := newMemPipe()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Write(make([]byte, 2*memPipeCapacity))
+		done <- err
+	}()
+
+	buf := make([]byte, 16)
+	_, err := r.Read(buf)
+	require.NoError(t, err)
+	r.Close()
+	require.ErrorIs(t, <-done, io.ErrClosedPipe)
+}
+
+func TestMemPipeReadAfterWriterClose(t *testing.T) {
+	r, w := newMemPipe()
+	_, err := w.Write([]byte("tail"))
+	require.NoError(t, err)
+	w.Close()
+
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "tail", string(buf[:n]))
+
+	_, err = r.Read(buf)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestCreatePipelinePipeKinds(t *testing.T) {
+	metas := []command_meta.CommandMeta{
+		{Name: "echo", Args: []string{"b\na"}},
+		{Name: "sort", Args: []string{}},
+		{Name: "od", Args: []string{"-c"}},
+	}
+
+	pf := NewPipelineFactory(newTestSession(t))
+	p := pf.CreatePipeline(strings.NewReader(""), &bytes.Buffer{}, metas)
+	require.NotNil(t, p)
+	defer func() {
+		for _, pipe := range p.pipes {
+			pipe.input.Close()
+			pipe.output.Close()
+		}
+	}()
+
+	require.IsType(t, &memPipeReader{}, p.pipes[0].input)
+	require.IsType(t, &os.File{}, p.pipes[1].input)
+}
+
+func TestRecordPipe(t *testing.T) {
+	r, w := newRecordPipe()
+
+	go func() {
+		for i := 0; i < 2*recordPipeCapacity; i++ {
+			record := commands.NewRecord()
+			record.Set("n", int64(i))
+			require.NoError(t, w.WriteRecord(record))
+		}
+		w.Close()
+	}()
+
+	for i := 0; i < 2*recordPipeCapacity-1; i++ {
+		record, err := r.ReadRecord()
+		require.NoError(t, err)
+		value, _ := record.Get("n")
+		require.Equal(t, int64(i), value)
+	}
+
+	// Последняя запись читается текстом в формате JSON lines
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("{\"n\":%d}\n", 2*recordPipeCapacity-1), string(output))
+
+	_, err = w.Write([]byte("text"))
+	require.Error(t, err)
+	r.Close()
+	require.ErrorIs(t, w.WriteRecord(commands.NewRecord()), io.ErrClosedPipe)
+}
+
+func TestCreatePipelineRecordPipes(t *testing.T) {
+	metas := []command_meta.CommandMeta{
+		{Name: "ls", Args: []string{}},
+		{Name: "where", Args: []string{"type", "==", "file"}},
+		{Name: "cat", Args: []string{}},
+	}
+
+	sess := newTestSession(t)
+	pf := NewPipelineFactory(sess)
+	p := pf.CreatePipeline(strings.NewReader(""), &bytes.Buffer{}, metas)
+	require.IsType(t, &memPipeReader{}, p.pipes[0].input)
+
+	sess.SetOption(session.OptionStructured, true)
+	p = pf.CreatePipeline(strings.NewReader(""), &bytes.Buffer{}, metas)
+	require.IsType(t, &recordPipeReader{}, p.pipes[0].input)
+	require.IsType(t, &memPipeReader{}, p.pipes[1].input)
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"shell/internal/command_meta"
+	"shell/internal/commands"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+)
+
+// Команда с перенаправлениями ввода-вывода.
+// Файлы открываются при исполнении, в порядке перенаправлений, после чего
+// исполняется сама команда, у которой ввод или вывод заменен на эти файлы.
+type redirectedCommand struct {
+	factory *commands.CommandFactory
+	meta    command_meta.CommandMeta
+	input   io.Reader
+	output  io.Writer
+}
+
+var _ commands.Command = redirectedCommand{}
+
+func (cmd redirectedCommand) Execute(ctx context.Context) error {
+	in, out := cmd.input, cmd.output
+	for _, redirect := range cmd.meta.Redirects {
+		file, err := openRedirect(cmd.factory.Session(), redirect)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if redirect.Op == command_meta.RedirectInput {
+			in = file
+		} else {
+			out = file
+		}
+	}
+
+	return cmd.factory.CommandFromMeta(cmd.meta, in, out).Execute(ctx)
+}
+
+// openRedirect открывает файл перенаправления относительно директории сессии
+// в файловой системе сессии.
+// При включенной опции noclobber оператор > не перезаписывает существующие
+// обычные файлы, оператор >| перезаписывает их всегда.
+// В ограниченном режиме разрешено только перенаправление ввода.
+func openRedirect(sess *session.Session, redirect command_meta.Redirect) (vfs.File, error) {
+	path := sess.Path(redirect.Target)
+	if sess.Restricted() && redirect.Op != command_meta.RedirectInput {
+		return nil, &commands.RestrictedError{Subject: redirect.Target, Reason: "cannot redirect output"}
+	}
+
+	switch redirect.Op {
+	case command_meta.RedirectInput:
+		return vfs.Open(sess.FS(), path)
+	case command_meta.RedirectAppend:
+		return sess.FS().OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	case command_meta.RedirectOutput:
+		if sess.Option(session.OptionNoclobber) {
+			return openNoclobber(sess.FS(), path, redirect.Target)
+		}
+	}
+	return sess.FS().OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// openNoclobber открывает файл для > с опцией noclobber. Новый файл создается
+// с O_EXCL, поэтому файл, появившийся одновременно с перенаправлением, не будет
+// перезаписан. Существующий файл открывается без обрезки и проверяется уже
+// открытым: разрешена запись только в необычные файлы, такие как /dev/null или FIFO.
+func openNoclobber(fsys vfs.FS, path string, target string) (vfs.File, error) {
+	file, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if !errors.Is(err, fs.ErrExist) {
+		return file, err
+	}
+
+	file, err = fsys.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err == nil && info.Mode().IsRegular() {
+		err = fmt.Errorf("%s: cannot overwrite existing file", target)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/internal/commands"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Команда с подстановками процессов <(...) и >(...).
+// Для каждой подстановки создается именованный канал (FIFO) во временной директории
+// и запускается пайплайн, который пишет в него или читает из него. Текст подстановки
+// в аргументе или имени файла перенаправления, где его нашел парсер, заменяется на путь к каналу,
+// поэтому встроенные команды и внешние процессы открывают его одинаково.
+// Каналы создаются на диске, поэтому в сессии с другой файловой системой
+// (vfs.NewMem, vfs.NewDir) подстановки процессов не поддерживаются.
+type substitutedCommand struct {
+	factory *PipelineFactory
+	meta    command_meta.CommandMeta
+	input   io.Reader
+	output  io.Writer
+}
+
+var _ commands.Command = substitutedCommand{}
+
+// Пайплайн подстановки процесса, исполняемый параллельно с основной командой
+type substitution struct {
+	op     string
+	path   string
+	cancel context.CancelFunc
+	// Закрывается, когда пайплайн открыл свой конец канала
+	opened chan struct{}
+	// Закрывается, когда пайплайн завершился
+	done chan struct{}
+}
+
+// Execute запускает пайплайны подстановок и исполняет основную команду.
+// После ее завершения пайплайны <(...) останавливаются, а завершения пайплайнов >(...)
+// команда дожидается, чтобы они успели обработать весь записанный в них вывод.
+func (cmd substitutedCommand) Execute(ctx context.Context) error {
+	if cmd.factory.cmdFactory.Session().FS() != vfs.OS {
+		return fmt.Errorf("process substitution: not supported outside the OS file system")
+	}
+	// Пайплайны >(...) пишут туда же, куда и основная команда, одновременно с ней
+	cmd.output = session.NewSyncWriter(cmd.output)
+
+	dir, err := os.MkdirTemp("", "shell-subst-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	meta := cmd.meta
+	meta.Substitutions = nil
+	meta.Args = append([]string{}, cmd.meta.Args...)
+	meta.Redirects = append([]command_meta.Redirect{}, cmd.meta.Redirects...)
+
+	var substitutions []*substitution
+	defer func() {
+		for _, s := range substitutions {
+			s.finish()
+		}
+	}()
+
+	for i, ps := range cmd.meta.Substitutions {
+		path := filepath.Join(dir, strconv.Itoa(i))
+		if err := syscall.Mkfifo(path, 0600); err != nil {
+			return fmt.Errorf("process substitution: %w", err)
+		}
+		substitutions = append(substitutions, cmd.start(ctx, ps, path))
+	}
+	// Замена с конца сохраняет смещения предыдущих подстановок того же слова
+	for i := len(substitutions) - 1; i >= 0; i-- {
+		replaceSubstitution(&meta, cmd.meta.Substitutions[i], substitutions[i].path)
+	}
+
+	return cmd.factory.command(meta, cmd.input, cmd.output).Execute(ctx)
+}
+
+// start запускает пайплайн подстановки, соединенный с каналом path
+func (cmd substitutedCommand) start(ctx context.Context, ps command_meta.ProcessSubstitution, path string) *substitution {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &substitution{op: ps.Op, path: path, cancel: cancel, opened: make(chan struct{}), done: make(chan struct{})}
+	stderr := cmd.factory.cmdFactory.Session().Stderr
+
+	go func() {
+		defer close(s.done)
+
+		// Открытие канала блокируется, пока не будет открыт его второй конец
+		flag := os.O_WRONLY
+		if ps.Op == command_meta.SubstituteOutput {
+			flag = os.O_RDONLY
+		}
+		fifo, err := os.OpenFile(path, flag, 0)
+		close(s.opened)
+		if err != nil {
+			fmt.Fprintf(stderr, "process substitution: %s\n", err)
+			return
+		}
+		defer fifo.Close()
+
+		// Пайплайн <(...) не читает ввод оболочки, а вывод пайплайна >(...)
+		// направляется туда же, куда и вывод основной команды
+		var in io.Reader = strings.NewReader("")
+		var out io.Writer = fifo
+		if ps.Op == command_meta.SubstituteOutput {
+			in, out = fifo, cmd.output
+		}
+		pipeline := cmd.factory.CreatePipeline(in, out, ps.Commands)
+		if pipeline == nil {
+			return
+		}
+		err = pipeline.Execute(ctx)
+		if err != nil && ctx.Err() == nil && !commands.IsSilent(err) && !isBrokenPipe(err) {
+			fmt.Fprintf(stderr, "%s\n", err)
+		}
+	}()
+	return s
+}
+
+// finish завершает подстановку после завершения основной команды
+func (s *substitution) finish() {
+	s.release()
+	if s.op == command_meta.SubstituteInput {
+		s.cancel()
+	}
+	<-s.done
+	s.cancel()
+}
+
+// release разблокирует пайплайн, если основная команда так и не открыла канал:
+// второй конец канала открывается и сразу закрывается, после чего пайплайн
+// получает конец ввода или ошибку записи. Пока пайплайн не начал открывать канал,
+// неблокирующее открытие на запись может не удаться, поэтому оно повторяется.
+func (s *substitution) release() {
+	flag := os.O_RDONLY
+	if s.op == command_meta.SubstituteOutput {
+		flag = os.O_WRONLY
+	}
+	for {
+		select {
+		case <-s.opened:
+			return
+		default:
+		}
+		if fifo, err := os.OpenFile(s.path, flag|syscall.O_NONBLOCK, 0); err == nil {
+			fifo.Close()
+		}
+		select {
+		case <-s.opened:
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// replaceSubstitution заменяет текст подстановки в слове, где его нашел парсер,
+// на путь к каналу
+func replaceSubstitution(meta *command_meta.CommandMeta, ps command_meta.ProcessSubstitution, path string) {
+	var word *string
+	switch {
+	case ps.InRedirect && ps.Word >= 0 && ps.Word < len(meta.Redirects):
+		word = &meta.Redirects[ps.Word].Target
+	case !ps.InRedirect && ps.Word >= 0 && ps.Word < len(meta.Args):
+		word = &meta.Args[ps.Word]
+	default:
+		return
+	}
+	end := ps.Offset + len(ps.Text)
+	if end > len(*word) || (*word)[ps.Offset:end] != ps.Text {
+		return
+	}
+	*word = (*word)[:ps.Offset] + path + (*word)[end:]
+}
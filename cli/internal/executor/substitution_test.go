@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"shell/internal/command_meta"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// substitute создает подстановку процесса для одной команды, текст которой
+// составляет аргумент номер word
+func substitute(op string, text string, word int, meta command_meta.CommandMeta) command_meta.ProcessSubstitution {
+	return command_meta.ProcessSubstitution{Op: op, Text: text, Word: word, Commands: []command_meta.CommandMeta{meta}}
+}
+
+// substituteRedirect создает подстановку процесса для одной команды, текст которой
+// составляет имя файла перенаправления номер redirect
+func substituteRedirect(op string, text string, redirect int, meta command_meta.CommandMeta) command_meta.ProcessSubstitution {
+	ps := substitute(op, text, redirect, meta)
+	ps.InRedirect = true
+	return ps
+}
+
+func TestProcessSubstitutionInput(t *testing.T) {
+	var output bytes.Buffer
+	pf := NewPipelineFactory(newTestSession(t))
+	metas := []command_meta.CommandMeta{
+		{
+			Name: "cat",
+			Args: []string{"<(echo hi)"},
+			Substitutions: []command_meta.ProcessSubstitution{
+				substitute(command_meta.SubstituteInput, "<(echo hi)", 0, command_meta.CommandMeta{Name: "echo", Args: []string{"hi"}}),
+			},
+		},
+		{
+			Name:      "wc",
+			Redirects: []command_meta.Redirect{{Op: command_meta.RedirectInput, Target: "<(echo a b)"}},
+			Substitutions: []command_meta.ProcessSubstitution{
+				substituteRedirect(command_meta.SubstituteInput, "<(echo a b)", 0, command_meta.CommandMeta{Name: "echo", Args: []string{"a", "b"}}),
+			},
+		},
+	}
+	require.NoError(t, pf.CreatePipeline(nil, &output, metas).Execute(context.Background()))
+	require.Equal(t, "\t1\t2\t4\n", output.String())
+}
+
+func TestProcessSubstitutionOutput(t *testing.T) {
+	dir := t.TempDir()
+	sess, err := session.New(dir, io.Discard)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	pf := NewPipelineFactory(sess)
+	metas := []command_meta.CommandMeta{
+		{Name: "echo", Args: []string{"data"}},
+		{
+			Name: "tee",
+			Args: []string{">(cat > copy.txt)"},
+			Substitutions: []command_meta.ProcessSubstitution{
+				substitute(command_meta.SubstituteOutput, ">(cat > copy.txt)", 0, command_meta.CommandMeta{
+					Name:      "cat",
+					Redirects: []command_meta.Redirect{{Op: command_meta.RedirectOutput, Target: "copy.txt"}},
+				}),
+			},
+		},
+	}
+	require.NoError(t, pf.CreatePipeline(nil, &output, metas).Execute(context.Background()))
+	require.Equal(t, "data\n", output.String())
+
+	// Пайплайн >(...) завершается до завершения команды
+	content, err := os.ReadFile(filepath.Join(dir, "copy.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "data\n", string(content))
+}
+
+func TestProcessSubstitutionUnused(t *testing.T) {
+	var output bytes.Buffer
+	pf := NewPipelineFactory(newTestSession(t))
+	metas := []command_meta.CommandMeta{{
+		Name: "echo",
+		Args: []string{"<(echo in)", ">(cat)"},
+		Substitutions: []command_meta.ProcessSubstitution{
+			substitute(command_meta.SubstituteInput, "<(echo in)", 0, command_meta.CommandMeta{Name: "echo", Args: []string{"in"}}),
+			substitute(command_meta.SubstituteOutput, ">(cat)", 1, command_meta.CommandMeta{Name: "cat"}),
+		},
+	}}
+
+	// Команда не открывает каналы, но пайплайны подстановок не зависают
+	require.NoError(t, pf.CreatePipeline(nil, &output, metas).Execute(context.Background()))
+	require.Regexp(t, `^/\S+/0 /\S+/1\n$`, output.String())
+
+	// Временная директория с каналами удаляется после завершения команды
+	_, err := os.Stat(filepath.Dir(output.String()))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestProcessSubstitutionPosition(t *testing.T) {
+	var output bytes.Buffer
+	pf := NewPipelineFactory(newTestSession(t))
+	second := substitute(command_meta.SubstituteInput, "<(echo b)", 1, command_meta.CommandMeta{Name: "echo", Args: []string{"b"}})
+	second.Offset = len("<(echo a)")
+	metas := []command_meta.CommandMeta{{
+		Name: "echo",
+		Args: []string{"<(echo a)", "<(echo a)<(echo b)"},
+		Substitutions: []command_meta.ProcessSubstitution{
+			substitute(command_meta.SubstituteInput, "<(echo a)", 1, command_meta.CommandMeta{Name: "echo", Args: []string{"a"}}),
+			second,
+		},
+	}}
+
+	// Заменяется текст подстановки в том месте, где его нашел парсер,
+	// а не первое совпадение: первый аргумент - обычное слово из кавычек
+	require.NoError(t, pf.CreatePipeline(nil, &output, metas).Execute(context.Background()))
+	require.Regexp(t, `^<\(echo a\) (/\S+)/0(/\S+)/1\n$`, output.String())
+}
+
+func TestProcessSubstitutionOutputSync(t *testing.T) {
+	input := strings.Repeat("line\n", 1000)
+	var output bytes.Buffer
+	pf := NewPipelineFactory(newTestSession(t))
+	metas := []command_meta.CommandMeta{{
+		Name: "tee",
+		Args: []string{">(cat)", ">(cat)"},
+		Substitutions: []command_meta.ProcessSubstitution{
+			substitute(command_meta.SubstituteOutput, ">(cat)", 0, command_meta.CommandMeta{Name: "cat"}),
+			substitute(command_meta.SubstituteOutput, ">(cat)", 1, command_meta.CommandMeta{Name: "cat"}),
+		},
+	}}
+
+	// Пайплайны >(...) и tee пишут в один буфер одновременно; гонку ловит -race
+	require.NoError(t, pf.CreatePipeline(strings.NewReader(input), &output, metas).Execute(context.Background()))
+	require.Len(t, output.String(), 3*len(input))
+}
+
+func TestProcessSubstitutionMemFS(t *testing.T) {
+	sess, err := session.New("/", io.Discard)
+	require.NoError(t, err)
+	sess.SetFS(vfs.NewMem())
+
+	var output bytes.Buffer
+	pf := NewPipelineFactory(sess)
+	metas := []command_meta.CommandMeta{{
+		Name: "cat",
+		Args: []string{"<(echo hi)"},
+		Substitutions: []command_meta.ProcessSubstitution{
+			substitute(command_meta.SubstituteInput, "<(echo hi)", 0, command_meta.CommandMeta{Name: "echo", Args: []string{"hi"}}),
+		},
+	}}
+	err = pf.CreatePipeline(nil, &output, metas).Execute(context.Background())
+	require.ErrorContains(t, err, "process substitution: not supported outside the OS file system")
+	require.Empty(t, output.String())
+}
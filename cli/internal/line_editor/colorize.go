@@ -0,0 +1,48 @@
+package lineeditor
+
+import (
+	"shell/internal/parser"
+	"strings"
+)
+
+// Управляющие последовательности терминала для подсветки строки
+const (
+	termReset     = "\x1b[0m"
+	termClearLine = "\x1b[K"
+)
+
+// Цвета видов символов; символы без цвета выводятся как есть
+var highlightColors = map[parser.HighlightKind]string{
+	parser.HighlightCommand:        "\x1b[1;32m",
+	parser.HighlightUnknownCommand: "\x1b[1;31m",
+	parser.HighlightString:         "\x1b[33m",
+	parser.HighlightVariable:       "\x1b[36m",
+	parser.HighlightPipe:           "\x1b[35m",
+	parser.HighlightRedirect:       "\x1b[34m",
+	parser.HighlightComment:        "\x1b[90m",
+	parser.HighlightError:          "\x1b[4;31m",
+}
+
+// Colorize окрашивает строку line управляющими последовательностями терминала.
+// pending - уже введенные строки той же незаконченной команды:
+// они разбираются вместе с line, но в результат не входят.
+func Colorize(pending string, line string, known func(name string) bool) string {
+	kinds := parser.Highlight(pending+line, known).Kinds[len([]rune(pending)):]
+
+	var result strings.Builder
+	color := ""
+	for i, r := range []rune(line) {
+		if next := highlightColors[kinds[i]]; next != color {
+			if color != "" {
+				result.WriteString(termReset)
+			}
+			result.WriteString(next)
+			color = next
+		}
+		result.WriteRune(r)
+	}
+	if color != "" {
+		result.WriteString(termReset)
+	}
+	return result.String()
+}
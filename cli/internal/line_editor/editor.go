@@ -0,0 +1,292 @@
+package lineeditor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"shell/internal/parser"
+	"strings"
+	"unicode/utf8"
+)
+
+// Editor - редактор строки ввода интерактивной оболочки.
+// Строка подсвечивается по мере набора: команды, строки, подстановки, пайпы,
+// перенаправления и комментарии окрашиваются каждые в свой цвет,
+// незакрытая кавычка выделяется как ошибка.
+// Editor реализует io.Reader: каждое чтение возвращает очередную введенную строку
+// с переводом строки, Ctrl-D в пустой строке означает конец ввода, а Ctrl-C
+// отбрасывает строку и возвращает ошибку parser.InputInterrupted, по которой
+// оболочка забывает незаконченную команду.
+// Приглашение выводит оболочка, редактор только дописывает строку после него.
+type Editor struct {
+	// Терминал, который на время редактирования переводится в посимвольный режим;
+	// nil, если ввод не терминал
+	terminal *os.File
+	input    io.Reader
+	output   io.Writer
+	known    func(name string) bool
+
+	history []string
+	// Уже введенные строки незаконченной команды: они подсвечиваются вместе
+	// с редактируемой строкой, чтобы, например, продолжение строки в кавычках
+	// окрашивалось как строка
+	pending string
+	// Часть введенной строки, еще не прочитанная оболочкой
+	buffered []byte
+}
+
+var _ io.Reader = &Editor{}
+
+// New создает редактор, читающий клавиши с терминала in и выводящий строку в out.
+// Функция known сообщает, известна ли команда, и определяет цвет имени команды.
+// Возвращает ошибку, если in не терминал.
+func New(in *os.File, out io.Writer, known func(name string) bool) (*Editor, error) {
+	if !isTerminal(int(in.Fd())) {
+		return nil, fmt.Errorf("line editor: %s is not a terminal", in.Name())
+	}
+	return &Editor{terminal: in, input: in, output: out, known: known}, nil
+}
+
+// Read возвращает очередную строку ввода, при необходимости дожидаясь,
+// пока пользователь ее наберет
+func (e *Editor) Read(p []byte) (int, error) {
+	if len(e.buffered) == 0 {
+		line, err := e.readLine()
+		if err != nil {
+			return 0, err
+		}
+		e.buffered = []byte(line + "\n")
+	}
+	n := copy(p, e.buffered)
+	e.buffered = e.buffered[n:]
+	return n, nil
+}
+
+// readLine переводит терминал в посимвольный режим на время редактирования строки
+func (e *Editor) readLine() (string, error) {
+	if e.terminal != nil {
+		restore, err := makeRaw(int(e.terminal.Fd()))
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	line, err := e.edit()
+	if err != nil {
+		return "", err
+	}
+
+	if line != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+		e.history = append(e.history, line)
+	}
+	if parser.Highlight(e.pending+line, e.known).Incomplete {
+		e.pending += line + "\n"
+	} else {
+		e.pending = ""
+	}
+	return line, nil
+}
+
+// Коды клавиш, которые обрабатывает редактор
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyNewLine   = 10
+	keyEnter     = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Состояние редактируемой строки
+type lineState struct {
+	line   []rune
+	cursor int
+	// Позиция курсора на экране относительно начала строки
+	shown int
+	// Номер показанной строки истории; len(history) - редактируемая строка
+	historyPos int
+	// Редактируемая строка, сохраненная на время просмотра истории
+	draft []rune
+}
+
+// edit читает клавиши до Enter и возвращает введенную строку.
+// Ctrl-C отбрасывает строку вместе с предыдущими строками незаконченной команды
+// и возвращает parser.InputInterrupted, Ctrl-D в пустой строке возвращает io.EOF.
+func (e *Editor) edit() (string, error) {
+	state := &lineState{historyPos: len(e.history)}
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			if err == io.EOF && len(state.line) != 0 {
+				break
+			}
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, keyNewLine:
+			state.cursor = len(state.line)
+			e.redraw(state)
+			_, err := io.WriteString(e.output, "\n")
+			return string(state.line), err
+		case keyCtrlC:
+			state.cursor = len(state.line)
+			e.redraw(state)
+			e.pending = ""
+			if _, err := io.WriteString(e.output, "^C\n"); err != nil {
+				return "", err
+			}
+			return "", parser.InputInterrupted
+		case keyCtrlD:
+			if len(state.line) == 0 {
+				io.WriteString(e.output, "\n")
+				return "", io.EOF
+			}
+			state.deleteAt(state.cursor)
+		case keyBackspace, keyDelete:
+			if state.cursor > 0 {
+				state.cursor--
+				state.deleteAt(state.cursor)
+			}
+		case keyCtrlA:
+			state.cursor = 0
+		case keyCtrlE:
+			state.cursor = len(state.line)
+		case keyCtrlB:
+			state.cursor = max(state.cursor-1, 0)
+		case keyCtrlF:
+			state.cursor = min(state.cursor+1, len(state.line))
+		case keyCtrlU:
+			state.line = state.line[state.cursor:]
+			state.cursor = 0
+		case keyCtrlK:
+			state.line = state.line[:state.cursor]
+		case keyEscape:
+			if err := e.handleEscape(state); err != nil {
+				return "", err
+			}
+		default:
+			if r < ' ' {
+				continue
+			}
+			state.line = append(state.line[:state.cursor], append([]rune{r}, state.line[state.cursor:]...)...)
+			state.cursor++
+		}
+		e.redraw(state)
+	}
+	return string(state.line), nil
+}
+
+// handleEscape обрабатывает управляющую последовательность, начинающуюся с ESC:
+// стрелки, Home, End и Delete
+func (e *Editor) handleEscape(state *lineState) error {
+	r, err := e.readRune()
+	if err != nil || r != '[' && r != 'O' {
+		return err
+	}
+	var params []rune
+	for {
+		if r, err = e.readRune(); err != nil {
+			return err
+		}
+		if r < '0' || r > '9' && r != ';' {
+			break
+		}
+		params = append(params, r)
+	}
+
+	switch string(r) {
+	case "A":
+		e.showHistory(state, state.historyPos-1)
+	case "B":
+		e.showHistory(state, state.historyPos+1)
+	case "C":
+		state.cursor = min(state.cursor+1, len(state.line))
+	case "D":
+		state.cursor = max(state.cursor-1, 0)
+	case "H":
+		state.cursor = 0
+	case "F":
+		state.cursor = len(state.line)
+	case "~":
+		switch string(params) {
+		case "1", "7":
+			state.cursor = 0
+		case "4", "8":
+			state.cursor = len(state.line)
+		case "3":
+			state.deleteAt(state.cursor)
+		}
+	}
+	return nil
+}
+
+// showHistory заменяет строку строкой истории с номером pos
+func (e *Editor) showHistory(state *lineState, pos int) {
+	if pos < 0 || pos > len(e.history) || pos == state.historyPos {
+		return
+	}
+	if state.historyPos == len(e.history) {
+		state.draft = state.line
+	}
+	state.historyPos = pos
+	if pos == len(e.history) {
+		state.line = state.draft
+	} else {
+		state.line = []rune(e.history[pos])
+	}
+	state.cursor = len(state.line)
+}
+
+func (state *lineState) deleteAt(i int) {
+	if i < len(state.line) {
+		state.line = append(state.line[:i:i], state.line[i+1:]...)
+	}
+}
+
+// readRune читает с терминала один символ в UTF-8.
+// Ввод читается по байту, чтобы не забрать у запускаемых команд набранное заранее.
+func (e *Editor) readRune() (rune, error) {
+	var buf [utf8.UTFMax]byte
+	if _, err := io.ReadFull(e.input, buf[:1]); err != nil {
+		return 0, err
+	}
+	size := 1
+	switch {
+	case buf[0] >= 0xf0:
+		size = 4
+	case buf[0] >= 0xe0:
+		size = 3
+	case buf[0] >= 0xc0:
+		size = 2
+	}
+	if _, err := io.ReadFull(e.input, buf[1:size]); err != nil {
+		return 0, err
+	}
+	r, _ := utf8.DecodeRune(buf[:size])
+	return r, nil
+}
+
+// redraw перерисовывает строку на месте: курсор возвращается к началу строки,
+// строка выводится с подсветкой, остаток экранной строки очищается,
+// и курсор переводится в позицию редактирования
+func (e *Editor) redraw(state *lineState) {
+	var screen strings.Builder
+	if state.shown > 0 {
+		fmt.Fprintf(&screen, "\x1b[%dD", state.shown)
+	}
+	screen.WriteString(Colorize(e.pending, string(state.line), e.known))
+	screen.WriteString(termClearLine)
+	if back := len(state.line) - state.cursor; back > 0 {
+		fmt.Fprintf(&screen, "\x1b[%dD", back)
+	}
+	state.shown = state.cursor
+	io.WriteString(e.output, screen.String())
+}
@@ -0,0 +1,78 @@
+package lineeditor
+
+import (
+	"bytes"
+	"io"
+	"shell/internal/parser"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func knownEcho(name string) bool {
+	return name == "echo"
+}
+
+func TestColorize(t *testing.T) {
+	require.Equal(t, "\x1b[1;32mecho\x1b[0m \x1b[33m\"a\x1b[0m\x1b[36m$x\x1b[0m\x1b[33m\"\x1b[0m \x1b[35m|\x1b[0m \x1b[1;31mnope\x1b[0m",
+		Colorize("", `echo "a$x" | nope`, knownEcho))
+	require.Equal(t, "\x1b[1;32mecho\x1b[0m \x1b[4;31m\"abc\x1b[0m", Colorize("", `echo "abc`, knownEcho))
+	// Продолжение строки в кавычках окрашивается как строка
+	require.Equal(t, "\x1b[33mdef'\x1b[0m \x1b[90m# c\x1b[0m", Colorize("echo 'abc\n", "def' # c", knownEcho))
+}
+
+// readAll читает из редактора все строки до конца ввода
+func readAll(t *testing.T, keys string) (string, *Editor) {
+	editor := &Editor{input: strings.NewReader(keys), output: &bytes.Buffer{}, known: knownEcho}
+	data, err := io.ReadAll(editor)
+	require.NoError(t, err)
+	return string(data), editor
+}
+
+func TestEditorKeys(t *testing.T) {
+	// Стрелки, Home, Delete, Backspace и Ctrl-U/Ctrl-K
+	lines, _ := readAll(t, "ecxo\x1b[D\x7fh\x1b[C\r"+
+		"xx\x1b[Hab\x1b[3~\r"+
+		"abc\x1b[D\x15z\x0bx\r"+
+		"\x04")
+	require.Equal(t, "echo\nabx\nzx\n", lines)
+}
+
+func TestEditorHistory(t *testing.T) {
+	lines, editor := readAll(t, "one\rtwo\rtwo\r\x1b[A!\rdraft\x1b[A\x1b[B\r")
+	require.Equal(t, "one\ntwo\ntwo\ntwo!\ndraft\n", lines)
+	require.Equal(t, []string{"one", "two", "two!", "draft"}, editor.history)
+}
+
+func TestEditorInterrupt(t *testing.T) {
+	editor := &Editor{input: strings.NewReader("echo 'a\rlost\x03echo\r"), output: io.Discard, known: knownEcho}
+	line, err := editor.readLine()
+	require.NoError(t, err)
+	require.Equal(t, "echo 'a", line)
+	require.NotEmpty(t, editor.pending)
+
+	// Ctrl-C отбрасывает строку и незаконченную команду
+	_, err = editor.Read(make([]byte, 16))
+	require.ErrorIs(t, err, parser.InputInterrupted)
+	require.Empty(t, editor.pending)
+
+	line, err = editor.readLine()
+	require.NoError(t, err)
+	require.Equal(t, "echo", line)
+}
+
+func TestEditorPending(t *testing.T) {
+	var lines []string
+	editor := &Editor{input: strings.NewReader("echo 'a\rb' |\rcat\r"), output: io.Discard, known: knownEcho}
+	for range 3 {
+		line, err := editor.readLine()
+		require.NoError(t, err)
+		lines = append(lines, line)
+		if len(lines) < 3 {
+			require.NotEmpty(t, editor.pending)
+		}
+	}
+	require.Equal(t, []string{"echo 'a", "b' |", "cat"}, lines)
+	require.Empty(t, editor.pending)
+}
@@ -0,0 +1,31 @@
+package lineeditor
+
+import "golang.org/x/sys/unix"
+
+// isTerminal сообщает, что fd - терминал
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	return err == nil
+}
+
+// makeRaw переводит терминал в посимвольный режим без эха и без генерации сигналов
+// по Ctrl-C и Ctrl-Z: эти клавиши обрабатывает редактор.
+// Обработка вывода (замена \n на \r\n) сохраняется.
+// Возвращает функцию, восстанавливающую прежний режим.
+func makeRaw(fd int) (func() error, error) {
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.Iflag &^= unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ICANON | unix.ECHO | unix.ISIG | unix.IEXTEN
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return unix.IoctlSetTermios(fd, ioctlSetTermios, old)
+	}, nil
+}
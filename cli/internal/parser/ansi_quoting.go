@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Односимвольные escape-последовательности строки $'...'
+var ansiEscapes = map[byte]string{
+	'a': "\a", 'b': "\b", 'e': "\x1b", 'E': "\x1b", 'f': "\f", 'n': "\n", 'r': "\r",
+	't': "\t", 'v': "\v", '\\': "\\", '\'': "'", '"': "\"", '?': "?",
+}
+
+// decodeAnsiC раскрывает escape-последовательности строки $'...' так же, как bash:
+// односимвольные (\n, \t, \e и другие), восьмеричные \NNN, шестнадцатеричные \xHH,
+// символы Unicode \uHHHH и \UHHHHHHHH и управляющие символы \cX.
+// Неизвестная последовательность остается как есть.
+func decodeAnsiC(s string) string {
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			result.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		c := s[i]
+		if escape, ok := ansiEscapes[c]; ok {
+			result.WriteString(escape)
+			continue
+		}
+
+		switch {
+		case c >= '0' && c <= '7':
+			n := digitsPrefix(s[i:], 8, 3)
+			value, _ := strconv.ParseUint(s[i:i+n], 8, 16)
+			result.WriteByte(byte(value))
+			i += n - 1
+		case c == 'x' || c == 'u' || c == 'U':
+			limit := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			n := digitsPrefix(s[i+1:], 16, limit)
+			if n == 0 {
+				result.WriteByte('\\')
+				result.WriteByte(c)
+				continue
+			}
+			value, _ := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if c == 'x' {
+				result.WriteByte(byte(value))
+			} else if utf8.ValidRune(rune(value)) {
+				result.WriteRune(rune(value))
+			}
+			i += n
+		case c == 'c' && i+1 < len(s):
+			i++
+			result.WriteByte(s[i] & 0x1f)
+		default:
+			result.WriteByte('\\')
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
+
+// digitsPrefix возвращает число цифр в указанной системе счисления,
+// не более limit, с которых начинается строка
+func digitsPrefix(s string, base int, limit int) int {
+	n := 0
+	for n < len(s) && n < limit {
+		digit, err := strconv.ParseUint(s[n:n+1], base, 8)
+		if err != nil || digit >= uint64(base) {
+			break
+		}
+		n++
+	}
+	return n
+}
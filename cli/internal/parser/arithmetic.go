@@ -0,0 +1,693 @@
+package parser
+
+import (
+	"fmt"
+	envsholder "shell/internal/envs_holder"
+	"strconv"
+	"strings"
+)
+
+// Максимальная глубина рекурсивного вычисления значений переменных.
+// Значение переменной, не являющееся числом, вычисляется как выражение.
+const maxArithmeticDepth = 64
+
+// ArithmeticError описывает ошибку разбора или вычисления арифметического выражения
+type ArithmeticError struct {
+	Expr    string
+	Message string
+}
+
+func (e *ArithmeticError) Error() string {
+	return fmt.Sprintf("%s: %s", strings.TrimSpace(e.Expr), e.Message)
+}
+
+type arithTokenType int
+
+const (
+	arithEnd arithTokenType = iota
+	arithNumber
+	arithName
+	arithOperator
+)
+
+type arithToken struct {
+	tokenType arithTokenType
+	value     string
+	number    int64
+}
+
+// Узел дерева арифметического выражения
+type arithNode interface {
+	eval(e *arithEvaluator) (int64, error)
+}
+
+type arithNumberNode struct {
+	value int64
+}
+
+type arithVarNode struct {
+	name string
+}
+
+type arithUnaryNode struct {
+	op      string
+	operand arithNode
+}
+
+type arithBinaryNode struct {
+	op  string
+	lhs arithNode
+	rhs arithNode
+}
+
+type arithTernaryNode struct {
+	cond    arithNode
+	ifTrue  arithNode
+	ifFalse arithNode
+}
+
+type arithAssignNode struct {
+	op    string
+	name  string
+	value arithNode
+}
+
+// Префиксный или постфиксный инкремент/декремент
+type arithIncDecNode struct {
+	op     string
+	name   string
+	prefix bool
+}
+
+// Бинарные операторы в порядке возрастания приоритета.
+// Присваивание, тернарный оператор, запятая и возведение в степень
+// разбираются отдельно, так как отличаются ассоциативностью.
+var arithBinaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// Операторы отсортированы по убыванию длины, чтобы лексер выбирал самый длинный
+var arithOperators = []string{
+	"<<=", ">>=",
+	"**", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "=", "!", "~", "&", "^", "|", "?", ":", ",", "(", ")",
+}
+
+var arithAssignOperators = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"<<=": true, ">>=": true, "&=": true, "^=": true, "|=": true,
+}
+
+// EvalArithmetic вычисляет арифметическое выражение в стиле $(( )).
+// Поддерживаются операторы языка C с их приоритетами, скобки, присваивания,
+// инкременты, переменные (по имени или через $) и литералы вида 0x1F, 017, 2#1010.
+// Значения переменных читаются из vars, результаты присваиваний записываются туда же
+// функцией set; nil означает запись в vars без проверок.
+func EvalArithmetic(expr string, vars *envsholder.Env, set ArithmeticSetter) (int64, error) {
+	e := &arithEvaluator{vars: vars, set: set}
+	value, err := e.evalString(expr)
+	if assignErr, ok := err.(*arithAssignError); ok {
+		return 0, assignErr.err
+	}
+	return value, err
+}
+
+// ArithmeticSetter записывает результат присваивания в арифметическом выражении.
+// Ошибка (например, запрет изменять переменную в ограниченном режиме)
+// прерывает вычисление выражения.
+type ArithmeticSetter func(name string, value string) error
+
+// CheckArithmetic проверяет синтаксис арифметического выражения, не вычисляя его
+func CheckArithmetic(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	_, err := parseArithmetic(expr)
+	return err
+}
+
+// Вычислитель арифметических выражений
+type arithEvaluator struct {
+	vars  *envsholder.Env
+	set   ArithmeticSetter
+	depth int
+}
+
+func (e *arithEvaluator) evalString(expr string) (int64, error) {
+	if strings.TrimSpace(expr) == "" {
+		return 0, nil
+	}
+
+	node, err := parseArithmetic(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := node.eval(e)
+	if _, ok := err.(*arithAssignError); ok {
+		return 0, err
+	}
+	if _, ok := err.(*ArithmeticError); err != nil && !ok {
+		return 0, &ArithmeticError{Expr: expr, Message: err.Error()}
+	}
+	return value, err
+}
+
+// parseArithmetic строит дерево арифметического выражения
+func parseArithmetic(expr string) (arithNode, error) {
+	tokens, err := tokenizeArithmetic(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &arithParser{expr: expr, tokens: tokens}
+	node, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().tokenType != arithEnd {
+		return nil, p.error("syntax error in expression (error token is \"%s\")", p.peek().value)
+	}
+	return node, nil
+}
+
+// tokenizeArithmetic разбивает выражение на числа, имена и операторы
+func tokenizeArithmetic(expr string) ([]arithToken, error) {
+	var tokens []arithToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c):
+			end := i
+			for end < len(expr) && (isNameChar(expr[end]) || expr[end] == '#' || expr[end] == '@') {
+				end++
+			}
+			number, err := parseArithmeticNumber(expr[i:end])
+			if err != nil {
+				return nil, &ArithmeticError{Expr: expr, Message: err.Error()}
+			}
+			tokens = append(tokens, arithToken{tokenType: arithNumber, value: expr[i:end], number: number})
+			i = end
+		case isNameStart(c) || (c == '$' && i+1 < len(expr) && isNameStart(expr[i+1])):
+			if c == '$' {
+				i++
+			}
+			end := i
+			for end < len(expr) && isNameChar(expr[end]) {
+				end++
+			}
+			tokens = append(tokens, arithToken{tokenType: arithName, value: expr[i:end]})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range arithOperators {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &ArithmeticError{Expr: expr, Message: fmt.Sprintf("syntax error: invalid arithmetic operator (error token is \"%s\")", expr[i:])}
+			}
+			tokens = append(tokens, arithToken{tokenType: arithOperator, value: op})
+			i += len(op)
+		}
+	}
+	return append(tokens, arithToken{tokenType: arithEnd}), nil
+}
+
+// parseArithmeticNumber разбирает целочисленный литерал:
+// десятичный, восьмеричный (0NNN), шестнадцатеричный (0xNN) или base#digits.
+// В системах счисления с основанием до 36 регистр цифр не важен, с большим
+// основанием строчные буквы идут перед заглавными, затем @ и _.
+func parseArithmeticNumber(literal string) (int64, error) {
+	if baseStr, digits, ok := strings.Cut(literal, "#"); ok {
+		base, err := strconv.Atoi(baseStr)
+		if err != nil || base < 2 || base > 64 || digits == "" {
+			return 0, fmt.Errorf("invalid arithmetic base (error token is \"%s\")", literal)
+		}
+		var value int64
+		for i := 0; i < len(digits); i++ {
+			digit := arithmeticDigit(digits[i], base)
+			if digit < 0 || digit >= base {
+				return 0, fmt.Errorf("value too great for base (error token is \"%s\")", literal)
+			}
+			value = value*int64(base) + int64(digit)
+		}
+		return value, nil
+	}
+
+	base := 10
+	digits := literal
+	switch {
+	case strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0X"):
+		base, digits = 16, literal[2:]
+	case len(literal) > 1 && literal[0] == '0':
+		base, digits = 8, literal[1:]
+	}
+	value, err := strconv.ParseUint(digits, base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value too great for base (error token is \"%s\")", literal)
+	}
+	return int64(value), nil
+}
+
+func arithmeticDigit(c byte, base int) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		if base <= 36 {
+			return int(c-'A') + 10
+		}
+		return int(c-'A') + 36
+	case c == '@':
+		return 62
+	case c == '_':
+		return 63
+	}
+	return -1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+//////////////////////////////////
+
+// Разбор арифметического выражения методом рекурсивного спуска
+type arithParser struct {
+	expr   string
+	tokens []arithToken
+	pos    int
+}
+
+func (p *arithParser) peek() arithToken {
+	return p.tokens[p.pos]
+}
+
+func (p *arithParser) next() arithToken {
+	token := p.tokens[p.pos]
+	if token.tokenType != arithEnd {
+		p.pos++
+	}
+	return token
+}
+
+func (p *arithParser) isOperator(ops ...string) (string, bool) {
+	token := p.peek()
+	if token.tokenType != arithOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if token.value == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *arithParser) error(format string, args ...any) error {
+	return &ArithmeticError{Expr: p.expr, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *arithParser) parseComma() (arithNode, error) {
+	node, err := p.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOperator(","); !ok {
+			return node, nil
+		}
+		p.next()
+		rhs, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		node = &arithBinaryNode{op: ",", lhs: node, rhs: rhs}
+	}
+}
+
+func (p *arithParser) parseAssignment() (arithNode, error) {
+	if p.peek().tokenType == arithName {
+		if op := p.tokens[p.pos+1]; op.tokenType == arithOperator && arithAssignOperators[op.value] {
+			name := p.next().value
+			p.next()
+			value, err := p.parseAssignment()
+			if err != nil {
+				return nil, err
+			}
+			return &arithAssignNode{op: op.value, name: name, value: value}, nil
+		}
+	}
+	return p.parseTernary()
+}
+
+func (p *arithParser) parseTernary() (arithNode, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.isOperator("?"); !ok {
+		return cond, nil
+	}
+	p.next()
+
+	ifTrue, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.isOperator(":"); !ok {
+		return nil, p.error("`:' expected for conditional expression")
+	}
+	p.next()
+
+	ifFalse, err := p.parseAssignment()
+	if err != nil {
+		return nil, err
+	}
+	return &arithTernaryNode{cond: cond, ifTrue: ifTrue, ifFalse: ifFalse}, nil
+}
+
+// parseBinary разбирает левоассоциативные бинарные операторы уровня level и выше
+func (p *arithParser) parseBinary(level int) (arithNode, error) {
+	if level == len(arithBinaryLevels) {
+		return p.parsePower()
+	}
+
+	node, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.isOperator(arithBinaryLevels[level]...)
+		if !ok {
+			return node, nil
+		}
+		p.next()
+		rhs, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		node = &arithBinaryNode{op: op, lhs: node, rhs: rhs}
+	}
+}
+
+// parsePower разбирает правоассоциативное возведение в степень
+func (p *arithParser) parsePower() (arithNode, error) {
+	base, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.isOperator("**"); !ok {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.parsePower()
+	if err != nil {
+		return nil, err
+	}
+	return &arithBinaryNode{op: "**", lhs: base, rhs: exponent}, nil
+}
+
+func (p *arithParser) parseUnary() (arithNode, error) {
+	if op, ok := p.isOperator("++", "--"); ok {
+		p.next()
+		name := p.next()
+		if name.tokenType != arithName {
+			return nil, p.error("syntax error: operand expected (error token is \"%s\")", name.value)
+		}
+		return &arithIncDecNode{op: op, name: name.value, prefix: true}, nil
+	}
+	if op, ok := p.isOperator("!", "~", "+", "-"); ok {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithUnaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *arithParser) parsePostfix() (arithNode, error) {
+	token := p.next()
+	switch token.tokenType {
+	case arithNumber:
+		return &arithNumberNode{value: token.number}, nil
+	case arithName:
+		if op, ok := p.isOperator("++", "--"); ok {
+			p.next()
+			return &arithIncDecNode{op: op, name: token.value, prefix: false}, nil
+		}
+		return &arithVarNode{name: token.value}, nil
+	case arithOperator:
+		if token.value == "(" {
+			node, err := p.parseComma()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.isOperator(")"); !ok {
+				return nil, p.error("missing `)'")
+			}
+			p.next()
+			return node, nil
+		}
+	}
+
+	errorToken := token.value
+	if token.tokenType == arithEnd {
+		errorToken = ""
+	}
+	return nil, p.error("syntax error: operand expected (error token is \"%s\")", errorToken)
+}
+
+//////////////////////////////////
+
+// variable возвращает числовое значение переменной.
+// Неустановленная или пустая переменная равна нулю.
+func (e *arithEvaluator) variable(name string) (int64, error) {
+	value := strings.TrimSpace(e.vars.Vars[name])
+	if value == "" {
+		return 0, nil
+	}
+
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return number, nil
+	}
+
+	e.depth++
+	defer func() { e.depth-- }()
+	if e.depth > maxArithmeticDepth {
+		return 0, fmt.Errorf("expression recursion level exceeded (error token is \"%s\")", name)
+	}
+	return e.evalString(value)
+}
+
+// Ошибка функции записи присваиваний: возвращается из EvalArithmetic как есть
+type arithAssignError struct {
+	err error
+}
+
+func (e *arithAssignError) Error() string {
+	return e.err.Error()
+}
+
+func (e *arithEvaluator) assign(name string, value int64) error {
+	if e.set != nil {
+		if err := e.set(name, strconv.FormatInt(value, 10)); err != nil {
+			return &arithAssignError{err}
+		}
+		return nil
+	}
+	e.vars.Init()
+	e.vars.Set(name, strconv.FormatInt(value, 10))
+	return nil
+}
+
+func (n *arithNumberNode) eval(e *arithEvaluator) (int64, error) {
+	return n.value, nil
+}
+
+func (n *arithVarNode) eval(e *arithEvaluator) (int64, error) {
+	return e.variable(n.name)
+}
+
+func (n *arithUnaryNode) eval(e *arithEvaluator) (int64, error) {
+	value, err := n.operand.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "!":
+		return boolToInt(value == 0), nil
+	case "~":
+		return ^value, nil
+	case "-":
+		return -value, nil
+	}
+	return value, nil
+}
+
+func (n *arithBinaryNode) eval(e *arithEvaluator) (int64, error) {
+	lhs, err := n.lhs.eval(e)
+	if err != nil {
+		return 0, err
+	}
+
+	// Логические операторы вычисляются по короткой схеме
+	switch n.op {
+	case "&&":
+		if lhs == 0 {
+			return 0, nil
+		}
+	case "||":
+		if lhs != 0 {
+			return 1, nil
+		}
+	}
+
+	rhs, err := n.rhs.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	return applyArithmetic(n.op, lhs, rhs)
+}
+
+func (n *arithTernaryNode) eval(e *arithEvaluator) (int64, error) {
+	cond, err := n.cond.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if cond != 0 {
+		return n.ifTrue.eval(e)
+	}
+	return n.ifFalse.eval(e)
+}
+
+func (n *arithAssignNode) eval(e *arithEvaluator) (int64, error) {
+	value, err := n.value.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if n.op != "=" {
+		current, err := e.variable(n.name)
+		if err != nil {
+			return 0, err
+		}
+		if value, err = applyArithmetic(strings.TrimSuffix(n.op, "="), current, value); err != nil {
+			return 0, err
+		}
+	}
+	if err := e.assign(n.name, value); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+func (n *arithIncDecNode) eval(e *arithEvaluator) (int64, error) {
+	current, err := e.variable(n.name)
+	if err != nil {
+		return 0, err
+	}
+	updated := current + 1
+	if n.op == "--" {
+		updated = current - 1
+	}
+	if err := e.assign(n.name, updated); err != nil {
+		return 0, err
+	}
+	if n.prefix {
+		return updated, nil
+	}
+	return current, nil
+}
+
+// applyArithmetic применяет бинарный оператор к вычисленным операндам
+func applyArithmetic(op string, lhs int64, rhs int64) (int64, error) {
+	switch op {
+	case ",":
+		return rhs, nil
+	case "&&", "||":
+		return boolToInt(rhs != 0), nil
+	case "|":
+		return lhs | rhs, nil
+	case "^":
+		return lhs ^ rhs, nil
+	case "&":
+		return lhs & rhs, nil
+	case "==":
+		return boolToInt(lhs == rhs), nil
+	case "!=":
+		return boolToInt(lhs != rhs), nil
+	case "<":
+		return boolToInt(lhs < rhs), nil
+	case "<=":
+		return boolToInt(lhs <= rhs), nil
+	case ">":
+		return boolToInt(lhs > rhs), nil
+	case ">=":
+		return boolToInt(lhs >= rhs), nil
+	case "<<":
+		return lhs << uint64(rhs&63), nil
+	case ">>":
+		return lhs >> uint64(rhs&63), nil
+	case "+":
+		return lhs + rhs, nil
+	case "-":
+		return lhs - rhs, nil
+	case "*":
+		return lhs * rhs, nil
+	case "/", "%":
+		if rhs == 0 {
+			return 0, fmt.Errorf("division by 0")
+		}
+		if op == "/" {
+			return lhs / rhs, nil
+		}
+		return lhs % rhs, nil
+	case "**":
+		if rhs < 0 {
+			return 0, fmt.Errorf("exponent less than 0")
+		}
+		result := int64(1)
+		for ; rhs > 0; rhs >>= 1 {
+			if rhs&1 == 1 {
+				result *= lhs
+			}
+			lhs *= lhs
+		}
+		return result, nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", op)
+}
+
+func boolToInt(value bool) int64 {
+	if value {
+		return 1
+	}
+	return 0
+}
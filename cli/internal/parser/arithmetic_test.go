@@ -0,0 +1,114 @@
+package parser_test
+
+import (
+	envsholder "shell/internal/envs_holder"
+	. "shell/internal/parser"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalArithmetic(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected int64
+	}{
+		{"", 0},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"7 / 2", 3},
+		{"-7 % 3", -1},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", 4},
+		{"1 << 4 | 1", 17},
+		{"~0", -1},
+		{"!5", 0},
+		{"3 > 2 && 2 > 3", 0},
+		{"3 > 2 || 2 > 3", 1},
+		{"1 == 1 ? 10 : 20", 10},
+		{"0 ? 10 : 0 ? 20 : 30", 30},
+		{"0x1F", 31},
+		{"017", 15},
+		{"2#1010", 10},
+		{"16#ff", 255},
+		{"64#_", 63},
+		{"x + y", 12},
+		{"$x * 2", 10},
+		{"unset + 1", 1},
+		{"expr * 2", 24},
+		{"1, 2, 3", 3},
+		{"6 & 3 ^ 1", 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			vars := envsholder.Env{Vars: map[string]string{"x": "5", "y": "7", "expr": "x+y"}}
+			actual, err := EvalArithmetic(tc.expr, &vars, nil)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestEvalArithmeticAssignment(t *testing.T) {
+	vars := envsholder.Env{Vars: map[string]string{"i": "1"}}
+
+	cases := []struct {
+		expr     string
+		expected int64
+		i        string
+	}{
+		{"i++", 1, "2"},
+		{"++i", 3, "3"},
+		{"i--", 3, "2"},
+		{"--i", 1, "1"},
+		{"i += 10", 11, "11"},
+		{"i *= 2", 22, "22"},
+		{"i <<= 1", 44, "44"},
+		{"i %= 5", 4, "4"},
+		{"i = j = 3", 3, "3"},
+	}
+
+	for _, tc := range cases {
+		actual, err := EvalArithmetic(tc.expr, &vars, nil)
+		require.NoError(t, err, tc.expr)
+		require.Equal(t, tc.expected, actual, tc.expr)
+		require.Equal(t, tc.i, vars.Vars["i"], tc.expr)
+	}
+	require.Equal(t, "3", vars.Vars["j"])
+}
+
+func TestEvalArithmeticShortCircuit(t *testing.T) {
+	vars := envsholder.Env{Vars: map[string]string{}}
+	_, err := EvalArithmetic("0 && (a = 1), 1 || (b = 1), 1 ? c = 1 : (d = 1)", &vars, nil)
+	require.NoError(t, err)
+	require.NotContains(t, vars.Vars, "a")
+	require.NotContains(t, vars.Vars, "b")
+	require.NotContains(t, vars.Vars, "d")
+	require.Equal(t, "1", vars.Vars["c"])
+}
+
+func TestEvalArithmeticErrors(t *testing.T) {
+	cases := []string{
+		"1 / 0",
+		"5 % 0",
+		"2 ** -1",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"2#102",
+		"08",
+		"1 ? 2",
+		"a = ",
+		"1 @ 2",
+		"loop",
+	}
+
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			vars := envsholder.Env{Vars: map[string]string{"loop": "loop + 1"}}
+			_, err := EvalArithmetic(expr, &vars, nil)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"shell/pkg/vfs"
+	"sort"
+	"strings"
+)
+
+// expandGlob раскрывает шаблон имен файлов относительно директории dir
+// в файловой системе fsys (nil - файловая система ОС).
+// Как и в bash, символы шаблона не сопоставляются с точкой в начале имени,
+// если точка не указана в шаблоне явно.
+// Возвращает отсортированный список путей в том же виде (относительном
+// или абсолютном), в котором задан шаблон, или nil, если совпадений нет.
+func expandGlob(pattern string, dir string, fsys vfs.FS) []string {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	if fsys == nil {
+		fsys = vfs.OS
+	}
+
+	absolute := filepath.IsAbs(pattern)
+	fullPattern := pattern
+	if !absolute {
+		fullPattern = filepath.Join(dir, pattern)
+	}
+
+	matches, err := vfs.Glob(fsys, fullPattern)
+	if err != nil || len(matches) == 0 {
+		return nil
+	}
+
+	patternParts := strings.Split(filepath.Clean(pattern), "/")
+	result := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if !absolute {
+			match, err = filepath.Rel(dir, match)
+			if err != nil {
+				continue
+			}
+		}
+		if hidesDotFiles(patternParts, strings.Split(match, "/")) {
+			continue
+		}
+		result = append(result, match)
+	}
+	sort.Strings(result)
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// hidesDotFiles сообщает, что совпадение содержит скрытый файл
+// в той части пути, где шаблон не начинается с точки
+func hidesDotFiles(patternParts []string, matchParts []string) bool {
+	if len(patternParts) != len(matchParts) {
+		return false
+	}
+	for i, part := range matchParts {
+		if strings.HasPrefix(part, ".") && !strings.HasPrefix(patternParts[i], ".") {
+			return true
+		}
+	}
+	return false
+}
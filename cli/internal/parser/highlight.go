@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	envsholder "shell/internal/envs_holder"
+	"strings"
+)
+
+// Вид символа строки для подсветки синтаксиса
+type HighlightKind int
+
+const (
+	HighlightNone           HighlightKind = iota // пробелы и переводы строк
+	HighlightCommand                             // имя известной команды
+	HighlightUnknownCommand                      // имя команды, которая не найдена
+	HighlightArgument                            // аргументы, присваивания и имена файлов
+	HighlightString                              // строки в кавычках
+	HighlightVariable                            // подстановки переменных и арифметические выражения
+	HighlightPipe                                // оператор |
+	HighlightRedirect                            // операторы перенаправления и подстановки процессов
+	HighlightComment                             // комментарии
+	HighlightError                               // незакрытая кавычка до конца ввода или строка с ошибкой
+)
+
+// Разметка строки для подсветки синтаксиса
+type Highlighting struct {
+	// Вид каждого символа строки
+	Kinds []HighlightKind
+	// Команда не закончена: открыта кавычка, в конце стоит \ или |
+	Incomplete bool
+}
+
+// Highlight размечает символы незаконченного ввода для подсветки синтаксиса.
+// Ввод разбирает Tokenizer без подстановок: вид символа определяется состоянием
+// автомата, в котором токенизатор его обработал, а имена команд - по токенам слов.
+// Незакрытая кавычка размечается как ошибка до конца ввода, строка с другой
+// синтаксической ошибкой - с места ошибки до конца строки.
+// Текст подстановки процесса размечается отдельно, как самостоятельная команда.
+// Функция known сообщает, известна ли команда; имя команды, содержащее подстановку,
+// считается известным.
+func Highlight(source string, known func(name string) bool) Highlighting {
+	h := highlighter{
+		runes:          []rune(source),
+		known:          known,
+		lineStarts:     []int{0},
+		dollar:         -1,
+		substitutionAt: -1,
+		unclosedQuote:  -1,
+	}
+	for i, r := range h.runes {
+		if r == '\n' {
+			h.lineStarts = append(h.lineStarts, i+1)
+		}
+	}
+	h.kinds = make([]HighlightKind, len(h.runes))
+	h.traced = make([]bool, len(h.runes))
+
+	vars := envsholder.Env{}
+	vars.Init()
+	h.tokenizer = NewTokenizer(strings.NewReader(source), &vars)
+	h.tokenizer.SetNoExpand(true)
+	h.tokenizer.trace = h.trace
+	h.run()
+	return Highlighting{Kinds: h.kinds, Incomplete: h.incomplete}
+}
+
+type highlighter struct {
+	runes     []rune
+	kinds     []HighlightKind
+	known     func(name string) bool
+	tokenizer *Tokenizer
+	// Индексы первых символов строк ввода
+	lineStarts []int
+	// Символ уже обработан токенизатором; остальные символы - вторые символы
+	// операторов вроде >> или $((, которые токенизатор читает вместе с первым
+	traced []bool
+	// Последний обработанный символ
+	last int
+
+	// Последний символ $ и вид, который он получает, если остается литералом
+	dollar     int
+	dollarKind HighlightKind
+	// Текст подстановок процессов: начало и конец, включая закрывающую скобку,
+	// и последний символ, обработанный внутри подстановки
+	substitutions  [][2]int
+	substitutionAt int
+	// Места синтаксических ошибок, с которых строка размечается как ошибка,
+	// и незакрытая кавычка, с которой как ошибка размечается весь остаток ввода
+	lineErrors    []int
+	unclosedQuote int
+
+	incomplete bool
+}
+
+// index возвращает индекс символа ввода в позиции pos
+func (h *highlighter) index(pos position) int {
+	if pos.line < 1 || pos.line > len(h.lineStarts) {
+		return len(h.runes)
+	}
+	return min(h.lineStarts[pos.line-1]+pos.column-1, len(h.runes))
+}
+
+// trace размечает символ по состоянию автомата, в котором его обрабатывает токенизатор.
+// Символ, завершивший подстановку переменной, обрабатывается повторно в объемлющем
+// состоянии, и его разметка перезаписывается.
+func (h *highlighter) trace(pos position, state lexerState, class runeTokenClass, r rune) {
+	i := h.index(pos)
+	if i >= len(h.runes) {
+		return
+	}
+	h.traced[i], h.last = true, i
+
+	kind := HighlightString
+	switch state {
+	case startState, inWordState:
+		kind = h.wordKind(i, state, class)
+	case escapingState:
+		kind = HighlightArgument
+		if class == endLineRuneClass {
+			kind = HighlightNone
+		}
+	case quotingEscapingState:
+		if class == envVarClass {
+			h.dollar, h.dollarKind = i, HighlightString
+			kind = HighlightVariable
+		}
+	case commentState:
+		kind = HighlightComment
+		if class == endLineRuneClass {
+			kind = HighlightNone
+		}
+	case enviromentVariableState:
+		kind = h.variableKind(i, class, r)
+	case arithmeticState:
+		kind = HighlightVariable
+	case processSubstitutionState:
+		kind = HighlightRedirect
+		if h.substitutionAt != i-1 {
+			h.substitutions = append(h.substitutions, [2]int{i, i})
+		}
+		h.substitutions[len(h.substitutions)-1][1] = i
+		h.substitutionAt = i
+	}
+	h.kinds[i] = kind
+}
+
+// wordKind возвращает вид символа, обработанного вне кавычек
+func (h *highlighter) wordKind(i int, state lexerState, class runeTokenClass) HighlightKind {
+	switch class {
+	case spaceRuneClass, endLineRuneClass:
+		return HighlightNone
+	case commentRuneClass:
+		if state == startState {
+			return HighlightComment
+		}
+	case pipeRuneClass:
+		return HighlightPipe
+	case redirectRuneClass:
+		return HighlightRedirect
+	case escapingQuoteRuneClass, nonEscapingQuoteRuneClass:
+		return HighlightString
+	case envVarClass:
+		h.dollar, h.dollarKind = i, HighlightArgument
+		return HighlightVariable
+	}
+	return HighlightArgument
+}
+
+// variableKind возвращает вид символа после $. Если за $ не следует имя,
+// специальный параметр или $((, то $ остается литералом, а $' начинает строку.
+func (h *highlighter) variableKind(i int, class runeTokenClass, r rune) HighlightKind {
+	if i != h.dollar+1 || class == unknownRuneClass || strings.ContainsRune(specialParamRunes, r) {
+		return HighlightVariable
+	}
+	if r == '\'' && h.dollarKind == HighlightArgument {
+		h.kinds[h.dollar] = HighlightString
+		return HighlightString
+	}
+	h.kinds[h.dollar] = h.dollarKind
+	return HighlightVariable
+}
+
+// run разбирает ввод токенизатором и размечает имена команд.
+// Как и парсер, первое слово команды, кроме присваиваний, считается именем команды,
+// а слово после оператора перенаправления - именем файла.
+func (h *highlighter) run() {
+	commandPos, afterRedirect, afterPipe := true, false, false
+	for {
+		token, err := h.tokenizer.Next()
+		// Слово в конце ввода токенизатор возвращает вместе с io.EOF
+		if token != nil && (err == nil || err == io.EOF) {
+			switch token.TokenType {
+			case WordToken:
+				if afterRedirect {
+					afterRedirect = false
+				} else if commandPos && !isAssignment(token.Value) {
+					commandPos = false
+					h.markCommand(token)
+				}
+				afterPipe = false
+			case RedirectToken:
+				afterRedirect, afterPipe = true, false
+			case PipeToken:
+				commandPos, afterRedirect, afterPipe = true, false, true
+			case EndLineToken:
+				commandPos, afterRedirect = true, false
+			}
+		}
+
+		var syntaxErr *SyntaxError
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &syntaxErr) && syntaxErr.Incomplete:
+			h.incomplete = true
+			h.markIncomplete(syntaxErr)
+		case errors.As(err, &syntaxErr):
+			// Токенизатор пропустил остаток строки с ошибкой и продолжает со следующей
+			h.lineErrors = append(h.lineErrors, h.index(position{line: syntaxErr.Line, column: syntaxErr.Column}))
+			commandPos, afterRedirect, afterPipe = true, false, false
+			continue
+		}
+		break
+	}
+	if afterPipe {
+		h.incomplete = true
+	}
+	h.finish()
+}
+
+// markCommand окрашивает имя команды в зависимости от того, известна ли команда
+func (h *highlighter) markCommand(token *Token) {
+	start := h.index(position{line: token.Line, column: token.Column})
+	end := min(h.last+1, len(h.runes))
+
+	kind := HighlightUnknownCommand
+	if h.known == nil || h.known(token.Value) {
+		kind = HighlightCommand
+	}
+	for j := start; j < end; j++ {
+		if h.kinds[j] == HighlightVariable || h.kinds[j] == HighlightRedirect {
+			kind = HighlightCommand
+		}
+	}
+	for j := start; j < end; j++ {
+		if h.kinds[j] == HighlightArgument {
+			h.kinds[j] = kind
+		}
+	}
+}
+
+// markIncomplete размечает незакрытую кавычку как ошибку до конца ввода.
+// Ошибка строки $'...' начинается с символа $.
+func (h *highlighter) markIncomplete(err *SyntaxError) {
+	start := h.index(position{line: err.Line, column: err.Column})
+	if start >= len(h.runes) || h.runes[start] != '\'' && h.runes[start] != '"' {
+		return
+	}
+	if start > 0 && h.runes[start-1] == '$' && h.kinds[start-1] == HighlightString {
+		start--
+	}
+	h.unclosedQuote = start
+}
+
+// finish размечает символы, которые токенизатор прочитал вместе с предыдущими,
+// текст подстановок процессов и места ошибок
+func (h *highlighter) finish() {
+	for i := 1; i < len(h.runes); i++ {
+		if !h.traced[i] && h.runes[i] != '\n' {
+			h.kinds[i] = h.kinds[i-1]
+		}
+	}
+	// $ в конце ввода остается литералом
+	if h.dollar >= 0 && h.dollar == len(h.runes)-1 {
+		h.kinds[h.dollar] = h.dollarKind
+	}
+
+	unclosed := h.tokenizer.statesStack.CurrentState() == processSubstitutionState
+	for n, bounds := range h.substitutions {
+		start, end := bounds[0], bounds[1]
+		if !unclosed || n != len(h.substitutions)-1 {
+			// Последний символ - закрывающая скобка
+			end--
+		}
+		inner := Highlight(string(h.runes[start:end+1]), h.known)
+		copy(h.kinds[start:end+1], inner.Kinds)
+	}
+
+	for _, start := range h.lineErrors {
+		for j := start; j < len(h.runes) && h.runes[j] != '\n'; j++ {
+			h.kinds[j] = HighlightError
+		}
+	}
+	if h.unclosedQuote >= 0 {
+		for j := h.unclosedQuote; j < len(h.runes); j++ {
+			h.kinds[j] = HighlightError
+		}
+	}
+}
+
+// isAssignment сообщает, что слово - присваивание переменной ИМЯ=значение
+func isAssignment(word string) bool {
+	for i, r := range word {
+		switch {
+		case r == '=':
+			return i > 0
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package parser_test
+
+import (
+	"shell/internal/parser"
+	"strings"
+	"testing"
+)
+
+// kindLetters кодирует разметку строкой, по букве на символ
+var kindLetters = map[parser.HighlightKind]byte{
+	parser.HighlightNone: '.', parser.HighlightCommand: 'C', parser.HighlightUnknownCommand: 'U', parser.HighlightArgument: 'a',
+	parser.HighlightString: 's', parser.HighlightVariable: 'v', parser.HighlightPipe: 'p', parser.HighlightRedirect: 'r',
+	parser.HighlightComment: 'c', parser.HighlightError: 'E',
+}
+
+func TestHighlight(t *testing.T) {
+	known := func(name string) bool { return name == "echo" || name == "wc" }
+
+	cases := []struct {
+		source     string
+		expected   string
+		incomplete bool
+	}{
+		{source: `echo "a $x" 'b' | wcc >>out # done`, expected: `CCCC.sssvvs.sss.p.UUU.rraaa.cccccc`},
+		{source: `x=1 e\cho $HOME$1 <(wc -l)`, expected: `aaa.CCCCC.vvvvvvv.rrCC.aar`},
+		{source: `$cmd $'a\'b' $((1 + 2))`, expected: `vvvv.sssssss.vvvvvvvvvv`},
+		{source: "echo 'abc", expected: "CCCC.EEEE", incomplete: true},
+		{source: "echo $'a", expected: "CCCC.EEE", incomplete: true},
+		{source: "echo a |", expected: "CCCC.a.p", incomplete: true},
+		{source: "echo a \\", expected: "CCCC.a.a", incomplete: true},
+		{source: "echo 'a\nb' |\nwc", expected: "CCCC.sssss.p.CC"},
+		{source: `echo $ "$" a$ >>b`, expected: `CCCC.a.sss.aa.rra`},
+		{source: "echo $((1 +)) x\nwc", expected: "CCCC.vvvvvvvEEE.CC"},
+		{source: "cat <(wc 'a", expected: "UUU.rrCC.EE", incomplete: true},
+		{source: `"ec"ho a # c`, expected: `ssssCC.a.ccc`},
+	}
+	for _, tc := range cases {
+		result := parser.Highlight(tc.source, known)
+		var actual strings.Builder
+		for _, kind := range result.Kinds {
+			actual.WriteByte(kindLetters[kind])
+		}
+		if actual.String() != tc.expected || result.Incomplete != tc.incomplete {
+			t.Fatalf("%q: got %s (incomplete %v), expected %s", tc.source, actual.String(), result.Incomplete, tc.expected)
+		}
+	}
+}
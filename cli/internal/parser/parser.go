@@ -2,13 +2,75 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"shell/internal/command_meta"
+	"shell/internal/session"
+	"shell/pkg/vfs"
 	"strings"
 )
 
 var ParseError = errors.New("Cannot parse command")
 
+// Ошибка чтения, которой источник ввода сообщает, что набор команды прерван,
+// например нажатием Ctrl-C в редакторе строки. Токенизатор при ней забывает
+// незаконченную команду (открытую кавычку, продолжение строки), и следующая
+// строка разбирается с начала.
+var InputInterrupted = errors.New("input interrupted")
+
+// Синтаксическая ошибка с позицией во входном потоке.
+// errors.Is(err, ParseError) для нее истинно.
+type SyntaxError struct {
+	// Строка и столбец, начиная с единицы
+	Line   int
+	Column int
+	// Описание ошибки
+	Message string
+	// Текст строки, в которой произошла ошибка
+	Source string
+	// Ввод закончился раньше команды: незакрытая кавычка, \ или | в конце ввода
+	Incomplete bool
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ParseError
+}
+
+// Diagnostic возвращает сообщение об ошибке вместе со строкой,
+// в которой она произошла, и указателем ^ на позицию ошибки
+func (e *SyntaxError) Diagnostic() string {
+	if e.Source == "" && e.Column <= 1 {
+		return e.Error()
+	}
+
+	var caret strings.Builder
+	for i, r := range []rune(e.Source) {
+		if i >= e.Column-1 {
+			break
+		}
+		// Табуляции сохраняются, чтобы указатель совпал с позицией в строке
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+	return e.Error() + "\n" + e.Source + "\n" + caret.String()
+}
+
+// describeToken возвращает имя токена для сообщения об ошибке
+func describeToken(token *Token) string {
+	if token.TokenType == EndLineToken {
+		return "newline"
+	}
+	return token.Value
+}
+
 type Parser struct {
 	tokenizer *Tokenizer
 }
@@ -19,7 +81,22 @@ func NewParser(tokenizer *Tokenizer) *Parser {
 	}
 }
 
+// Разбирает очередную строку ввода в пайплайн команд.
+// Слова с подстановками вне кавычек разбиваются на поля по IFS,
+// кроме присваиваний и имен файлов перенаправлений.
+// Слова с шаблонами имен файлов раскрываются, если не включена опция noglob.
+// Слово после оператора перенаправления становится именем файла перенаправления.
+// Если строка заканчивается на |, пайплайн продолжается на следующей строке.
+// Подстановки процессов <(...) и >(...) разбираются в отдельные пайплайны,
+// а в словах остается их исходный текст.
+// Ошибки разбора возвращаются в виде *SyntaxError.
 func (p *Parser) Parse() ([]command_meta.CommandMeta, error) {
+	p.tokenizer.resetSource()
+	unexpected := func(token *Token) error {
+		pos := position{line: token.Line, column: token.Column}
+		return p.tokenizer.syntaxError(pos, false, "syntax error near unexpected token `%s'", describeToken(token))
+	}
+
 	pipe := make([]command_meta.CommandMeta, 0)
 	current := command_meta.CommandMeta{}
 	var prev_token TokenType = EndLineToken
@@ -30,22 +107,36 @@ func (p *Parser) Parse() ([]command_meta.CommandMeta, error) {
 			switch token.TokenType {
 			case WordToken:
 				{
-					if current.Name == "" {
-						if strings.Contains(token.Value, "=") {
-							current.Envs.Init()
-							parts := strings.SplitN(token.Value, "=", 2)
-							current.Envs.Set(parts[0], parts[1])
-						} else {
-							current.Name = token.Value
+					if prev_token == RedirectToken {
+						last := &current.Redirects[len(current.Redirects)-1]
+						last.Target = token.Value
+						for _, substitution := range token.Substitutions {
+							substitution.Word, substitution.InRedirect = len(current.Redirects)-1, true
+							current.Substitutions = append(current.Substitutions, substitution)
 						}
+					} else if current.Name == "" && strings.Contains(token.Value, "=") {
+						current.Envs.Init()
+						parts := strings.SplitN(token.Value, "=", 2)
+						current.Envs.Set(parts[0], parts[1])
+					} else if !token.Split {
+						addWords(&current, p.expandPattern(token.Value, token.Pattern), token.Value, token.Substitutions)
 					} else {
-						current.Args = append(current.Args, token.Value)
+						for _, field := range token.Fields {
+							addWords(&current, p.expandPattern(field.Value, field.Pattern), field.Value, field.Substitutions)
+						}
 					}
 				}
+			case RedirectToken:
+				{
+					if prev_token == RedirectToken {
+						return pipe, unexpected(token)
+					}
+					current.Redirects = append(current.Redirects, command_meta.Redirect{Op: token.Value})
+				}
 			case PipeToken:
 				{
-					if prev_token == EndLineToken {
-						return pipe, ParseError
+					if prev_token == EndLineToken || prev_token == PipeToken || prev_token == RedirectToken {
+						return pipe, unexpected(token)
 					}
 					if !current.IsEmpty() {
 						pipe = append(pipe, current)
@@ -55,7 +146,11 @@ func (p *Parser) Parse() ([]command_meta.CommandMeta, error) {
 			case EndLineToken:
 				{
 					if prev_token == PipeToken {
-						return pipe, ParseError
+						p.tokenizer.continueLine()
+						continue
+					}
+					if prev_token == RedirectToken {
+						return pipe, unexpected(token)
 					}
 					if !current.IsEmpty() {
 						pipe = append(pipe, current)
@@ -67,6 +162,12 @@ func (p *Parser) Parse() ([]command_meta.CommandMeta, error) {
 		}
 
 		if err == io.EOF {
+			switch prev_token {
+			case PipeToken:
+				return pipe, p.tokenizer.syntaxError(p.tokenizer.endPosition(), true, "syntax error: unexpected end of file")
+			case RedirectToken:
+				return pipe, p.tokenizer.syntaxError(p.tokenizer.endPosition(), true, "syntax error near unexpected token `newline'")
+			}
 			if !current.IsEmpty() {
 				pipe = append(pipe, current)
 			}
@@ -77,3 +178,41 @@ func (p *Parser) Parse() ([]command_meta.CommandMeta, error) {
 		}
 	}
 }
+
+// addWords добавляет слова, полученные из слова value, в имя и аргументы команды.
+// Подстановкам процессов из value указывается номер аргумента, если value
+// стало аргументом как есть, а не раскрылось по шаблону и не стало именем команды.
+func addWords(current *command_meta.CommandMeta, words []string, value string, substitutions []command_meta.ProcessSubstitution) {
+	for _, substitution := range substitutions {
+		substitution.Word = -1
+		if current.Name != "" && len(words) == 1 && words[0] == value {
+			substitution.Word = len(current.Args)
+		}
+		current.Substitutions = append(current.Substitutions, substitution)
+	}
+	for _, word := range words {
+		if current.Name == "" {
+			current.Name = word
+		} else {
+			current.Args = append(current.Args, word)
+		}
+	}
+}
+
+// expandPattern раскрывает шаблон имен файлов pattern слова value
+func (p *Parser) expandPattern(value string, pattern string) []string {
+	if pattern == "" || p.tokenizer.noExpand || p.tokenizer.option(session.OptionNoglob) {
+		return []string{value}
+	}
+
+	dir := ""
+	var fsys vfs.FS
+	if p.tokenizer.options != nil {
+		dir = p.tokenizer.options.Dir()
+		fsys = p.tokenizer.options.FS()
+	}
+	if matches := expandGlob(pattern, dir, fsys); matches != nil {
+		return matches
+	}
+	return []string{value}
+}
@@ -1,9 +1,14 @@
 package parser_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"shell/internal/command_meta"
 	envsholder "shell/internal/envs_holder"
 	. "shell/internal/parser"
+	"shell/pkg/vfs"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -38,3 +43,282 @@ func TestComplexEnvs(t *testing.T) {
 		}
 	}
 }
+
+// testOptions - опции раскрытия с фиксированным набором включенных опций
+type testOptions struct {
+	enabled map[string]bool
+	dir     string
+}
+
+func (o testOptions) Option(name string) bool { return o.enabled[name] }
+func (o testOptions) Dir() string             { return o.dir }
+func (o testOptions) FS() vfs.FS              { return vfs.OS }
+
+func parseLine(t *testing.T, s string, options ExpansionOptions) ([]command_meta.CommandMeta, error) {
+	vars := envsholder.Env{}
+	vars.Init()
+	tokenizer := NewTokenizer(strings.NewReader(s), &vars)
+	tokenizer.SetOptions(options)
+	return NewParser(tokenizer).Parse()
+}
+
+func TestRedirects(t *testing.T) {
+	commands, err := parseLine(t, "sort <in.txt -r >>'out file' | wc >|count\n", testOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []command_meta.CommandMeta{
+		{
+			Name: "sort",
+			Args: []string{"-r"},
+			Redirects: []command_meta.Redirect{
+				{Op: command_meta.RedirectInput, Target: "in.txt"},
+				{Op: command_meta.RedirectAppend, Target: "out file"},
+			},
+		},
+		{
+			Name:      "wc",
+			Redirects: []command_meta.Redirect{{Op: command_meta.RedirectClobber, Target: "count"}},
+		},
+	}
+	if len(commands) != len(expected) {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+	for i := range expected {
+		if !commands[i].Equal(&expected[i]) {
+			t.Fatalf("unexpected command %d: %v", i, commands[i])
+		}
+	}
+}
+
+func TestRedirectWithoutTarget(t *testing.T) {
+	for _, line := range []string{"echo >\n", "echo > | wc\n", "echo > > x\n"} {
+		if _, err := parseLine(t, line, testOptions{}); !errors.Is(err, ParseError) {
+			t.Fatalf("%q: expected parse error, got %v", line, err)
+		}
+	}
+}
+
+func TestGlobExpansion(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", ".hidden.txt", "c.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		line     string
+		noglob   bool
+		expected []string
+	}{
+		{line: "ls *.txt\n", expected: []string{"a.txt", "b.txt"}},
+		{line: "ls ?.log '*.txt'\n", expected: []string{"c.log", "*.txt"}},
+		{line: "ls *.md\n", expected: []string{"*.md"}},
+		{line: "ls *.txt\n", noglob: true, expected: []string{"*.txt"}},
+	}
+	for _, tc := range cases {
+		options := testOptions{enabled: map[string]bool{"noglob": tc.noglob}, dir: dir}
+		commands, err := parseLine(t, tc.line, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(commands[0].Args, " ") != strings.Join(tc.expected, " ") {
+			t.Fatalf("%q: unexpected args %q", tc.line, commands[0].Args)
+		}
+	}
+}
+
+func TestNounset(t *testing.T) {
+	options := testOptions{enabled: map[string]bool{"nounset": true}}
+	_, err := parseLine(t, "echo $missing\n", options)
+
+	var unbound *UnboundVariableError
+	if !errors.As(err, &unbound) || unbound.Name != "missing" {
+		t.Fatalf("expected unbound variable error, got %v", err)
+	}
+
+	if _, err := parseLine(t, "echo $missing\n", testOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeContinuation(t *testing.T) {
+	continuations := 0
+	vars := envsholder.Env{}
+	tokenizer := NewTokenizer(strings.NewReader("echo a |\n\n  wc\n"), &vars)
+	tokenizer.SetContinuation(func() { continuations++ })
+
+	commands, err := NewParser(tokenizer).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 2 || commands[1].Name != "wc" {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+	if continuations != 2 {
+		t.Fatalf("unexpected number of continuations: %d", continuations)
+	}
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	vars := envsholder.Env{}
+	tokenizer := NewTokenizer(strings.NewReader("echo |\tcat | | wc\necho next\n"), &vars)
+	parser := NewParser(tokenizer)
+
+	_, err := parser.Parse()
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Incomplete {
+		t.Fatalf("expected syntax error, got %v", err)
+	}
+	expected := "1:14: syntax error near unexpected token `|'\necho |\tcat | | wc\n      \t      ^"
+	if syntaxErr.Diagnostic() != expected {
+		t.Fatalf("unexpected diagnostic:\n%s", syntaxErr.Diagnostic())
+	}
+
+	// Остаток строки с ошибкой пропускается
+	commands, err := parser.Parse()
+	if err != nil || len(commands) != 1 || commands[0].Args[0] != "next" {
+		t.Fatalf("unexpected commands after error: %v %v", commands, err)
+	}
+
+	tokenizer = NewTokenizer(strings.NewReader("echo |"), &vars)
+	_, err = NewParser(tokenizer).Parse()
+	if !errors.As(err, &syntaxErr) || !syntaxErr.Incomplete {
+		t.Fatalf("expected incomplete input error, got %v", err)
+	}
+}
+
+func TestProcessSubstitution(t *testing.T) {
+	commands, err := parseLine(t, "diff <(sort 'a b') x>(wc -l | cat)y < <(echo ')')\n", testOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+
+	diff := commands[0]
+	if strings.Join(diff.Args, " ") != "<(sort 'a b') x>(wc -l | cat)y" || diff.Redirects[0].Target != "<(echo ')')" {
+		t.Fatalf("unexpected command: %v", diff)
+	}
+	if len(diff.Substitutions) != 3 {
+		t.Fatalf("unexpected substitutions: %v", diff.Substitutions)
+	}
+
+	sort, wc, echo := diff.Substitutions[0], diff.Substitutions[1], diff.Substitutions[2]
+	if sort.Op != command_meta.SubstituteInput || sort.Text != "<(sort 'a b')" ||
+		len(sort.Commands) != 1 || sort.Commands[0].Args[0] != "a b" {
+		t.Fatalf("unexpected substitution: %v", sort)
+	}
+	if wc.Op != command_meta.SubstituteOutput || len(wc.Commands) != 2 || wc.Commands[1].Name != "cat" {
+		t.Fatalf("unexpected substitution: %v", wc)
+	}
+	if echo.Text != "<(echo ')')" || echo.Commands[0].Args[0] != ")" {
+		t.Fatalf("unexpected substitution: %v", echo)
+	}
+	if sort.Word != 0 || sort.InRedirect || sort.Offset != 0 ||
+		wc.Word != 1 || wc.InRedirect || wc.Offset != 1 ||
+		echo.Word != 0 || !echo.InRedirect || echo.Offset != 0 {
+		t.Fatalf("unexpected substitution positions: %+v %+v %+v", sort, wc, echo)
+	}
+}
+
+func TestProcessSubstitutionPosition(t *testing.T) {
+	vars := envsholder.Env{}
+	vars.Init()
+	vars.Set("x", "a b")
+	tokenizer := NewTokenizer(strings.NewReader("echo '<(ls)' <(ls) $x\"é\"<(ls)<(pwd)\n<(ls) arg\n"), &vars)
+	commands, err := NewParser(tokenizer).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Текст подстановки в кавычках остается словом, подстановка указывает на свой аргумент,
+	// а после разбиения по IFS - на поле со смещением внутри него
+	echo := commands[0]
+	if strings.Join(echo.Args, " ") != "<(ls) <(ls) a bé<(ls)<(pwd)" {
+		t.Fatalf("unexpected command: %v", echo)
+	}
+	want := [][2]int{{1, 0}, {3, 3}, {3, 8}}
+	for i, substitution := range echo.Substitutions {
+		if substitution.InRedirect || substitution.Word != want[i][0] || substitution.Offset != want[i][1] {
+			t.Fatalf("unexpected substitution position: %+v", substitution)
+		}
+	}
+
+	// Подстановка в имени команды не заменяется
+	commands, err = NewParser(tokenizer).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands[0].Substitutions) != 1 || commands[0].Substitutions[0].Word != -1 {
+		t.Fatalf("unexpected substitutions: %+v", commands[0].Substitutions)
+	}
+}
+
+func TestProcessSubstitutionErrors(t *testing.T) {
+	var syntaxErr *SyntaxError
+	if _, err := parseLine(t, "cat <(echo | | wc)\n", testOptions{}); !errors.As(err, &syntaxErr) || syntaxErr.Column != 5 {
+		t.Fatalf("expected syntax error at the substitution, got %v", err)
+	}
+	if _, err := parseLine(t, "cat <(echo\n", testOptions{}); !errors.As(err, &syntaxErr) || !syntaxErr.Incomplete {
+		t.Fatalf("expected incomplete input error, got %v", err)
+	}
+}
+
+func TestFieldSplitting(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		line     string
+		vars     map[string]string
+		params   []string
+		expected []string
+	}{
+		{line: "echo $x \"$x\"\n", vars: map[string]string{"x": "  a  b "}, expected: []string{"a", "b", "  a  b "}},
+		{line: "echo a$x\n", vars: map[string]string{"x": ""}, expected: []string{"a"}},
+		{line: "echo $x \"\" ''\n", vars: map[string]string{"x": ""}, expected: []string{"", ""}},
+		{line: "echo $x\n", vars: map[string]string{"x": "a:b::c:", "IFS": ":"}, expected: []string{"a", "b", "", "c"}},
+		{line: "echo $x\n", vars: map[string]string{"x": " a : b ", "IFS": " :"}, expected: []string{"a", "b"}},
+		{line: "echo $x\n", vars: map[string]string{"x": "a b", "IFS": ""}, expected: []string{"a b"}},
+		{line: "echo $x\n", vars: map[string]string{"x": "*.txt c"}, expected: []string{"a.txt", "b.txt", "c"}},
+		{line: "echo \"$x\"\n", vars: map[string]string{"x": "*.txt"}, expected: []string{"*.txt"}},
+		{line: "echo \"$@\" x\"$@\"y\n", params: []string{"a b", ""}, expected: []string{"a b", "", "xa b", "y"}},
+		{line: "echo \"$@\" $# \"$*\" $1\n", params: []string{"a b", "c"}, expected: []string{"a b", "c", "2", "a b c", "a", "b"}},
+		{line: "echo \"$@\" \"$1\"\n", expected: []string{""}},
+		{line: "echo $'a\\tb\\'c' \"$'x'\"\n", expected: []string{"a\tb'c", "$'x'"}},
+		{line: "echo $'\\x41\\101\\u00e9\\cA\\q'\n", expected: []string{"AAé\x01\\q"}},
+	}
+	for _, tc := range cases {
+		vars := envsholder.Env{Vars: tc.vars, Params: tc.params}
+		vars.Init()
+		tokenizer := NewTokenizer(strings.NewReader(tc.line), &vars)
+		tokenizer.SetOptions(testOptions{dir: dir})
+		commands, err := NewParser(tokenizer).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(commands[0].Args, tc.expected) {
+			t.Fatalf("%q: unexpected args %q", tc.line, commands[0].Args)
+		}
+	}
+}
+
+func TestNoSplitInAssignmentsAndRedirects(t *testing.T) {
+	vars := envsholder.Env{Vars: map[string]string{"x": "a b"}}
+	tokenizer := NewTokenizer(strings.NewReader("y=$x cat >$x\n"), &vars)
+	commands, err := NewParser(tokenizer).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commands[0].Envs.Vars["y"] != "a b" || commands[0].Redirects[0].Target != "a b" || len(commands[0].Args) != 0 {
+		t.Fatalf("unexpected command: %v", commands[0])
+	}
+}
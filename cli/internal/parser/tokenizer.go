@@ -2,9 +2,15 @@ package parser
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"shell/internal/command_meta"
 	envsholder "shell/internal/envs_holder"
+	"shell/internal/session"
+	"shell/pkg/vfs"
+	"strconv"
+	"strings"
 )
 
 type TokenType int
@@ -16,6 +22,29 @@ type lexerState int
 type Token struct {
 	TokenType TokenType
 	Value     string
+	// Шаблон имен файлов, если слово содержит неэкранированные символы * ? [.
+	// Символы шаблона, бывшие в кавычках, в нем экранированы обратной косой чертой.
+	Pattern string
+	// Строка и столбец первого символа токена, начиная с единицы
+	Line   int
+	Column int
+	// Подстановки процессов, исходный текст которых входит в слово
+	Substitutions []command_meta.ProcessSubstitution
+	// Слово содержит подстановки вне кавычек или "$@" и раскрывается в поля Fields,
+	// которых может быть и ноль. Value при этом хранит слово без разбиения,
+	// как оно используется в присваиваниях и перенаправлениях.
+	Split  bool
+	Fields []Field
+}
+
+// Поле, полученное разбиением слова по IFS
+type Field struct {
+	Value string
+	// Шаблон имен файлов поля, как Token.Pattern
+	Pattern string
+	// Подстановки процессов, текст которых попал в поле,
+	// со смещением внутри поля
+	Substitutions []command_meta.ProcessSubstitution
 }
 
 func (a *Token) Equal(b *Token) bool {
@@ -37,6 +66,9 @@ const (
 	endLineRunes          = "\n"
 	pipeRunes             = "|"
 	envVarRunes           = "$"
+	redirectRunes         = "<>"
+	globRunes             = "*?["
+	specialParamRunes     = "@*#0123456789"
 )
 
 const (
@@ -50,6 +82,7 @@ const (
 	pipeRuneClass
 	eofRuneClass
 	envVarClass
+	redirectRuneClass
 )
 
 const (
@@ -58,19 +91,40 @@ const (
 	CommentToken
 	EndLineToken
 	PipeToken
+	RedirectToken
 )
 
+func (t TokenType) String() string {
+	switch t {
+	case WordToken:
+		return "word"
+	case CommentToken:
+		return "comment"
+	case EndLineToken:
+		return "newline"
+	case PipeToken:
+		return "pipe"
+	case RedirectToken:
+		return "redirect"
+	}
+	return "unknown"
+}
+
 const (
-	startState              lexerState = iota // еще не было символов
-	inWordState                               // в процессе определения слова
-	escapingState                             // экранирование, следующий символ должен быть литеральным
-	escapingQuotedState                       // экранирование в заключенной в кавычки строке
-	quotingEscapingState                      // внутри заключенной в кавычки строки, которая поддерживает экранирование
-	quotingState                              // внутри строки, которая не поддерживает экранирование
-	commentState                              // в пределах комментария
-	pipeSymbolState                           // прошлый символ был pipe
-	endLineState                              // прошлый символ был \n
-	enviromentVariableState                   // внутри имени переменной окружения
+	startState               lexerState = iota // еще не было символов
+	inWordState                                // в процессе определения слова
+	escapingState                              // экранирование, следующий символ должен быть литеральным
+	escapingQuotedState                        // экранирование в заключенной в кавычки строке
+	quotingEscapingState                       // внутри заключенной в кавычки строки, которая поддерживает экранирование
+	quotingState                               // внутри строки, которая не поддерживает экранирование
+	commentState                               // в пределах комментария
+	pipeSymbolState                            // прошлый символ был pipe
+	endLineState                               // прошлый символ был \n
+	enviromentVariableState                    // внутри имени переменной окружения
+	arithmeticState                            // внутри арифметического выражения $(( ))
+	redirectSymbolState                        // прошлый символ завершил оператор перенаправления
+	processSubstitutionState                   // внутри подстановки процесса <( ) или >( )
+	ansiQuotingState                           // внутри строки $'...' с escape-последовательностями
 )
 
 type tokenClassifier map[rune]runeTokenClass
@@ -91,6 +145,7 @@ func newDefaultClassifier() tokenClassifier {
 	t.addRuneClass(endLineRunes, endLineRuneClass)
 	t.addRuneClass(pipeRunes, pipeRuneClass)
 	t.addRuneClass(envVarRunes, envVarClass)
+	t.addRuneClass(redirectRunes, redirectRuneClass)
 	return t
 }
 
@@ -99,21 +154,101 @@ func (t tokenClassifier) ClassifyRune(runeVal rune) runeTokenClass {
 }
 
 type Tokenizer struct {
-	input             bufio.Reader
-	classifier        tokenClassifier
-	statesStack       lexerStateStack
-	envsHolder        *envsholder.Env
+	input       bufio.Reader
+	classifier  tokenClassifier
+	statesStack lexerStateStack
+	envsHolder  *envsholder.Env
+	options     ExpansionOptions
+	// Не выполнять подстановки (режим проверки синтаксиса)
+	noExpand          bool
 	isEnded           bool
 	currentTokenState *getTokenState
+	// Прочитанный, но еще не отданный оператор перенаправления
+	pendingRedirect string
+
+	// Позиция последнего прочитанного символа
+	pos position
+	// Последний прочитанный символ - перевод строки (или ничего еще не прочитано)
+	atLineStart bool
+	// Позиция символа, завершившего прошлый токен: |, перевод строки или оператор перенаправления
+	symbolPos position
+	// Прочитанные строки текущей команды для сообщений об ошибках: завершенные строки,
+	// номер первой из них и текущая строка
+	source      []string
+	sourceStart int
+	line        []rune
+	// Вызывается, когда команда продолжается на следующей строке
+	continuation func()
+	// Записывает присваивания в $(( )); nil - запись в envsHolder
+	arithmeticSetter ArithmeticSetter
+	// Вызывается перед обработкой каждого символа с его позицией и состоянием автомата,
+	// в котором он обрабатывается; используется подсветкой синтаксиса
+	trace func(pos position, state lexerState, class runeTokenClass, r rune)
+}
+
+// Позиция символа во входном потоке
+type position struct {
+	line   int
+	column int
+}
+
+// Настройки сессии, от которых зависит раскрытие слов
+type ExpansionOptions interface {
+	// Option сообщает, включена ли опция оболочки
+	Option(name string) bool
+	// Dir возвращает директорию, относительно которой раскрываются шаблоны имен файлов
+	Dir() string
+	// FS возвращает файловую систему, в которой раскрываются шаблоны имен файлов
+	FS() vfs.FS
+}
+
+// Ошибка подстановки незаданной переменной при включенной опции nounset
+type UnboundVariableError struct {
+	Name string
+}
+
+func (e *UnboundVariableError) Error() string {
+	return fmt.Sprintf("%s: unbound variable", e.Name)
 }
 
 type getTokenState struct {
-	tokenType    TokenType
-	nextRuneType runeTokenClass
-	nextRune     rune
-	value        []rune
-	envVarBuffer []rune
-	err          error
+	tokenType        TokenType
+	nextRuneType     runeTokenClass
+	nextRune         rune
+	value            []rune
+	envVarBuffer     []rune
+	arithmeticBuffer []rune
+	arithmeticDepth  int
+	// Позиции неэкранированных символов шаблона в value
+	globPositions []int
+	// Позиции символов value, полученных подстановкой вне кавычек:
+	// по ним слово разбивается на поля
+	splitPositions []int
+	// Позиции value, с которых "$@" начинает новое поле
+	fieldBreaks []int
+	// Слово содержит кавычки, поэтому сохраняется, даже если пусто
+	quoted bool
+	// "$@" раскрылся без позиционных параметров
+	emptyParams bool
+	// Текст строки $'...' и признак экранирования следующего символа в ней
+	ansiBuffer []rune
+	ansiEscape bool
+	// Текст подстановки процесса, ее направление, позиция оператора,
+	// глубина вложенных скобок и состояние кавычек внутри нее
+	substitution       []rune
+	substitutionOp     string
+	substitutionPos    position
+	substitutionDepth  int
+	substitutionQuote  rune
+	substitutionEscape bool
+	// Разобранные подстановки процессов текущего слова
+	// и номера символов слова, с которых начинается их текст
+	substitutions      []command_meta.ProcessSubstitution
+	substitutionStarts []int
+	// Позиция первого символа токена и открывающей кавычки
+	start    position
+	quotePos position
+	err      error
 }
 
 func NewTokenizer(r io.Reader, vars *envsholder.Env) *Tokenizer {
@@ -125,12 +260,259 @@ func NewTokenizer(r io.Reader, vars *envsholder.Env) *Tokenizer {
 		statesStack: *NewEmptyStack(),
 		envsHolder:  vars,
 		isEnded:     false,
+		atLineStart: true,
+		sourceStart: 1,
+	}
+}
+
+// SetContinuation задает функцию, которая вызывается, когда команда не закончилась
+// в конце строки (открытая кавычка, \ или | в конце строки) и нужна следующая строка.
+// Интерактивная оболочка выводит в ней приглашение PS2.
+func (t *Tokenizer) SetContinuation(fn func()) {
+	t.continuation = fn
+}
+
+// SetArithmeticSetter задает функцию, которой записываются присваивания
+// в арифметических подстановках $(( )), например с проверкой ограниченного режима
+func (t *Tokenizer) SetArithmeticSetter(set ArithmeticSetter) {
+	t.arithmeticSetter = set
+}
+
+func (t *Tokenizer) continueLine() {
+	if t.continuation != nil {
+		t.continuation()
+	}
+}
+
+// readRune читает следующий символ, отслеживая его позицию и текст строки
+func (t *Tokenizer) readRune() (rune, error) {
+	r, _, err := t.input.ReadRune()
+	if err != nil {
+		return r, err
+	}
+
+	if t.atLineStart {
+		t.pos.line++
+		t.pos.column = 0
+	}
+	t.pos.column++
+	t.atLineStart = r == '\n'
+	if r == '\n' {
+		t.source = append(t.source, string(t.line))
+		t.line = t.line[:0]
+	} else {
+		t.line = append(t.line, r)
+	}
+	return r, nil
+}
+
+// endPosition возвращает позицию сразу после последнего прочитанного символа
+func (t *Tokenizer) endPosition() position {
+	if t.atLineStart {
+		return position{line: t.pos.line + 1, column: 1}
+	}
+	return position{line: t.pos.line, column: t.pos.column + 1}
+}
+
+// resetSource забывает строки предыдущих команд.
+// Вызывается парсером перед разбором очередной команды.
+func (t *Tokenizer) resetSource() {
+	t.source = nil
+	t.sourceStart = t.pos.line
+	if t.atLineStart {
+		t.sourceStart++
+	}
+}
+
+// sourceLine возвращает текст строки с номером n, если она относится к текущей команде
+func (t *Tokenizer) sourceLine(n int) string {
+	if i := n - t.sourceStart; i >= 0 && i < len(t.source) {
+		return t.source[i]
+	}
+	if n == t.pos.line && !t.atLineStart || n == t.pos.line+1 && t.atLineStart {
+		return string(t.line)
+	}
+	return ""
+}
+
+// syntaxError создает ошибку разбора в позиции pos.
+// Если ввод еще не закончился, остаток строки пропускается, чтобы
+// сообщение содержало строку целиком, а разбор продолжился со следующей строки.
+func (t *Tokenizer) syntaxError(pos position, incomplete bool, format string, args ...any) *SyntaxError {
+	if !incomplete {
+		t.discardLine(nil)
+	}
+	return &SyntaxError{
+		Line:       pos.line,
+		Column:     pos.column,
+		Message:    fmt.Sprintf(format, args...),
+		Source:     t.sourceLine(pos.line),
+		Incomplete: incomplete,
+	}
+}
+
+// SetOptions задает настройки раскрытия слов.
+// Без настроек опции считаются выключенными, а шаблоны раскрываются
+// относительно текущей директории процесса.
+func (t *Tokenizer) SetOptions(options ExpansionOptions) {
+	t.options = options
+}
+
+// SetNoExpand отключает подстановки: переменные и арифметические выражения
+// остаются в словах как есть (арифметика только проверяется на синтаксис),
+// шаблоны имен файлов не раскрываются. Используется для проверки синтаксиса
+// без исполнения, когда значения переменных неизвестны.
+func (t *Tokenizer) SetNoExpand(noExpand bool) {
+	t.noExpand = noExpand
+}
+
+// option сообщает, включена ли опция оболочки
+func (t *Tokenizer) option(name string) bool {
+	return t.options != nil && t.options.Option(name)
+}
+
+// appendUnquoted добавляет в слово символ вне кавычек,
+// запоминая позиции символов шаблона имен файлов
+func (t *Tokenizer) appendUnquoted(r rune) {
+	state := t.currentTokenState
+	if strings.ContainsRune(globRunes, r) {
+		state.globPositions = append(state.globPositions, len(state.value))
+	}
+	state.value = append(state.value, r)
+}
+
+// readRedirect дочитывает оператор перенаправления, начинающийся с first
+func (t *Tokenizer) readRedirect(first rune) {
+	t.pendingRedirect = string(first)
+	if first != '>' {
+		return
+	}
+	if next, err := t.input.Peek(1); err == nil && (next[0] == '>' || next[0] == '|') {
+		t.readRune()
+		t.pendingRedirect += string(next[0])
+	}
+}
+
+// partialToken создает токен из слова, разбор которого прерван концом ввода
+func (t *Tokenizer) partialToken() *Token {
+	state := t.currentTokenState
+	return &Token{TokenType: state.tokenType, Value: string(state.value), Line: state.start.line, Column: state.start.column}
+}
+
+// wordToken создает токен слова, вычисляя шаблон имен файлов
+// и поля, на которые слово разбивается
+func (t *Tokenizer) wordToken() *Token {
+	state := t.currentTokenState
+	token := &Token{TokenType: state.tokenType, Value: string(state.value), Line: state.start.line, Column: state.start.column}
+	token.Substitutions = state.substitutions
+	token.Pattern = wordPattern(state.value, state.globPositions)
+	if len(state.splitPositions) != 0 || len(state.fieldBreaks) != 0 || state.emptyParams {
+		token.Split = true
+		token.Fields = t.splitFields()
 	}
+	return token
+}
+
+// wordPattern возвращает шаблон имен файлов слова value с неэкранированными символами
+// шаблона в позициях glob или пустую строку, если таких символов нет
+func wordPattern(value []rune, glob []int) string {
+	if len(glob) == 0 {
+		return ""
+	}
+
+	var pattern strings.Builder
+	for i, r := range value {
+		if len(glob) > 0 && glob[0] == i {
+			glob = glob[1:]
+		} else if strings.ContainsRune(globRunes+escapeRunes, r) {
+			pattern.WriteRune('\\')
+		}
+		pattern.WriteRune(r)
+	}
+	return pattern.String()
+}
+
+// splitFields разбивает слово на поля по правилам POSIX: разделителями служат
+// символы IFS, полученные подстановкой вне кавычек. Идущие подряд пробельные
+// разделители образуют один, а в начале и конце слова отбрасываются;
+// каждый непробельный разделитель завершает поле, возможно пустое.
+func (t *Tokenizer) splitFields() []Field {
+	state := t.currentTokenState
+	ifs := t.ifs()
+
+	var fields []Field
+	var value []rune
+	var glob []int
+	var substitutions []command_meta.ProcessSubstitution
+	emit := func() {
+		fields = append(fields, Field{Value: string(value), Pattern: wordPattern(value, glob), Substitutions: substitutions})
+		value, glob, substitutions = nil, nil, nil
+	}
+
+	// started - поле начато, afterSpace - прошлое поле завершил пробельный разделитель
+	started, afterSpace := false, false
+	split, breaks, globs := state.splitPositions, state.fieldBreaks, state.globPositions
+	starts := state.substitutionStarts
+	for i, r := range state.value {
+		if len(breaks) > 0 && breaks[0] == i {
+			breaks = breaks[1:]
+			emit()
+			started, afterSpace = true, false
+		}
+		isGlob := len(globs) > 0 && globs[0] == i
+		if isGlob {
+			globs = globs[1:]
+		}
+		isSplit := len(split) > 0 && split[0] == i
+		if isSplit {
+			split = split[1:]
+		}
+
+		if isSplit && strings.ContainsRune(ifs, r) {
+			if strings.ContainsRune(envsholder.DefaultIFS, r) {
+				if started {
+					emit()
+					started, afterSpace = false, true
+				}
+			} else {
+				if started || !afterSpace {
+					emit()
+				}
+				started, afterSpace = false, false
+			}
+			continue
+		}
+
+		if isGlob {
+			glob = append(glob, len(value))
+		}
+		// Текст подстановки процесса не разбивается, поэтому попадает в одно поле
+		if len(starts) > 0 && starts[0] == i {
+			substitution := state.substitutions[len(state.substitutions)-len(starts)]
+			substitution.Offset = len(string(value))
+			substitutions = append(substitutions, substitution)
+			starts = starts[1:]
+		}
+		value = append(value, r)
+		started, afterSpace = true, false
+	}
+	// Поля "$@" для пустых параметров в конце слова
+	for range breaks {
+		emit()
+		started = true
+	}
+
+	if started || len(fields) == 0 && state.quoted && !state.emptyParams {
+		emit()
+	}
+	if fields == nil {
+		fields = []Field{}
+	}
+	return fields
 }
 
 func (t *Tokenizer) handleInWordState() bool {
 	nextRuneType := t.currentTokenState.nextRuneType
-	value := &t.currentTokenState.value
 	nextRune := t.currentTokenState.nextRune
 
 	switch nextRuneType {
@@ -146,10 +528,14 @@ func (t *Tokenizer) handleInWordState() bool {
 		}
 	case escapingQuoteRuneClass:
 		{
+			t.currentTokenState.quotePos = t.pos
+			t.currentTokenState.quoted = true
 			t.statesStack.Push(quotingEscapingState)
 		}
 	case nonEscapingQuoteRuneClass:
 		{
+			t.currentTokenState.quotePos = t.pos
+			t.currentTokenState.quoted = true
 			t.statesStack.Push(quotingState)
 		}
 	case escapeRuneClass:
@@ -172,14 +558,26 @@ func (t *Tokenizer) handleInWordState() bool {
 			t.statesStack.Push(pipeSymbolState)
 			return true
 		}
+	case redirectRuneClass:
+		{
+			if t.startProcessSubstitution(nextRune) {
+				return false
+			}
+			t.statesStack.Pop()
+			t.readRedirect(nextRune)
+			t.statesStack.Push(redirectSymbolState)
+			return true
+		}
 	default:
 		{
-			*value = append(*value, nextRune)
+			t.appendUnquoted(nextRune)
 		}
 	}
 	return false
 }
 
+// Экранированный перевод строки продолжает команду на следующей строке
+// и в слово не попадает
 func (t *Tokenizer) handleEscapingState() bool {
 	nextRuneType := t.currentTokenState.nextRuneType
 	value := &t.currentTokenState.value
@@ -191,6 +589,11 @@ func (t *Tokenizer) handleEscapingState() bool {
 			t.isEnded = true
 			return true
 		}
+	case endLineRuneClass:
+		{
+			t.statesStack.Pop()
+			t.continueLine()
+		}
 	default:
 		{
 			t.statesStack.Pop()
@@ -211,6 +614,11 @@ func (t *Tokenizer) handleEscapingQuotedState() bool {
 			t.isEnded = true
 			return true
 		}
+	case endLineRuneClass:
+		{
+			t.statesStack.Pop()
+			t.continueLine()
+		}
 	default:
 		{
 			t.statesStack.Pop()
@@ -243,6 +651,11 @@ func (t *Tokenizer) handleQuotingEscapingState() bool {
 		{
 			t.statesStack.Push(enviromentVariableState)
 		}
+	case endLineRuneClass:
+		{
+			*value = append(*value, nextRune)
+			t.continueLine()
+		}
 	default:
 		{
 			*value = append(*value, nextRune)
@@ -266,6 +679,11 @@ func (t *Tokenizer) handleQuotingState() bool {
 		{
 			t.statesStack.Pop()
 		}
+	case endLineRuneClass:
+		{
+			*value = append(*value, nextRune)
+			t.continueLine()
+		}
 	default:
 		{
 			*value = append(*value, nextRune)
@@ -305,7 +723,6 @@ func (t *Tokenizer) handleCommentState() bool {
 func (t *Tokenizer) handleStartState() {
 	tokenType := &t.currentTokenState.tokenType
 	nextRuneType := t.currentTokenState.nextRuneType
-	value := &t.currentTokenState.value
 	nextRune := t.currentTokenState.nextRune
 
 	switch nextRuneType {
@@ -319,12 +736,16 @@ func (t *Tokenizer) handleStartState() {
 	case escapingQuoteRuneClass:
 		{
 			*tokenType = WordToken
+			t.currentTokenState.quotePos = t.pos
+			t.currentTokenState.quoted = true
 			t.statesStack.Push(inWordState)
 			t.statesStack.Push(quotingEscapingState)
 		}
 	case nonEscapingQuoteRuneClass:
 		{
 			*tokenType = WordToken
+			t.currentTokenState.quotePos = t.pos
+			t.currentTokenState.quoted = true
 			t.statesStack.Push(inWordState)
 			t.statesStack.Push(quotingState)
 		}
@@ -353,11 +774,22 @@ func (t *Tokenizer) handleStartState() {
 		{
 			t.statesStack.Push(pipeSymbolState)
 		}
+	case redirectRuneClass:
+		{
+			if t.startProcessSubstitution(nextRune) {
+				*tokenType = WordToken
+				t.statesStack.Push(inWordState)
+				t.statesStack.Push(processSubstitutionState)
+				return
+			}
+			t.readRedirect(nextRune)
+			t.statesStack.Push(redirectSymbolState)
+		}
 	default:
 		{
 			*tokenType = WordToken
 			t.statesStack.Push(inWordState)
-			*value = append(*value, nextRune)
+			t.appendUnquoted(nextRune)
 		}
 	}
 }
@@ -368,15 +800,46 @@ func (t *Tokenizer) handleEnviromentVariableState() (*Token, error) {
 	envVarBuffer := &t.currentTokenState.envVarBuffer
 	nextRune := t.currentTokenState.nextRune
 
+	// $(( - начало арифметического выражения
+	if nextRune == '(' && len(*envVarBuffer) == 0 {
+		if next, err := t.input.Peek(1); err == nil && next[0] == '(' {
+			t.readRune()
+			t.statesStack.Pop()
+			t.statesStack.Push(arithmeticState)
+			return nil, nil
+		}
+	}
+
+	if len(*envVarBuffer) == 0 {
+		// $'...' - строка с escape-последовательностями, внутри "..." не распознается
+		if nextRune == '\'' {
+			t.statesStack.Pop()
+			if t.statesStack.CurrentState() != quotingEscapingState {
+				t.currentTokenState.quotePos = t.pos
+				t.currentTokenState.quoted = true
+				t.statesStack.Push(ansiQuotingState)
+				return nil, nil
+			}
+			*value = append(*value, '$')
+			return t.handleRune()
+		}
+		// Специальные параметры $@ $* $# и $0-$9 состоят из одного символа
+		if strings.ContainsRune(specialParamRunes, nextRune) {
+			t.statesStack.Pop()
+			quoted := t.statesStack.CurrentState() == quotingEscapingState
+			return nil, t.expandParam(string(nextRune), quoted)
+		}
+	}
+
 	if nextRuneType != unknownRuneClass {
 		name := string(*envVarBuffer)
+		*envVarBuffer = []rune{}
+		t.statesStack.Pop()
 		if name == "" {
 			*value = append(*value, '$')
-		} else if env, ok := t.envsHolder.Vars[name]; ok {
-			*value = append(*value, []rune(env)...)
+		} else if err := t.expandParam(name, t.statesStack.CurrentState() == quotingEscapingState); err != nil {
+			return nil, err
 		}
-		*envVarBuffer = []rune{}
-		t.statesStack.Pop()
 		return t.handleRune()
 	} else {
 		*envVarBuffer = append(*envVarBuffer, nextRune)
@@ -384,9 +847,290 @@ func (t *Tokenizer) handleEnviromentVariableState() (*Token, error) {
 	return nil, nil
 }
 
+// expandParam подставляет в слово значение переменной или специального параметра.
+// Значение, подставленное вне кавычек, затем разбивается на поля по IFS
+// и может содержать шаблоны имен файлов.
+func (t *Tokenizer) expandParam(name string, quoted bool) error {
+	state := t.currentTokenState
+	if t.noExpand {
+		state.value = append(state.value, []rune("$"+name)...)
+		return nil
+	}
+
+	params := t.envsHolder.Params
+	switch name {
+	case "#":
+		t.appendExpansion(strconv.Itoa(len(params)), quoted)
+	case "@":
+		if !quoted {
+			t.appendExpansion(strings.Join(params, " "), false)
+			break
+		}
+		// "$@" - каждый параметр становится отдельным полем
+		if len(params) == 0 {
+			state.emptyParams = true
+		}
+		for i, param := range params {
+			if i > 0 {
+				state.fieldBreaks = append(state.fieldBreaks, len(state.value))
+			}
+			state.value = append(state.value, []rune(param)...)
+		}
+	case "*":
+		// "$*" объединяет параметры первым символом IFS
+		separator := " "
+		if ifs, ok := t.envsHolder.Vars[envsholder.IFSKey]; ok && quoted {
+			separator = ""
+			if ifs != "" {
+				separator = string([]rune(ifs)[0])
+			}
+		}
+		t.appendExpansion(strings.Join(params, separator), quoted)
+	default:
+		if n, err := strconv.Atoi(name); err == nil && n > 0 {
+			if n <= len(params) {
+				t.appendExpansion(params[n-1], quoted)
+				return nil
+			}
+		} else if env, ok := t.envsHolder.Vars[name]; ok {
+			t.appendExpansion(env, quoted)
+			return nil
+		}
+		if t.option(session.OptionNounset) {
+			return t.discardLine(&UnboundVariableError{Name: name})
+		}
+	}
+	return nil
+}
+
+// appendExpansion добавляет в слово результат подстановки
+func (t *Tokenizer) appendExpansion(text string, quoted bool) {
+	state := t.currentTokenState
+	for _, r := range text {
+		if !quoted {
+			state.splitPositions = append(state.splitPositions, len(state.value))
+			if strings.ContainsRune(globRunes, r) {
+				state.globPositions = append(state.globPositions, len(state.value))
+			}
+		}
+		state.value = append(state.value, r)
+	}
+}
+
+// ifs возвращает разделители полей
+func (t *Tokenizer) ifs() string {
+	if ifs, ok := t.envsHolder.Vars[envsholder.IFSKey]; ok {
+		return ifs
+	}
+	return envsholder.DefaultIFS
+}
+
+// handleAnsiQuotingState накапливает текст строки $'...' до закрывающей кавычки
+// и подставляет его в слово, раскрыв escape-последовательности
+func (t *Tokenizer) handleAnsiQuotingState() (*Token, error) {
+	state := t.currentTokenState
+	r := state.nextRune
+
+	switch {
+	case state.nextRuneType == eofRuneClass:
+		t.isEnded = true
+		return t.partialToken(), t.syntaxError(state.quotePos, true, "unexpected EOF while looking for matching `''")
+	case state.ansiEscape:
+		state.ansiEscape = false
+	case r == '\\':
+		state.ansiEscape = true
+	case r == '\'':
+		state.value = append(state.value, []rune(decodeAnsiC(string(state.ansiBuffer)))...)
+		state.ansiBuffer = nil
+		t.statesStack.Pop()
+		return nil, nil
+	}
+
+	if r == '\n' {
+		t.continueLine()
+	}
+	state.ansiBuffer = append(state.ansiBuffer, r)
+	return nil, nil
+}
+
+// handleArithmeticState накапливает текст выражения до закрывающих )),
+// учитывая вложенные скобки, после чего подставляет в слово результат вычисления
+func (t *Tokenizer) handleArithmeticState() (*Token, error) {
+	state := t.currentTokenState
+
+	switch {
+	case state.nextRuneType == eofRuneClass:
+		t.isEnded = true
+		return nil, t.syntaxError(t.endPosition(), true, "unexpected EOF while looking for matching `))'")
+	case state.nextRune == '(':
+		state.arithmeticDepth++
+	case state.nextRune == ')' && state.arithmeticDepth > 0:
+		state.arithmeticDepth--
+	case state.nextRune == ')':
+		if next, err := t.input.Peek(1); err != nil || next[0] != ')' {
+			return nil, t.syntaxError(t.pos, false, "missing `))' in arithmetic expression")
+		}
+		t.readRune()
+
+		if t.noExpand {
+			if err := CheckArithmetic(string(state.arithmeticBuffer)); err != nil {
+				return nil, t.syntaxError(t.pos, false, "%s", err)
+			}
+			state.value = append(state.value, []rune("$(("+string(state.arithmeticBuffer)+"))")...)
+			state.arithmeticBuffer = nil
+			t.statesStack.Pop()
+			return nil, nil
+		}
+
+		result, err := EvalArithmetic(string(state.arithmeticBuffer), t.envsHolder, t.arithmeticSetter)
+		if err != nil {
+			return nil, t.discardLine(err)
+		}
+		state.value = append(state.value, []rune(strconv.FormatInt(result, 10))...)
+		state.arithmeticBuffer = nil
+		t.statesStack.Pop()
+		return nil, nil
+	}
+
+	state.arithmeticBuffer = append(state.arithmeticBuffer, state.nextRune)
+	return nil, nil
+}
+
+// startProcessSubstitution начинает подстановку процесса, если за символом < или >
+// следует открывающая скобка
+func (t *Tokenizer) startProcessSubstitution(op rune) bool {
+	if next, err := t.input.Peek(1); err != nil || next[0] != '(' {
+		return false
+	}
+	state := t.currentTokenState
+	state.substitutionOp = string(op)
+	state.substitutionPos = t.pos
+	state.substitution = nil
+	t.readRune()
+	if t.statesStack.CurrentState() == inWordState {
+		t.statesStack.Push(processSubstitutionState)
+	}
+	return true
+}
+
+// handleProcessSubstitutionState накапливает текст подстановки процесса до парной
+// закрывающей скобки, учитывая кавычки и вложенные скобки
+func (t *Tokenizer) handleProcessSubstitutionState() (*Token, error) {
+	state := t.currentTokenState
+	r := state.nextRune
+
+	switch {
+	case state.nextRuneType == eofRuneClass:
+		t.isEnded = true
+		return t.partialToken(), t.syntaxError(state.substitutionPos, true, "unexpected EOF while looking for matching `)'")
+	case state.substitutionEscape:
+		state.substitutionEscape = false
+	case state.substitutionQuote != 0:
+		if r == state.substitutionQuote {
+			state.substitutionQuote = 0
+		} else if r == '\\' && state.substitutionQuote == '"' {
+			state.substitutionEscape = true
+		}
+	case r == '\\':
+		state.substitutionEscape = true
+	case r == '\'' || r == '"':
+		state.substitutionQuote = r
+	case r == '(':
+		state.substitutionDepth++
+	case r == ')' && state.substitutionDepth > 0:
+		state.substitutionDepth--
+	case r == ')':
+		t.statesStack.Pop()
+		return nil, t.finishProcessSubstitution()
+	}
+
+	if r == '\n' {
+		t.continueLine()
+	}
+	state.substitution = append(state.substitution, r)
+	return nil, nil
+}
+
+// finishProcessSubstitution разбирает текст подстановки процесса в пайплайн
+// и оставляет в слове исходный текст подстановки
+func (t *Tokenizer) finishProcessSubstitution() error {
+	state := t.currentTokenState
+	script := string(state.substitution)
+	substitution := command_meta.ProcessSubstitution{
+		Op:   state.substitutionOp,
+		Text: state.substitutionOp + "(" + script + ")",
+	}
+
+	commands, err := t.parseSubstitution(script)
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return t.syntaxError(state.substitutionPos, false, "%s in process substitution", syntaxErr.Message)
+	} else if err != nil {
+		return t.discardLine(err)
+	}
+
+	substitution.Commands = commands
+	substitution.Offset = len(string(state.value))
+	state.substitutions = append(state.substitutions, substitution)
+	state.substitutionStarts = append(state.substitutionStarts, len(state.value))
+	state.value = append(state.value, []rune(substitution.Text)...)
+	return nil
+}
+
+// parseSubstitution разбирает текст подстановки процесса с теми же переменными и опциями.
+// Подстановка может содержать только один пайплайн, возможно пустой.
+func (t *Tokenizer) parseSubstitution(script string) ([]command_meta.CommandMeta, error) {
+	tokenizer := NewTokenizer(strings.NewReader(script+"\n"), t.envsHolder)
+	tokenizer.options = t.options
+	tokenizer.arithmeticSetter = t.arithmeticSetter
+	tokenizer.noExpand = t.noExpand
+	parser := NewParser(tokenizer)
+
+	var result []command_meta.CommandMeta
+	for {
+		commands, err := parser.Parse()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(commands) != 0 {
+			if result != nil {
+				return nil, &SyntaxError{Message: "syntax error: more than one pipeline"}
+			}
+			result = commands
+		}
+		if err == io.EOF {
+			return result, nil
+		}
+	}
+}
+
+// discardLine пропускает остаток строки, в которой произошла ошибка,
+// и сбрасывает состояние автомата, чтобы следующая строка разбиралась с начала
+func (t *Tokenizer) discardLine(err error) error {
+	t.statesStack = *NewEmptyStack()
+	for !t.atLineStart {
+		if _, readErr := t.readRune(); readErr != nil {
+			t.isEnded = true
+			break
+		}
+	}
+	return err
+}
+
+// reset забывает незаконченную команду, чтобы следующая строка разбиралась с начала
+func (t *Tokenizer) reset() {
+	t.statesStack = *NewEmptyStack()
+	t.pendingRedirect = ""
+	t.atLineStart = true
+	t.line = t.line[:0]
+}
+
 func (t *Tokenizer) handleRune() (*Token, error) {
 	tokenType := &t.currentTokenState.tokenType
 	value := &t.currentTokenState.value
+	if t.trace != nil && t.currentTokenState.nextRuneType != eofRuneClass {
+		t.trace(t.pos, t.statesStack.CurrentState(), t.currentTokenState.nextRuneType, t.currentTokenState.nextRune)
+	}
 
 	switch t.statesStack.CurrentState() {
 	case startState:
@@ -400,10 +1144,9 @@ func (t *Tokenizer) handleRune() (*Token, error) {
 		{
 			if t.handleInWordState() {
 				var token *Token
-				if len(*value) != 0 {
-					token = &Token{
-						TokenType: *tokenType,
-						Value:     string(*value)}
+				state := t.currentTokenState
+				if len(*value) != 0 || state.quoted || state.emptyParams {
+					token = t.wordToken()
 				} else {
 					token = nil
 				}
@@ -418,37 +1161,29 @@ func (t *Tokenizer) handleRune() (*Token, error) {
 	case escapingState:
 		{
 			if t.handleEscapingState() {
-				token := &Token{
-					TokenType: *tokenType,
-					Value:     string(*value)}
-				return token, fmt.Errorf("EOF found after escape character")
+				token := t.partialToken()
+				return token, t.syntaxError(t.pos, true, "unexpected EOF after escape character")
 			}
 		}
 	case escapingQuotedState:
 		{
 			if t.handleEscapingQuotedState() {
-				token := &Token{
-					TokenType: *tokenType,
-					Value:     string(*value)}
-				return token, fmt.Errorf("EOF found after escape character")
+				token := t.partialToken()
+				return token, t.syntaxError(t.pos, true, "unexpected EOF after escape character")
 			}
 		}
 	case quotingEscapingState:
 		{
 			if t.handleQuotingEscapingState() {
-				token := &Token{
-					TokenType: *tokenType,
-					Value:     string(*value)}
-				return token, fmt.Errorf("EOF found when expecting closing quote")
+				token := t.partialToken()
+				return token, t.syntaxError(t.currentTokenState.quotePos, true, "unexpected EOF while looking for matching `\"'")
 			}
 		}
 	case quotingState:
 		{
 			if t.handleQuotingState() {
-				token := &Token{
-					TokenType: *tokenType,
-					Value:     string(*value)}
-				return token, fmt.Errorf("EOF found when expecting closing quote")
+				token := t.partialToken()
+				return token, t.syntaxError(t.currentTokenState.quotePos, true, "unexpected EOF while looking for matching `''")
 			}
 		}
 	case commentState:
@@ -460,16 +1195,30 @@ func (t *Tokenizer) handleRune() (*Token, error) {
 			if t.isEnded {
 				token := &Token{
 					TokenType: *tokenType,
-					Value:     string(*value)}
+					Value:     string(*value),
+					Line:      t.currentTokenState.start.line,
+					Column:    t.currentTokenState.start.column}
 				return token, io.EOF
 			} else {
-				return &Token{TokenType: EndLineToken, Value: endLineRunes}, nil
+				return &Token{TokenType: EndLineToken, Value: endLineRunes, Line: t.pos.line, Column: t.pos.column}, nil
 			}
 		}
 	case enviromentVariableState:
 		{
 			return t.handleEnviromentVariableState()
 		}
+	case arithmeticState:
+		{
+			return t.handleArithmeticState()
+		}
+	case processSubstitutionState:
+		{
+			return t.handleProcessSubstitutionState()
+		}
+	case ansiQuotingState:
+		{
+			return t.handleAnsiQuotingState()
+		}
 	}
 	return nil, nil
 }
@@ -485,17 +1234,22 @@ func (t *Tokenizer) scanStream() (*Token, error) {
 		state := t.statesStack.CurrentState()
 
 		// Токен может быть получен на прошлой итерации, если так отдаем его
+		line, column := t.symbolPos.line, t.symbolPos.column
 		if state == pipeSymbolState {
 			t.statesStack.Pop()
-			return &Token{TokenType: PipeToken, Value: pipeRunes}, nil
+			return &Token{TokenType: PipeToken, Value: pipeRunes, Line: line, Column: column}, nil
 
 		} else if state == endLineState {
 			t.statesStack.Pop()
-			return &Token{TokenType: EndLineToken, Value: endLineRunes}, nil
+			return &Token{TokenType: EndLineToken, Value: endLineRunes, Line: line, Column: column}, nil
+
+		} else if state == redirectSymbolState {
+			t.statesStack.Pop()
+			return &Token{TokenType: RedirectToken, Value: t.pendingRedirect, Line: line, Column: column}, nil
 		}
 
 		// Читаем следующий символ и классифицируем его
-		t.currentTokenState.nextRune, _, t.currentTokenState.err = t.input.ReadRune()
+		t.currentTokenState.nextRune, t.currentTokenState.err = t.readRune()
 		t.currentTokenState.nextRuneType = t.classifier.ClassifyRune(t.currentTokenState.nextRune)
 
 		// Если произошла ошибка при чтении, вернуть ошибку
@@ -504,12 +1258,26 @@ func (t *Tokenizer) scanStream() (*Token, error) {
 			t.currentTokenState.err = nil
 
 		} else if t.currentTokenState.err != nil {
+			if errors.Is(t.currentTokenState.err, InputInterrupted) {
+				t.reset()
+			}
 			return nil, t.currentTokenState.err
 		}
 
+		// Токен начинается с первого символа, прочитанного в начальном состоянии
+		runePos := t.pos
+		if state == startState {
+			t.currentTokenState.start = runePos
+		}
+
 		// Обработать текущий символ в контексте текущего состояни
 		token, err := t.handleRune()
 
+		switch t.statesStack.CurrentState() {
+		case pipeSymbolState, endLineState, redirectSymbolState:
+			t.symbolPos = runePos
+		}
+
 		if token != nil || err != nil {
 			t.currentTokenState = nil
 			return token, err
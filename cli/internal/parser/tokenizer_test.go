@@ -1,6 +1,7 @@
 package parser_test
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	envsholder "shell/internal/envs_holder"
@@ -419,6 +420,7 @@ func TestEnvVarTestStirng(t *testing.T) {
 		},
 		{
 			{TokenType: WordToken, Value: "echo"},
+			{TokenType: WordToken, Value: ""},
 		},
 		{
 			{TokenType: WordToken, Value: "echo"},
@@ -458,3 +460,167 @@ func TestEnvVarTestStirng(t *testing.T) {
 		}
 	}
 }
+
+func TestArithmeticExpansion(t *testing.T) {
+	vars := map[string]string{"x": "4"}
+
+	list := []string{
+		"echo $((1 + 2))",
+		"echo a$(( x * (x + 1) ))b",
+		"echo \"sum: $(( $x + 1 ))\"",
+		"echo '$((1 + 2))'",
+	}
+	expected := [][]Token{
+		{
+			{TokenType: WordToken, Value: "echo"},
+			{TokenType: WordToken, Value: "3"},
+		},
+		{
+			{TokenType: WordToken, Value: "echo"},
+			{TokenType: WordToken, Value: "a20b"},
+		},
+		{
+			{TokenType: WordToken, Value: "echo"},
+			{TokenType: WordToken, Value: "sum: 5"},
+		},
+		{
+			{TokenType: WordToken, Value: "echo"},
+			{TokenType: WordToken, Value: "$((1 + 2))"},
+		},
+	}
+
+	for i, str := range list {
+		tokens, err := splitOnTokens(str, vars)
+
+		if err != nil {
+			t.Fail()
+		}
+
+		result := compareTwoTokensArray(tokens, expected[i])
+
+		if !result {
+			fmt.Println(i, " ", tokens)
+			t.Fail()
+		}
+	}
+}
+
+func TestArithmeticExpansionAssignment(t *testing.T) {
+	envs := envsholder.Env{}
+	envs.Init()
+	tokenizer := NewTokenizer(strings.NewReader("echo $((i += 2)) $((i * 10))\n"), &envs)
+
+	var values []string
+	for {
+		token, err := tokenizer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.TokenType == EndLineToken {
+			break
+		}
+		values = append(values, token.Value)
+	}
+
+	if strings.Join(values, " ") != "echo 2 20" || envs.Vars["i"] != "2" {
+		t.Fatalf("unexpected result: %v, i=%q", values, envs.Vars["i"])
+	}
+}
+
+func TestArithmeticExpansionError(t *testing.T) {
+	envs := envsholder.Env{}
+	envs.Init()
+	tokenizer := NewTokenizer(strings.NewReader("echo $((1 / 0)) rest\necho next\n"), &envs)
+
+	var err error
+	for err == nil {
+		_, err = tokenizer.Next()
+	}
+	if err == io.EOF {
+		t.Fatal("expected arithmetic error")
+	}
+
+	// После ошибки разбор продолжается со следующей строки
+	tokens := []string{}
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token.Value)
+	}
+	if strings.Join(tokens, " ") != "echo next \n" {
+		t.Fatalf("unexpected tokens after error: %q", tokens)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	envs := envsholder.Env{}
+	envs.Init()
+	tokenizer := NewTokenizer(strings.NewReader("echo 'a b'|wc >out\n\tcat\n"), &envs)
+
+	expected := []struct {
+		value        string
+		line, column int
+	}{
+		{"echo", 1, 1}, {"a b", 1, 6}, {"|", 1, 11}, {"wc", 1, 12}, {">", 1, 15}, {"out", 1, 16}, {"\n", 1, 19},
+		{"cat", 2, 2}, {"\n", 2, 5},
+	}
+	for _, e := range expected {
+		token, err := tokenizer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Value != e.value || token.Line != e.line || token.Column != e.column {
+			t.Fatalf("unexpected token %q at %d:%d, expected %q at %d:%d",
+				token.Value, token.Line, token.Column, e.value, e.line, e.column)
+		}
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	continuations := 0
+	envs := envsholder.Env{}
+	envs.Init()
+	tokenizer := NewTokenizer(strings.NewReader("echo ab\\\ncd \"x\\\ny\" 'p\nq'\n"), &envs)
+	tokenizer.SetContinuation(func() { continuations++ })
+
+	tokens := []string{}
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token.Value)
+	}
+
+	expected := []string{"echo", "abcd", "xy", "p\nq", "\n"}
+	if strings.Join(tokens, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected tokens: %q", tokens)
+	}
+	if continuations != 3 {
+		t.Fatalf("unexpected number of continuations: %d", continuations)
+	}
+}
+
+func TestUnterminatedQuote(t *testing.T) {
+	_, err := splitOnTokens("echo ok\necho 'open\nmore", nil)
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected syntax error, got %v", err)
+	}
+	if !syntaxErr.Incomplete || syntaxErr.Line != 2 || syntaxErr.Column != 6 {
+		t.Fatalf("unexpected error: %+v", syntaxErr)
+	}
+	expected := "2:6: unexpected EOF while looking for matching `''\necho 'open\n     ^"
+	if syntaxErr.Diagnostic() != expected {
+		t.Fatalf("unexpected diagnostic:\n%s", syntaxErr.Diagnostic())
+	}
+}
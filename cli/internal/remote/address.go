@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// parseAddress разбирает адрес вида "unix:/path/to.sock" или "tcp:host:port".
+// Без префикса адрес, содержащий "/", считается путем к unix-сокету, иначе - TCP-адресом.
+// Для TCP без хоста используется 127.0.0.1.
+func parseAddress(address string) (network string, addr string, err error) {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		network, addr = "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		network, addr = "tcp", strings.TrimPrefix(address, "tcp:")
+	case strings.Contains(address, "/"):
+		network, addr = "unix", address
+	default:
+		network, addr = "tcp", address
+	}
+
+	if addr == "" {
+		return "", "", fmt.Errorf("empty address: %q", address)
+	}
+	if network == "tcp" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", "", err
+		}
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		addr = net.JoinHostPort(host, port)
+	}
+	return network, addr, nil
+}
+
+// Listen начинает слушать адрес address.
+// Оболочка дает полный доступ к системе, поэтому TCP-адрес должен быть локальным,
+// а unix-сокет доступен только владельцу процесса.
+func Listen(address string) (net.Listener, error) {
+	network, addr, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if network == "tcp" {
+		host, _, _ := net.SplitHostPort(addr)
+		ip := net.ParseIP(host)
+		if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("refusing to listen on non-loopback address %s", addr)
+		}
+		return net.Listen(network, addr)
+	}
+
+	return listenUnix(addr)
+}
+
+// listenUnix создает unix-сокет path, к которому никогда не могут подключиться
+// другие пользователи: сокет создается во временной директории с правами 0700
+// рядом с path, получает права 0600 и только затем жесткой ссылкой переносится
+// на место. Как и net.Listen, функция не заменяет существующий файл.
+func listenUnix(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".shell-socket-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "socket")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// Временный путь удаляется вместе с директорией, а итоговый - в Close
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err := os.Link(tmp, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: listener, addr: &net.UnixAddr{Name: path, Net: "unix"}}, nil
+}
+
+// Слушающий unix-сокет, перенесенный из временной директории на место.
+// Addr возвращает итоговый путь, Close удаляет файл сокета.
+type unixListener struct {
+	*net.UnixListener
+	addr      *net.UnixAddr
+	closeOnce sync.Once
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.closeOnce.Do(func() {
+		os.Remove(l.addr.Name)
+	})
+	return err
+}
+
+// Dial подключается к оболочке, слушающей адрес address
+func Dial(address string) (net.Conn, error) {
+	network, addr, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return net.Dial(network, addr)
+}
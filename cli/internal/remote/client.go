@@ -0,0 +1,21 @@
+package remote
+
+import (
+	"io"
+	"net"
+)
+
+// Attach подключает stdin и stdout к удаленной сессии, доступной через conn.
+// Когда stdin заканчивается, сервер получает конец ввода и завершает сессию.
+// Возвращается после того, как сервер закроет соединение.
+func Attach(conn net.Conn, stdin io.Reader, stdout io.Writer) error {
+	go func() {
+		io.Copy(conn, stdin)
+		if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+			closer.CloseWrite()
+		}
+	}()
+
+	_, err := io.Copy(stdout, conn)
+	return err
+}
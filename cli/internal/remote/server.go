@@ -0,0 +1,172 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"shell/internal/executor"
+	"shell/internal/session"
+	shellmodel "shell/internal/shell_model"
+	"sync"
+)
+
+// Server принимает подключения и запускает для каждого клиента
+// отдельную сессию оболочки со своими переменными и текущей директорией.
+// Ввод и вывод сессии передаются через соединение.
+type Server struct {
+	listener net.Listener
+	// Поток для журнала подключений
+	log io.Writer
+	// Журнал исполненных пайплайнов всех сессий
+	audit *executor.AuditLog
+	// Настройка новой сессии перед запуском, например ограниченный режим
+	setup func(*session.Session) error
+
+	mutex  sync.Mutex
+	shells map[*shellmodel.Shell]net.Conn
+	wg     sync.WaitGroup
+}
+
+// Создает сервер, принимающий подключения из listener
+func NewServer(listener net.Listener, log io.Writer) *Server {
+	if log == nil {
+		log = io.Discard
+	}
+	return &Server{listener: listener, log: log, shells: make(map[*shellmodel.Shell]net.Conn)}
+}
+
+// SetAuditLog включает запись пайплайнов всех сессий в журнал audit.
+// Должен вызываться до Serve.
+func (s *Server) SetAuditLog(audit *executor.AuditLog) {
+	s.audit = audit
+}
+
+// SetSessionSetup задает функцию, которая вызывается для каждой новой сессии до ее запуска.
+// Если функция возвращает ошибку, сессия не запускается, а подключение закрывается.
+// Должен вызываться до Serve.
+func (s *Server) SetSessionSetup(setup func(*session.Session) error) {
+	s.setup = setup
+}
+
+// Serve принимает подключения, пока не будет отменен ctx.
+// После отмены сервер перестает принимать подключения, завершает все сессии
+// и дожидается их окончания.
+func (s *Server) Serve(ctx context.Context) error {
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.listener.Close()
+		case <-stopped:
+		}
+	}()
+
+	var err error
+	for {
+		var conn net.Conn
+		conn, err = s.listener.Accept()
+		if err != nil {
+			break
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+		}()
+	}
+
+	s.terminateAll()
+	s.wg.Wait()
+	if ctx.Err() != nil && errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// serveConn исполняет сессию оболочки поверх соединения conn
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	// Внешние процессы получают дескриптор сокета напрямую,
+	// иначе exec вычитывал бы из соединения ввод, предназначенный оболочке
+	var stream io.ReadWriter = conn
+	if fileConn, ok := conn.(interface{ File() (*os.File, error) }); ok {
+		file, err := fileConn.File()
+		if err != nil {
+			fmt.Fprintf(s.log, "remote: %s: %v\n", describeAddr(conn.RemoteAddr()), err)
+			return
+		}
+		defer file.Close()
+		stream = file
+	}
+
+	sess, err := session.New("", stream)
+	if err != nil {
+		fmt.Fprintf(conn, "shell: %v\n", err)
+		return
+	}
+	if s.setup != nil {
+		if err := s.setup(sess); err != nil {
+			fmt.Fprintf(conn, "shell: %v\n", err)
+			fmt.Fprintf(s.log, "remote: %s: session setup failed: %v\n", describeAddr(conn.RemoteAddr()), err)
+			return
+		}
+	}
+	sh := shellmodel.NewShell(sess)
+	sh.SetAuditLog(s.audit)
+	if !s.register(sh, conn) {
+		return
+	}
+	defer s.unregister(sh)
+
+	fmt.Fprintf(s.log, "remote: session %s started for %s\n", sess.ID, describeAddr(conn.RemoteAddr()))
+	status := sh.ShellLoop(stream, stream, true)
+	fmt.Fprintf(s.log, "remote: session %s finished with status %d\n", sess.ID, status)
+}
+
+// register запоминает активную сессию. Возвращает false, если сервер уже останавливается.
+func (s *Server) register(sh *shellmodel.Shell, conn net.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.shells == nil {
+		return false
+	}
+	s.shells[sh] = conn
+	return true
+}
+
+func (s *Server) unregister(sh *shellmodel.Shell) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.shells, sh)
+}
+
+// terminateAll завершает все активные сессии и закрывает их соединения
+func (s *Server) terminateAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for sh, conn := range s.shells {
+		sh.Terminate()
+		// Сессия читает из дубликата дескриптора сокета, поэтому соединение
+		// нужно именно выключить: закрытие одного дескриптора чтение не прервет
+		if closer, ok := conn.(interface{ CloseRead() error }); ok {
+			closer.CloseRead()
+		}
+		conn.Close()
+	}
+	s.shells = nil
+}
+
+// describeAddr возвращает адрес клиента для журнала.
+// У клиентов unix-сокета адреса обычно нет, поэтому выводится тип сети.
+func describeAddr(addr net.Addr) string {
+	if addr == nil || addr.String() == "" || addr.String() == "@" {
+		return "unix client"
+	}
+	return addr.String()
+}
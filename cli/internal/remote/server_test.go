@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"shell/internal/session"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startServer запускает сервер на address и останавливает его в конце теста
+func startServer(t *testing.T, address string) string {
+	listener, err := Listen(address)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(listener, nil).Serve(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	return listener.Addr().Network() + ":" + listener.Addr().String()
+}
+
+// runRemote исполняет скрипт в новой удаленной сессии и возвращает ее вывод без приглашений
+func runRemote(t *testing.T, address string, script string) string {
+	conn, err := Dial(address)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var output bytes.Buffer
+	require.NoError(t, Attach(conn, strings.NewReader(script), &output))
+	return strings.ReplaceAll(output.String(), "$ ", "")
+}
+
+func TestRemoteSessionsAreIsolated(t *testing.T) {
+	address := startServer(t, "unix:"+filepath.Join(t.TempDir(), "shell.sock"))
+	dir := t.TempDir()
+
+	const clients = 8
+	outputs := make([]string, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			script := "echo $x\n"
+			if i%2 == 0 {
+				script = "x=even\ncd " + dir + "\necho $x $PWD\n"
+			}
+			outputs[i] = runRemote(t, address, script)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, output := range outputs {
+		if i%2 == 0 {
+			require.Equal(t, "even "+dir+"\n", output)
+		} else {
+			require.Equal(t, "\n", output)
+		}
+	}
+}
+
+func TestRemoteExternalProcess(t *testing.T) {
+	if _, err := exec.LookPath("expr"); err != nil {
+		t.Skip("expr is not available")
+	}
+	address := startServer(t, "tcp:127.0.0.1:0")
+
+	output := runRemote(t, address, "expr 40 + 2\necho 2 | expr 3 \\* 3 | cat\n")
+	require.Equal(t, "42\n9\n", output)
+}
+
+func TestRemoteExit(t *testing.T) {
+	address := startServer(t, "unix:"+filepath.Join(t.TempDir(), "shell.sock"))
+
+	output := runRemote(t, address, "echo one\nexit\necho two\n")
+	require.Equal(t, "one\n", output)
+}
+
+func TestServerStopsSessions(t *testing.T) {
+	listener, err := Listen("unix:" + filepath.Join(t.TempDir(), "shell.sock"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(listener, nil).Serve(ctx)
+	}()
+
+	conn, err := Dial("unix:" + listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("echo started\n"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	_, err = conn.Read(buf)
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestListenRejectsRemoteAddresses(t *testing.T) {
+	_, err := Listen("tcp:0.0.0.0:0")
+	require.Error(t, err)
+}
+
+func TestListenUnixSocketPermissions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shell.sock")
+	listener, err := Listen("unix:" + path)
+	require.NoError(t, err)
+	require.Equal(t, path, listener.Addr().String())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.ModeSocket|0600, info.Mode()&(os.ModeSocket|os.ModePerm))
+	// Временная директория удалена, а существующий сокет не заменяется
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	_, err = Listen("unix:" + path)
+	require.Error(t, err)
+
+	require.NoError(t, listener.Close())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestServerRejectsFailedSetup(t *testing.T) {
+	listener, err := Listen("unix:" + filepath.Join(t.TempDir(), "shell.sock"))
+	require.NoError(t, err)
+
+	server := NewServer(listener, nil)
+	server.SetSessionSetup(func(sess *session.Session) error {
+		return errors.New("env.list: line 1: invalid variable name")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	// Сессия не запускается, и команды клиента не исполняются
+	conn, err := Dial("unix:" + listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	output, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "shell: env.list: line 1: invalid variable name\n", string(output))
+}
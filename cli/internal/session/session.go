@@ -0,0 +1,483 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	envsholder "shell/internal/envs_holder"
+	"shell/pkg/vfs"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Счетчик для выдачи уникальных идентификаторов сессий
+var lastSessionID atomic.Uint64
+
+// Session хранит состояние одного экземпляра оболочки:
+// переменные окружения, текущую директорию, алиасы и опции.
+// Несколько сессий могут работать одновременно в одном процессе,
+// так как ни одна из них не изменяет состояние процесса (os.Chdir, os.Setenv).
+// Команды одного пайплайна исполняются параллельно, поэтому доступ
+// к состоянию защищен мьютексом.
+type Session struct {
+	// Уникальный в рамках процесса идентификатор сессии
+	ID string
+	// Поток, в который выводятся ошибки и служебные сообщения.
+	// В него могут одновременно писать все команды пайплайна.
+	Stderr io.Writer
+
+	mutex   sync.RWMutex
+	env     *envsholder.Env
+	dir     string
+	aliases map[string]string
+	options map[string]bool
+	// Обработчики событий trap: имя события -> код оболочки
+	traps map[string]string
+	// Стек директорий pushd/popd без текущей директории, вершина - первый элемент
+	dirStack []string
+	// Таблица найденных в PATH программ: имя -> путь и число обращений
+	hashes map[string]*HashEntry
+	// Ограниченный режим и внешние программы, которые в нем разрешены
+	restricted bool
+	allowed    map[string]bool
+	// Файловая система, с которой работают встроенные команды и перенаправления
+	fs vfs.FS
+}
+
+// Запись таблицы найденных в PATH программ
+type HashEntry struct {
+	Name string
+	Path string
+	// Число запусков программы по этой записи
+	Hits int
+}
+
+// Имена опций оболочки, которые меняет команда set
+const (
+	// Завершать сессию, если команда завершилась с ненулевым кодом
+	OptionErrexit = "errexit"
+	// Считать ошибкой подстановку незаданной переменной
+	OptionNounset = "nounset"
+	// Выводить каждую команду перед исполнением
+	OptionXtrace = "xtrace"
+	// Код возврата пайплайна - код последней неудачной команды, а не последней команды
+	OptionPipefail = "pipefail"
+	// Не перезаписывать существующие файлы перенаправлением >
+	OptionNoclobber = "noclobber"
+	// Не раскрывать шаблоны имен файлов
+	OptionNoglob = "noglob"
+	// Передавать записи вместо текста между встроенными командами, которые это умеют
+	OptionStructured = "structured"
+)
+
+// Все опции оболочки в порядке вывода командой set -o
+var OptionNames = []string{OptionErrexit, OptionNoclobber, OptionNoglob, OptionNounset, OptionPipefail, OptionStructured, OptionXtrace}
+
+// События, для которых команда trap задает обработчики, кроме сигналов
+const (
+	// Завершение сессии
+	TrapExit = "EXIT"
+	// Завершение команды с ненулевым кодом возврата
+	TrapErr = "ERR"
+)
+
+// Переменные, в которых сессия хранит текущую и предыдущую директории
+const (
+	PwdKey    = "PWD"
+	OldPwdKey = "OLDPWD"
+)
+
+// Переменная со списком директорий поиска программ
+const PathKey = "PATH"
+
+// New создает сессию с текущей директорией dir.
+// Пустой dir означает текущую директорию процесса.
+func New(dir string, stderr io.Writer) (*Session, error) {
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	stderr = NewSyncWriter(stderr)
+
+	env := &envsholder.Env{}
+	env.Init()
+	env.Set(envsholder.ExecStatusKey, envsholder.OkStatusValue)
+	env.Set(PwdKey, dir)
+
+	return &Session{
+		ID:      fmt.Sprintf("%d-%d", os.Getpid(), lastSessionID.Add(1)),
+		Stderr:  stderr,
+		env:     env,
+		dir:     dir,
+		aliases: make(map[string]string),
+		options: make(map[string]bool),
+		traps:   make(map[string]string),
+		hashes:  make(map[string]*HashEntry),
+		fs:      vfs.OS,
+	}, nil
+}
+
+//////////////////////////////////
+
+// Env возвращает хранилище переменных сессии без блокировки.
+// Используется токенизатором, который работает только между исполнениями пайплайнов.
+func (s *Session) Env() *envsholder.Env {
+	return s.env
+}
+
+// WithEnv исполняет fn, удерживая блокировку переменных сессии
+func (s *Session) WithEnv(fn func(env *envsholder.Env)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fn(s.env)
+}
+
+// Get возвращает значение переменной сессии
+func (s *Session) Get(name string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	value, ok := s.env.Vars[name]
+	return value, ok
+}
+
+// Set устанавливает значение переменной сессии.
+// Изменение PATH сбрасывает таблицу найденных программ.
+func (s *Session) Set(name string, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.env.Set(name, value)
+	if name == PathKey {
+		clear(s.hashes)
+	}
+}
+
+// Unset удаляет переменную сессии
+func (s *Session) Unset(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.env.Unset(name)
+	if name == PathKey {
+		clear(s.hashes)
+	}
+}
+
+// Environ возвращает переменные сессии в виде набора строк вида "ключ=значение"
+func (s *Session) Environ() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.env.Environ()
+}
+
+// Params возвращает позиционные параметры сессии
+func (s *Session) Params() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]string(nil), s.env.Params...)
+}
+
+// SetParams заменяет позиционные параметры сессии
+func (s *Session) SetParams(params []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.env.Params = append([]string(nil), params...)
+}
+
+//////////////////////////////////
+
+// Dir возвращает текущую директорию сессии
+func (s *Session) Dir() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.dir
+}
+
+// Chdir меняет текущую директорию сессии.
+// Относительный путь разрешается относительно текущей директории сессии,
+// символические ссылки не раскрываются (логический путь).
+// Переменные PWD и OLDPWD обновляются вместе с директорией.
+func (s *Session) Chdir(path string) error {
+	path = s.Path(path)
+	info, err := s.FS().Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "chdir", Path: path, Err: fmt.Errorf("not a directory")}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.env.Set(OldPwdKey, s.dir)
+	s.env.Set(PwdKey, path)
+	s.dir = path
+	return nil
+}
+
+// PhysicalDir возвращает текущую директорию сессии с раскрытыми символическими ссылками.
+// Вне файловой системы ОС символические ссылки не раскрываются.
+func (s *Session) PhysicalDir() (string, error) {
+	if s.FS() != vfs.OS {
+		return s.Dir(), nil
+	}
+	return filepath.EvalSymlinks(s.Dir())
+}
+
+// FS возвращает файловую систему сессии
+func (s *Session) FS() vfs.FS {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.fs
+}
+
+// SetFS заменяет файловую систему сессии, например на файловую систему в памяти
+// или на поддиректорию. Текущая директория не меняется и должна существовать
+// в новой файловой системе; внешние программы по-прежнему работают с диском.
+func (s *Session) SetFS(fsys vfs.FS) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fs = fsys
+}
+
+// DirStack возвращает копию стека директорий
+func (s *Session) DirStack() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]string(nil), s.dirStack...)
+}
+
+// SetDirStack заменяет стек директорий
+func (s *Session) SetDirStack(dirs []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dirStack = append([]string(nil), dirs...)
+}
+
+// Path превращает путь, заданный относительно текущей директории сессии, в абсолютный
+func (s *Session) Path(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(s.Dir(), path)
+}
+
+//////////////////////////////////
+
+// Alias возвращает значение алиаса
+func (s *Session) Alias(name string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	value, ok := s.aliases[name]
+	return value, ok
+}
+
+// SetAlias устанавливает алиас
+func (s *Session) SetAlias(name string, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.aliases[name] = value
+}
+
+// Unalias удаляет алиас. Возвращает false, если алиаса не было.
+func (s *Session) Unalias(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.aliases[name]
+	delete(s.aliases, name)
+	return ok
+}
+
+// Aliases возвращает отсортированные по имени имена алиасов
+func (s *Session) Aliases() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := make([]string, 0, len(s.aliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//////////////////////////////////
+
+// Option сообщает, включена ли опция оболочки с указанным именем
+func (s *Session) Option(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.options[name]
+}
+
+// SetOption включает или выключает опцию оболочки
+func (s *Session) SetOption(name string, enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.options[name] = enabled
+}
+
+//////////////////////////////////
+
+// Trap возвращает код обработчика события name.
+// Пустой код означает, что событие игнорируется.
+func (s *Session) Trap(name string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	code, ok := s.traps[name]
+	return code, ok
+}
+
+// SetTrap задает обработчик события name
+func (s *Session) SetTrap(name string, code string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.traps[name] = code
+}
+
+// ResetTrap удаляет обработчик события name
+func (s *Session) ResetTrap(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.traps, name)
+}
+
+// Traps возвращает отсортированные имена событий, для которых заданы обработчики
+func (s *Session) Traps() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := make([]string, 0, len(s.traps))
+	for name := range s.traps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//////////////////////////////////
+
+// Писатель, сериализующий конкурентные вызовы Write
+type syncWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewSyncWriter оборачивает w так, чтобы его можно было использовать
+// из нескольких горутин одновременно.
+// Файлы не оборачиваются: запись в них и так безопасна, а внешние процессы
+// получают дескриптор файла напрямую.
+func NewSyncWriter(w io.Writer) io.Writer {
+	switch w.(type) {
+	case *syncWriter, *os.File:
+		return w
+	}
+	return &syncWriter{writer: w}
+}
+
+func (w *syncWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(data)
+}
+
+//////////////////////////////////
+
+// SearchPath возвращает список директорий поиска программ: переменную PATH сессии,
+// а если она не задана - PATH процесса
+func (s *Session) SearchPath() string {
+	if path, ok := s.Get(PathKey); ok {
+		return path
+	}
+	return os.Getenv(PathKey)
+}
+
+// Hashed возвращает путь к программе из таблицы найденных программ.
+// Если hit установлен, обращение засчитывается как запуск программы.
+func (s *Session) Hashed(name string, hit bool) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entry, ok := s.hashes[name]
+	if !ok {
+		return "", false
+	}
+	if hit {
+		entry.Hits++
+	}
+	return entry.Path, true
+}
+
+// Hash запоминает путь к программе name
+func (s *Session) Hash(name string, path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.hashes[name] = &HashEntry{Name: name, Path: path}
+}
+
+// Unhash удаляет программу из таблицы. Возвращает false, если ее там не было.
+func (s *Session) Unhash(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.hashes[name]
+	delete(s.hashes, name)
+	return ok
+}
+
+// ResetHashes очищает таблицу найденных программ
+func (s *Session) ResetHashes() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clear(s.hashes)
+}
+
+// Hashes возвращает копию таблицы найденных программ, отсортированную по имени
+func (s *Session) Hashes() []HashEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	entries := make([]HashEntry, 0, len(s.hashes))
+	for _, entry := range s.hashes {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+//////////////////////////////////
+
+// Переменные, которые нельзя изменять в ограниченном режиме
+var RestrictedVariables = []string{"ENV", PathKey, "SHELL"}
+
+// Restrict переводит сессию в ограниченный режим, в котором из внешних программ
+// можно запускать только перечисленные в allowed. Выйти из этого режима нельзя.
+func (s *Session) Restrict(allowed []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.restricted = true
+	s.allowed = make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		s.allowed[name] = true
+	}
+}
+
+// Restricted сообщает, что сессия работает в ограниченном режиме
+func (s *Session) Restricted() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.restricted
+}
+
+// ProgramAllowed сообщает, что внешнюю программу name можно запускать.
+// Вне ограниченного режима разрешены все программы.
+func (s *Session) ProgramAllowed(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return !s.restricted || s.allowed[name]
+}
@@ -1,57 +1,421 @@
 package shellmodel
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"shell/internal/command_meta"
+	"shell/internal/commands"
 	envsholder "shell/internal/envs_holder"
 	"shell/internal/executor"
 	"shell/internal/parser"
+	"shell/internal/session"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 )
 
+// Shell - экземпляр интерпретатора, работающий с состоянием одной сессии.
+// Разные экземпляры Shell не разделяют состояние и могут работать одновременно.
 type Shell struct {
+	sess            *session.Session
 	pipelineFactory *executor.PipelineFactory
-	terminate       chan bool
+
+	// Функция отмены исполняемого в данный момент пайплайна
+	cancelMutex sync.Mutex
+	cancel      context.CancelFunc
+	terminated  bool
+	// Код завершения сессии, прерванной методом Terminate или сигналом
+	terminateStatus int
+	// Исполняется обработчик EXIT: сессия уже завершается, но его команды должны исполниться
+	exiting bool
+	// Сигналы, обработчики которых нужно исполнить перед следующей командой
+	pendingTraps []string
+
+	// Исполняется обработчик события; обработчики не вызываются рекурсивно
+	inTrap bool
+
+	shutdownOnce   sync.Once
+	shutdownStatus int
+}
+
+func NewShell(sess *session.Session) *Shell {
+	pipelineFactory := executor.NewPipelineFactory(sess)
+	return &Shell{sess: sess, pipelineFactory: pipelineFactory}
+}
+
+// SetAuditLog включает запись исполняемых пайплайнов в журнал, nil выключает ее
+func (self *Shell) SetAuditLog(audit *executor.AuditLog) {
+	self.pipelineFactory.SetAuditLog(audit)
 }
 
-func NewShell() *Shell {
-	pipelineFactory := executor.NewPipelineFactory()
-	return &Shell{pipelineFactory: pipelineFactory, terminate: make(chan bool)}
+// Session возвращает сессию, с которой работает оболочка
+func (self *Shell) Session() *session.Session {
+	return self.sess
 }
 
 // Основной цикл оболочки
-// Обрабатывает пользовательский ввод
-func (self *Shell) ShellLoop(input *os.File, output *os.File, to_greet bool) {
+// Обрабатывает пользовательский ввод, который одновременно служит стандартным вводом команд.
+// Возвращает код завершения сессии.
+func (self *Shell) ShellLoop(input io.Reader, output io.Writer, to_greet bool) int {
+	return self.loop(context.Background(), input, input, output, to_greet)
+}
+
+// Interactive - цикл интерактивной оболочки: команды читаются из script
+// (обычно редактора строки) с выводом приглашений, а стандартным вводом
+// команд служит stdin. Возвращает код завершения сессии.
+func (self *Shell) Interactive(script io.Reader, stdin io.Reader, output io.Writer) int {
+	return self.loop(context.Background(), script, stdin, output, true)
+}
 
-	tokenizer := parser.NewTokenizer(input, &envsholder.GlobalEnv)
+// Run исполняет скрипт script, подавая командам на вход stdin.
+// Отмена ctx прерывает текущий пайплайн и завершает исполнение скрипта.
+// Возвращает код завершения сессии.
+func (self *Shell) Run(ctx context.Context, script io.Reader, stdin io.Reader, stdout io.Writer) int {
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	return self.loop(ctx, script, stdin, stdout, false)
+}
+
+func (self *Shell) loop(ctx context.Context, script io.Reader, stdin io.Reader, stdout io.Writer, to_greet bool) int {
+	status, _ := self.run(ctx, script, stdin, stdout, to_greet)
+	return self.Shutdown(status, stdin, stdout)
+}
+
+// run исполняет команды из script до конца ввода или завершения сессии.
+// Возвращает код возврата и признак того, что сессия должна завершиться
+// (exit, errexit, прерывание), а не просто закончился ввод.
+func (self *Shell) run(ctx context.Context, script io.Reader, stdin io.Reader, stdout io.Writer, to_greet bool) (int, bool) {
+	tokenizer := parser.NewTokenizer(script, self.sess.Env())
+	tokenizer.SetOptions(self.sess)
+	// Присваивания в $(( )) проверяются так же, как обычные присваивания
+	tokenizer.SetArithmeticSetter(commands.ArithmeticSetter(self.sess))
+	if to_greet {
+		tokenizer.SetContinuation(func() {
+			io.WriteString(stdout, self.prompt("PS2", defaultPS2))
+		})
+	}
 	curr_parser := parser.NewParser(tokenizer)
 	for {
+		if status, exited := self.runPendingTraps(ctx, stdin, stdout); exited {
+			return status, true
+		}
+		if ctx.Err() != nil || self.isTerminated() {
+			return self.terminationStatus(), true
+		}
 		if to_greet {
-			output.WriteString("$ ")
+			io.WriteString(stdout, defaultPS1)
 		}
-		commands, err := curr_parser.Parse()
+		metas, err := curr_parser.Parse()
 		end_of_file := err == io.EOF
 
+		// Сессия могла быть завершена, пока ожидался ввод
+		if self.isTerminated() {
+			return self.terminationStatus(), true
+		}
+
+		// С опцией nounset подстановка незаданной переменной - ошибка команды,
+		// которая завершает неинтерактивную сессию
+		var unbound *parser.UnboundVariableError
+		if errors.As(err, &unbound) {
+			fmt.Fprintf(self.sess.Stderr, "%s\n", err)
+			self.sess.Set(envsholder.ExecStatusKey, "1")
+			if !to_greet {
+				return 1, true
+			}
+			continue
+		}
+		// Набор команды прерван в редакторе строки: токенизатор уже забыл
+		// незаконченную команду, оболочка ждет следующую
+		if errors.Is(err, parser.InputInterrupted) {
+			self.sess.Set(envsholder.ExecStatusKey, strconv.Itoa(executor.InterruptedStatus))
+			continue
+		}
+		var syntaxErr *parser.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			fmt.Fprintf(self.sess.Stderr, "%s\n", syntaxErr.Diagnostic())
+			self.sess.Set(envsholder.ExecStatusKey, strconv.Itoa(SyntaxErrorStatus))
+			// Скрипт с синтаксической ошибкой не исполняется дальше,
+			// интерактивная оболочка ждет следующую команду
+			if !to_greet {
+				return SyntaxErrorStatus, true
+			}
+			continue
+		}
 		if err != nil && !end_of_file {
-			output.WriteString("Parse issue\n")
+			fmt.Fprintf(self.sess.Stderr, "Parse issue: %s\n", err)
 			continue
 		}
 
-		pipeline := self.pipelineFactory.CreatePipeline(input, output, commands)
+		if self.sess.Option(session.OptionXtrace) {
+			self.trace(metas)
+		}
+
+		metas, timer := takeTimeKeyword(metas)
+		execCtx := ctx
+		if timer != nil {
+			execCtx = timer.Start(ctx)
+		}
+
+		pipeline := self.pipelineFactory.CreatePipeline(stdin, stdout, metas)
+		if pipeline == nil && timer != nil {
+			self.reportTimes(timer)
+		}
 		if pipeline != nil {
-			err = pipeline.Execute()
-			if err != nil {
-				envsholder.GlobalEnv.Set(envsholder.ExecStatusKey, "1")
-				fmt.Printf("%s\n", err)
+			err = self.execute(execCtx, pipeline)
+			if timer != nil {
+				self.reportTimes(timer)
+			}
+			status := pipeline.Status(err)
+			self.sess.Set(envsholder.ExecStatusKey, strconv.Itoa(status))
+
+			var exitErr commands.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.Status, true
+			}
+			if err != nil && !commands.IsSilent(err) {
+				fmt.Fprintf(self.sess.Stderr, "%s\n", err)
+			}
+			if status != 0 {
+				if trapStatus, exited := self.runTrap(ctx, session.TrapErr, stdin, stdout); exited {
+					return trapStatus, true
+				}
+				if self.sess.Option(session.OptionErrexit) {
+					return status, true
+				}
 			}
 		}
 
 		if end_of_file {
+			return self.lastStatus(), false
+		}
+	}
+}
+
+// lastStatus возвращает код возврата последней команды ($?)
+func (self *Shell) lastStatus() int {
+	status, _ := self.sess.Get(envsholder.ExecStatusKey)
+	code, _ := strconv.Atoi(status)
+	return code
+}
+
+// runTrap исполняет обработчик события name, если он задан.
+// Код возврата последней команды ($?) после обработчика восстанавливается.
+// Если обработчик исполнил exit, возвращается код завершения сессии и true.
+func (self *Shell) runTrap(ctx context.Context, name string, stdin io.Reader, stdout io.Writer) (int, bool) {
+	code, ok := self.sess.Trap(name)
+	if !ok || code == "" || self.inTrap {
+		return 0, false
+	}
+
+	saved, _ := self.sess.Get(envsholder.ExecStatusKey)
+	self.inTrap = true
+	status, exited := self.run(ctx, strings.NewReader(code), stdin, stdout, false)
+	self.inTrap = false
+	if exited {
+		return status, true
+	}
+	self.sess.Set(envsholder.ExecStatusKey, saved)
+	return 0, false
+}
+
+// runPendingTraps исполняет обработчики полученных сигналов
+func (self *Shell) runPendingTraps(ctx context.Context, stdin io.Reader, stdout io.Writer) (int, bool) {
+	if self.inTrap {
+		return 0, false
+	}
+	self.cancelMutex.Lock()
+	pending := self.pendingTraps
+	self.pendingTraps = nil
+	self.cancelMutex.Unlock()
+
+	for _, name := range pending {
+		if status, exited := self.runTrap(ctx, name, stdin, stdout); exited {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// Shutdown завершает сессию с кодом status, исполняя обработчик EXIT, если он задан.
+// Обработчик исполняется один раз, даже если Shutdown вызывается из нескольких горутин;
+// все вызовы возвращают итоговый код завершения: status или код, переданный exit в обработчике.
+// Обработчик исполняется и после Terminate, так как обычно он удаляет временные файлы.
+func (self *Shell) Shutdown(status int, stdin io.Reader, stdout io.Writer) int {
+	self.shutdownOnce.Do(func() {
+		self.shutdownStatus = status
+		code, ok := self.sess.Trap(session.TrapExit)
+		if !ok || code == "" {
 			return
 		}
+
+		self.cancelMutex.Lock()
+		self.exiting = true
+		self.pendingTraps = nil
+		self.cancelMutex.Unlock()
+
+		self.sess.Set(envsholder.ExecStatusKey, strconv.Itoa(status))
+		self.inTrap = true
+		trapStatus, exited := self.run(context.Background(), strings.NewReader(code), stdin, stdout, false)
+		if exited {
+			self.shutdownStatus = trapStatus
+		}
+	})
+	return self.shutdownStatus
+}
+
+// Код возврата после синтаксической ошибки
+const SyntaxErrorStatus = 2
+
+// Приглашения по умолчанию: основное, приглашение продолжения команды
+// на следующей строке и префикс трассировки, если переменные PS2 и PS4 не заданы
+const (
+	defaultPS1 = "$ "
+	defaultPS2 = "> "
+	defaultPS4 = "+ "
+)
+
+// prompt возвращает значение переменной приглашения name или значение по умолчанию
+func (self *Shell) prompt(name string, fallback string) string {
+	if value, ok := self.sess.Get(name); ok {
+		return value
+	}
+	return fallback
+}
+
+// trace выводит команды пайплайна после подстановок (опция xtrace),
+// по команде в строке с префиксом из переменной PS4
+func (self *Shell) trace(metas []command_meta.CommandMeta) {
+	ps4 := self.prompt("PS4", defaultPS4)
+
+	var line strings.Builder
+	for _, meta := range metas {
+		words := make([]string, 0, len(meta.Envs.Vars)+len(meta.Args)+1)
+		names := make([]string, 0, len(meta.Envs.Vars))
+		for name := range meta.Envs.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			words = append(words, name+"="+commands.ShellQuote(meta.Envs.Vars[name]))
+		}
+		if meta.Name != "" {
+			words = append(words, commands.ShellQuote(meta.Name))
+		}
+		for _, arg := range meta.Args {
+			words = append(words, commands.ShellQuote(arg))
+		}
+		for _, redirect := range meta.Redirects {
+			words = append(words, redirect.Op+commands.ShellQuote(redirect.Target))
+		}
+
+		line.WriteString(ps4)
+		line.WriteString(strings.Join(words, " "))
+		line.WriteString("\n")
 	}
+	io.WriteString(self.sess.Stderr, line.String())
 }
 
+// execute исполняет пайплайн, позволяя прервать его методом Interrupt
+func (self *Shell) execute(ctx context.Context, pipeline *executor.Pipeline) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	self.cancelMutex.Lock()
+	self.cancel = cancel
+	self.cancelMutex.Unlock()
+
+	defer func() {
+		self.cancelMutex.Lock()
+		self.cancel = nil
+		self.cancelMutex.Unlock()
+	}()
+
+	return pipeline.Execute(ctx)
+}
+
+// Прервать исполняемый в данный момент пайплайн.
+// Сама оболочка продолжает работу и переходит к следующей команде.
+func (self *Shell) Interrupt() {
+	self.cancelMutex.Lock()
+	defer self.cancelMutex.Unlock()
+	if self.cancel != nil {
+		self.cancel()
+	}
+}
+
+// Завершить сессию: прервать текущий пайплайн и не исполнять следующие команды.
+// Процесс, в котором работает оболочка, при этом не завершается.
 func (self *Shell) Terminate() {
-	os.Exit(0)
+	self.terminate(executor.InterruptedStatus)
+}
+
+func (self *Shell) terminate(status int) {
+	self.cancelMutex.Lock()
+	defer self.cancelMutex.Unlock()
+	if !self.terminated {
+		self.terminated = true
+		self.terminateStatus = status
+	}
+	if self.cancel != nil {
+		self.cancel()
+	}
+}
+
+// isTerminated сообщает, что следующие команды исполнять не нужно.
+// Команды обработчика EXIT исполняются и после завершения сессии.
+func (self *Shell) isTerminated() bool {
+	self.cancelMutex.Lock()
+	defer self.cancelMutex.Unlock()
+	return self.terminated && !self.exiting
+}
+
+// terminationStatus возвращает код завершения прерванной сессии
+func (self *Shell) terminationStatus() int {
+	self.cancelMutex.Lock()
+	defer self.cancelMutex.Unlock()
+	if self.terminated {
+		return self.terminateStatus
+	}
+	return executor.InterruptedStatus
+}
+
+// Signal обрабатывает сигнал, полученный процессом оболочки.
+// Если для сигнала задан обработчик trap, он исполняется перед следующей командой,
+// а SIGINT кроме того прерывает текущий пайплайн; пустой обработчик означает, что сигнал игнорируется.
+// Без обработчика SIGINT прерывает текущий пайплайн, а остальные сигналы завершают сессию
+// с кодом 128+N: в этом случае возвращаются код завершения и true.
+func (self *Shell) Signal(sig syscall.Signal) (int, bool) {
+	name, ok := commands.SignalTrapName(sig)
+	if !ok {
+		return 0, false
+	}
+
+	code, trapped := self.sess.Trap(name)
+	switch {
+	case trapped && code == "":
+		return 0, false
+	case trapped:
+		self.cancelMutex.Lock()
+		self.pendingTraps = append(self.pendingTraps, name)
+		self.cancelMutex.Unlock()
+		if sig == syscall.SIGINT {
+			self.Interrupt()
+		}
+		return 0, false
+	case sig == syscall.SIGINT:
+		self.Interrupt()
+		return 0, false
+	}
+
+	status := 128 + int(sig)
+	self.terminate(status)
+	return status, true
 }
@@ -2,15 +2,31 @@ package shellmodel
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"os"
+	"shell/internal/parser"
+	"shell/internal/session"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
+// newTestShell создает оболочку с новой сессией в текущей директории процесса
+func newTestShell(t *testing.T) *Shell {
+	sess, err := session.New("", io.Discard)
+	if err != nil {
+		t.Fatal("Can't create session", err)
+	}
+	return NewShell(sess)
+}
+
 func TestShellCommand(t *testing.T) {
 	in_read, in_write, _ := os.Pipe()
 	out_read, out_write, _ := os.Pipe()
 
-	test_shell := NewShell()
+	test_shell := newTestShell(t)
 
 	buf := make([]byte, 128)
 	expected := []byte("42\n")
@@ -36,7 +52,7 @@ func TestPipe(t *testing.T) {
 	in_read, in_write, _ := os.Pipe()
 	out_read, out_write, _ := os.Pipe()
 
-	test_shell := NewShell()
+	test_shell := newTestShell(t)
 
 	buf := make([]byte, 128)
 	expected := []byte("\t1\t1\t3\n")
@@ -57,3 +73,308 @@ func TestPipe(t *testing.T) {
 		t.Fatalf(`Different outputs: %q != %q`, buf, expected)
 	}
 }
+
+func TestExitStatus(t *testing.T) {
+	in_read, in_write, _ := os.Pipe()
+	out_read, out_write, _ := os.Pipe()
+
+	test_shell := newTestShell(t)
+	expected := []byte("1\n0\n")
+
+	go func(sh *Shell, in *os.File, out *os.File) {
+		sh.ShellLoop(in, out, false)
+		out.Close()
+	}(test_shell, in_read, out_write)
+
+	in_write.WriteString("[ 1 -eq 2 ]\necho $?\ntest -n x\necho $?\n")
+	in_write.Close()
+	buf, err := io.ReadAll(out_read)
+	if err != nil {
+		t.Fatal("Cant read pipe", err)
+	}
+
+	if !bytes.Equal(buf, expected) {
+		t.Fatalf(`Different outputs: %q != %q`, buf, expected)
+	}
+}
+
+func TestExitEndsSession(t *testing.T) {
+	test_shell := newTestShell(t)
+	var output bytes.Buffer
+
+	script := strings.NewReader("echo before\nexit\necho after\n")
+	status := test_shell.Run(context.Background(), script, nil, &output)
+
+	if status != 0 {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if output.String() != "before\n" {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), "before\n")
+	}
+}
+
+func TestSessionsAreIsolated(t *testing.T) {
+	dir := t.TempDir()
+	first, second := newTestShell(t), newTestShell(t)
+	var firstOut, secondOut bytes.Buffer
+
+	first.Run(context.Background(), strings.NewReader("x=1\ncd "+dir+"\necho $x\npwd\n"), nil, &firstOut)
+	second.Run(context.Background(), strings.NewReader("echo $x\npwd\n"), nil, &secondOut)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal("Can't get working directory", err)
+	}
+	if firstOut.String() != "1\n"+dir {
+		t.Fatalf(`Different outputs: %q != %q`, firstOut.String(), "1\n"+dir)
+	}
+	if secondOut.String() != "\n"+cwd {
+		t.Fatalf(`Different outputs: %q != %q`, secondOut.String(), "\n"+cwd)
+	}
+}
+
+func TestSetErrexit(t *testing.T) {
+	test_shell := newTestShell(t)
+	var output bytes.Buffer
+
+	script := strings.NewReader("false\necho continued\nset -e\n[ 1 -eq 2 ]\necho after\n")
+	status := test_shell.Run(context.Background(), script, nil, &output)
+
+	if status != 1 {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if output.String() != "continued\n" {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), "continued\n")
+	}
+}
+
+func TestSetNounset(t *testing.T) {
+	var stderr bytes.Buffer
+	sess, err := session.New("", &stderr)
+	if err != nil {
+		t.Fatal("Can't create session", err)
+	}
+	var output bytes.Buffer
+
+	script := strings.NewReader("echo x$missing\nset -u\necho $missing\necho after\n")
+	status := NewShell(sess).Run(context.Background(), script, nil, &output)
+
+	if status != 1 {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if output.String() != "x\n" {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), "x\n")
+	}
+	if stderr.String() != "missing: unbound variable\n" {
+		t.Fatalf(`Different errors: %q`, stderr.String())
+	}
+}
+
+func TestSetXtrace(t *testing.T) {
+	var stderr bytes.Buffer
+	sess, err := session.New("", &stderr)
+	if err != nil {
+		t.Fatal("Can't create session", err)
+	}
+
+	script := strings.NewReader("x='a b'\nset -x\necho \"$x\" $x | y=1 wc\nPS4='>> '\necho hi\n")
+	NewShell(sess).Run(context.Background(), script, nil, io.Discard)
+
+	expected := "+ echo 'a b' a b\n+ y=1 wc\n+ PS4='>> '\n>> echo hi\n"
+	if stderr.String() != expected {
+		t.Fatalf(`Different traces: %q != %q`, stderr.String(), expected)
+	}
+}
+
+func TestExitWithStatus(t *testing.T) {
+	cases := []struct {
+		script string
+		status int
+	}{
+		{script: "exit 3\necho after\n", status: 3},
+		{script: "exit 257\n", status: 1},
+		{script: "[ 1 -eq 2 ]\nexit\n", status: 1},
+		{script: "exit abc\n", status: 2},
+	}
+	for _, tc := range cases {
+		var output bytes.Buffer
+		status := newTestShell(t).Run(context.Background(), strings.NewReader(tc.script), nil, &output)
+		if status != tc.status {
+			t.Fatalf("%q: unexpected status %d", tc.script, status)
+		}
+		if output.Len() != 0 {
+			t.Fatalf("%q: unexpected output %q", tc.script, output.String())
+		}
+	}
+}
+
+func TestTrapExitAndErr(t *testing.T) {
+	test_shell := newTestShell(t)
+	var output bytes.Buffer
+
+	script := strings.NewReader("trap 'echo cleanup $?' EXIT\ntrap 'echo failed $?' ERR\n" +
+		"[ 1 -eq 2 ]\necho status $?\ntrue\nexit 4\n")
+	status := test_shell.Run(context.Background(), script, nil, &output)
+
+	if status != 4 {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	expected := "failed 1\nstatus 1\ncleanup 4\n"
+	if output.String() != expected {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), expected)
+	}
+}
+
+func TestTrapExitOverridesStatus(t *testing.T) {
+	var output bytes.Buffer
+	script := strings.NewReader("trap 'echo bye\nexit 9' EXIT\necho hi\n")
+	status := newTestShell(t).Run(context.Background(), script, nil, &output)
+
+	if status != 9 {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if output.String() != "hi\nbye\n" {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), "hi\nbye\n")
+	}
+}
+
+func TestSignalTraps(t *testing.T) {
+	test_shell := newTestShell(t)
+
+	// Без обработчика SIGUSR1 завершает сессию с кодом 128+N
+	status, terminated := test_shell.Signal(syscall.SIGUSR1)
+	if !terminated || status != 128+int(syscall.SIGUSR1) {
+		t.Fatalf("Unexpected result: %d %v", status, terminated)
+	}
+
+	test_shell = newTestShell(t)
+	sess := test_shell.Session()
+	sess.SetTrap("TERM", "echo term")
+	sess.SetTrap("HUP", "")
+	sess.SetTrap("EXIT", "echo exit")
+
+	if _, terminated := test_shell.Signal(syscall.SIGTERM); terminated {
+		t.Fatal("Trapped signal terminated the session")
+	}
+	if _, terminated := test_shell.Signal(syscall.SIGHUP); terminated {
+		t.Fatal("Ignored signal terminated the session")
+	}
+
+	// Обработчик сигнала исполняется перед следующей командой
+	var output bytes.Buffer
+	test_shell.Run(context.Background(), strings.NewReader("echo next\n"), nil, &output)
+	if output.String() != "term\nnext\nexit\n" {
+		t.Fatalf(`Different outputs: %q`, output.String())
+	}
+}
+
+func TestContinuationPrompt(t *testing.T) {
+	test_shell := newTestShell(t)
+	test_shell.Session().Set("PS2", "more> ")
+	var output bytes.Buffer
+
+	test_shell.ShellLoop(strings.NewReader("echo 'a\nb' |\nwc\n"), &output, true)
+
+	expected := "$ more> more> \t2\t2\t4\n$ "
+	if output.String() != expected {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), expected)
+	}
+}
+
+// interruptedReader возвращает строки ввода по одной за чтение;
+// пустая строка означает прерывание набора команды
+type interruptedReader struct {
+	lines []string
+}
+
+func (r *interruptedReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	if line == "" {
+		return 0, parser.InputInterrupted
+	}
+	return copy(p, line), nil
+}
+
+func TestInterruptedInput(t *testing.T) {
+	test_shell := newTestShell(t)
+	var output bytes.Buffer
+
+	// Прерывание забывает открытую кавычку и продолжение пайплайна
+	script := &interruptedReader{lines: []string{"echo 'abc\n", "", "echo $?\n", "echo a |\n", "", "echo ok\n"}}
+	test_shell.Interactive(script, strings.NewReader(""), &output)
+
+	expected := "$ > $ 130\n$ > $ ok\n$ "
+	if output.String() != expected {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), expected)
+	}
+}
+
+func TestSyntaxErrorDiagnostic(t *testing.T) {
+	var stderr bytes.Buffer
+	sess, err := session.New("", &stderr)
+	if err != nil {
+		t.Fatal("Can't create session", err)
+	}
+	var output bytes.Buffer
+
+	// Скрипт завершается на синтаксической ошибке с кодом 2
+	script := strings.NewReader("echo a | | wc\necho after\n")
+	status := NewShell(sess).Run(context.Background(), script, nil, &output)
+
+	if status != SyntaxErrorStatus {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if output.String() != "" {
+		t.Fatalf(`Unexpected output: %q`, output.String())
+	}
+	expected := "1:10: syntax error near unexpected token `|'\necho a | | wc\n         ^\n"
+	if stderr.String() != expected {
+		t.Fatalf(`Different errors: %q != %q`, stderr.String(), expected)
+	}
+
+	// Интерактивная оболочка продолжает работу после ошибки
+	output.Reset()
+	NewShell(sess).ShellLoop(strings.NewReader("echo a | | wc\necho $?\n"), &output, true)
+	if strings.ReplaceAll(output.String(), defaultPS1, "") != "2\n" {
+		t.Fatalf(`Different outputs: %q`, output.String())
+	}
+}
+
+func TestTimeKeyword(t *testing.T) {
+	var stderr bytes.Buffer
+	sess, err := session.New("", &stderr)
+	if err != nil {
+		t.Fatal("Can't create session", err)
+	}
+	var output bytes.Buffer
+	script := strings.NewReader("TIMEFORMAT='took %0R %%'\ntime echo hi | cat\ntime\nTIMEFORMAT=\ntime echo quiet\n")
+	status := NewShell(sess).Run(context.Background(), script, nil, &output)
+
+	if status != 0 {
+		t.Fatalf("Unexpected status: %d", status)
+	}
+	if output.String() != "hi\nquiet\n" {
+		t.Fatalf(`Different outputs: %q != %q`, output.String(), "hi\nquiet\n")
+	}
+	if stderr.String() != "took 0 %\ntook 0 %\n" {
+		t.Fatalf(`Different reports: %q`, stderr.String())
+	}
+}
+
+func TestFormatTimes(t *testing.T) {
+	real, user, sys := 75250*time.Millisecond, 1500*time.Millisecond, 20*time.Millisecond
+	cases := map[string]string{
+		defaultTimeFormat: "\nreal\t1m15.250s\nuser\t0m1.500s\nsys\t0m0.020s",
+		posixTimeFormat:   "real 75.25\nuser 1.50\nsys 0.02",
+		"%1U %S %P %x %":  "1.5 0.020 2.02 %x %",
+	}
+	for format, expected := range cases {
+		if result := formatTimes(format, real, user, sys); result != expected {
+			t.Fatalf("%q: %q != %q", format, result, expected)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package shellmodel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"shell/internal/command_meta"
+	"shell/internal/commands"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Формат отчета time по умолчанию, если переменная TIMEFORMAT не задана,
+// и формат time -p
+const (
+	defaultTimeFormat = "\nreal\t%3lR\nuser\t%3lU\nsys\t%3lS"
+	posixTimeFormat   = "real %2R\nuser %2U\nsys %2S"
+)
+
+// Замер времени исполнения пайплайна для ключевого слова time
+type pipelineTimer struct {
+	posix bool
+	start time.Time
+	self  syscall.Rusage
+	usage commands.Usage
+}
+
+// takeTimeKeyword убирает ключевое слово time (и флаг -p) из начала пайплайна.
+// Возвращает nil вместо замера, если пайплайн не начинается с time.
+func takeTimeKeyword(metas []command_meta.CommandMeta) ([]command_meta.CommandMeta, *pipelineTimer) {
+	if len(metas) == 0 || metas[0].Name != "time" || len(metas[0].Envs.Vars) != 0 {
+		return metas, nil
+	}
+
+	timer := &pipelineTimer{}
+	first := shiftName(metas[0])
+	if first.Name == "-p" {
+		timer.posix = true
+		first = shiftName(first)
+	}
+
+	result := append([]command_meta.CommandMeta{}, metas...)
+	result[0] = first
+	if first.IsEmpty() {
+		result = result[1:]
+	}
+	return result, timer
+}
+
+// shiftName делает первый аргумент команды ее именем
+func shiftName(meta command_meta.CommandMeta) command_meta.CommandMeta {
+	meta.Name = ""
+	if len(meta.Args) != 0 {
+		meta.Name = meta.Args[0]
+		meta.Args = meta.Args[1:]
+	}
+	return meta
+}
+
+// Start запоминает момент начала и возвращает контекст, в котором внешние процессы
+// пайплайна сообщают свое процессорное время
+func (t *pipelineTimer) Start(ctx context.Context) context.Context {
+	t.start = time.Now()
+	syscall.Getrusage(syscall.RUSAGE_SELF, &t.self)
+	return commands.WithUsage(ctx, &t.usage)
+}
+
+// Stop возвращает реальное время, время в пользовательском режиме и в режиме ядра.
+// Процессорное время встроенных команд - прирост времени процесса оболочки
+// (в него попадают и другие сессии этого процесса), к нему добавляется время внешних процессов.
+func (t *pipelineTimer) Stop() (real time.Duration, user time.Duration, sys time.Duration) {
+	real = time.Since(t.start)
+	var self syscall.Rusage
+	syscall.Getrusage(syscall.RUSAGE_SELF, &self)
+	user, sys = t.usage.Times()
+	user += time.Duration(self.Utime.Nano() - t.self.Utime.Nano())
+	sys += time.Duration(self.Stime.Nano() - t.self.Stime.Nano())
+	return real, user, sys
+}
+
+// formatTimes формирует отчет time по формату TIMEFORMAT.
+// %[p][l]R, %[p][l]U, %[p][l]S - реальное, пользовательское и системное время,
+// p - число знаков после запятой (от 0 до 3, по умолчанию 3), l - формат MMmSS.FFFs;
+// %P - загрузка процессора в процентах, %% - символ %.
+func formatTimes(format string, real time.Duration, user time.Duration, sys time.Duration) string {
+	var result strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			result.WriteByte(format[i])
+			continue
+		}
+
+		j := i + 1
+		precision, long := 3, false
+		if format[j] >= '0' && format[j] <= '9' {
+			precision = min(int(format[j]-'0'), 3)
+			j++
+		}
+		if j < len(format) && format[j] == 'l' {
+			long = true
+			j++
+		}
+		if j == len(format) {
+			result.WriteString(format[i:])
+			break
+		}
+
+		var value time.Duration
+		switch format[j] {
+		case '%':
+			result.WriteByte('%')
+			i = j
+			continue
+		case 'P':
+			percent := 0.0
+			if real > 0 {
+				percent = float64(user+sys) / float64(real) * 100
+			}
+			fmt.Fprintf(&result, "%.2f", percent)
+			i = j
+			continue
+		case 'R':
+			value = real
+		case 'U':
+			value = user
+		case 'S':
+			value = sys
+		default:
+			result.WriteString(format[i : j+1])
+			i = j
+			continue
+		}
+
+		seconds := value.Seconds()
+		if long {
+			minutes := int(seconds / 60)
+			fmt.Fprintf(&result, "%dm%.*fs", minutes, precision, seconds-float64(minutes*60))
+		} else {
+			fmt.Fprintf(&result, "%.*f", precision, seconds)
+		}
+		i = j
+	}
+	return result.String()
+}
+
+// reportTimes выводит в поток ошибок сессии время исполнения пайплайна
+// в формате из переменной TIMEFORMAT. Пустое значение TIMEFORMAT отключает вывод.
+func (self *Shell) reportTimes(timer *pipelineTimer) {
+	real, user, sys := timer.Stop()
+	format, ok := self.sess.Get("TIMEFORMAT")
+	switch {
+	case timer.posix:
+		format = posixTimeFormat
+	case !ok:
+		format = defaultTimeFormat
+	case format == "":
+		return
+	}
+	io.WriteString(self.sess.Stderr, formatTimes(format, real, user, sys)+"\n")
+}
@@ -0,0 +1,202 @@
+package syntaxcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"shell/internal/command_meta"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/parser"
+	"sort"
+)
+
+// Check разбирает скрипт целиком, не исполняя его, и возвращает все синтаксические ошибки.
+// Подстановки при разборе не выполняются, поэтому ошибки вычисления
+// (например, деление на ноль в $(( ))) не сообщаются.
+func Check(script io.Reader) ([]*parser.SyntaxError, error) {
+	_, errs, err := parseAll(script)
+	return errs, err
+}
+
+// Результат разбора скрипта в виде, который выводит --dump-ast
+type AST struct {
+	Tokens    []Token    `json:"tokens"`
+	Pipelines []Pipeline `json:"pipelines"`
+	Errors    []Error    `json:"errors"`
+}
+
+// Токен с типом и позицией
+type Token struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Pattern string `json:"pattern,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+// Пайплайн команд, разобранный из одной строки скрипта (или из нескольких, если она продолжается)
+type Pipeline struct {
+	Commands []Command `json:"commands"`
+}
+
+// Команда пайплайна: поля command_meta.CommandMeta
+type Command struct {
+	Name      string       `json:"name"`
+	Args      []string     `json:"args"`
+	Envs      []Assignment `json:"envs,omitempty"`
+	Redirects []Redirect   `json:"redirects,omitempty"`
+	// Подстановки процессов <(...) и >(...)
+	Substitutions []Substitution `json:"substitutions,omitempty"`
+}
+
+// Подстановка процесса с разобранным пайплайном
+type Substitution struct {
+	Op       string    `json:"op"`
+	Text     string    `json:"text"`
+	Commands []Command `json:"commands"`
+}
+
+// Перенаправление ввода-вывода команды
+type Redirect struct {
+	Op     string `json:"op"`
+	Target string `json:"target"`
+}
+
+// Локальная для команды переменная окружения
+type Assignment struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Синтаксическая ошибка
+type Error struct {
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Message    string `json:"message"`
+	Incomplete bool   `json:"incomplete"`
+}
+
+// Parse разбирает скрипт, не исполняя его: возвращает токены, пайплайны CommandMeta и ошибки
+func Parse(script io.Reader) (*AST, error) {
+	data, err := io.ReadAll(script)
+	if err != nil {
+		return nil, err
+	}
+
+	ast := &AST{Tokens: []Token{}, Pipelines: []Pipeline{}, Errors: []Error{}}
+	for _, token := range tokenize(data) {
+		ast.Tokens = append(ast.Tokens, Token{
+			Type:    token.TokenType.String(),
+			Value:   token.Value,
+			Pattern: token.Pattern,
+			Line:    token.Line,
+			Column:  token.Column,
+		})
+	}
+
+	pipelines, errs, err := parseAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, metas := range pipelines {
+		pipeline := Pipeline{Commands: []Command{}}
+		for _, meta := range metas {
+			pipeline.Commands = append(pipeline.Commands, newCommand(meta))
+		}
+		ast.Pipelines = append(ast.Pipelines, pipeline)
+	}
+	for _, e := range errs {
+		ast.Errors = append(ast.Errors, Error{Line: e.Line, Column: e.Column, Message: e.Message, Incomplete: e.Incomplete})
+	}
+	return ast, nil
+}
+
+// Dump выводит результат Parse в формате JSON
+func Dump(script io.Reader, output io.Writer) (*AST, error) {
+	ast, err := Parse(script)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return ast, encoder.Encode(ast)
+}
+
+// newTokenizer создает токенизатор, который не выполняет подстановки
+func newTokenizer(script io.Reader) *parser.Tokenizer {
+	vars := &envsholder.Env{}
+	vars.Init()
+	tokenizer := parser.NewTokenizer(script, vars)
+	tokenizer.SetNoExpand(true)
+	return tokenizer
+}
+
+// tokenize возвращает все токены скрипта, пропуская строки с ошибками
+func tokenize(data []byte) []*parser.Token {
+	tokenizer := newTokenizer(bytes.NewReader(data))
+	var tokens []*parser.Token
+	for {
+		token, err := tokenizer.Next()
+		if token != nil {
+			tokens = append(tokens, token)
+		}
+		if err == io.EOF {
+			return tokens
+		}
+	}
+}
+
+// parseAll разбирает скрипт до конца, продолжая разбор после ошибок
+func parseAll(script io.Reader) ([][]command_meta.CommandMeta, []*parser.SyntaxError, error) {
+	p := parser.NewParser(newTokenizer(script))
+	var pipelines [][]command_meta.CommandMeta
+	var errs []*parser.SyntaxError
+	for {
+		metas, err := p.Parse()
+		var syntaxErr *parser.SyntaxError
+		switch {
+		case errors.As(err, &syntaxErr):
+			errs = append(errs, syntaxErr)
+			continue
+		case err != nil && err != io.EOF:
+			return nil, nil, err
+		}
+
+		if len(metas) != 0 {
+			pipelines = append(pipelines, metas)
+		}
+		if err == io.EOF {
+			return pipelines, errs, nil
+		}
+	}
+}
+
+func newCommand(meta command_meta.CommandMeta) Command {
+	command := Command{Name: meta.Name, Args: meta.Args}
+	if command.Args == nil {
+		command.Args = []string{}
+	}
+	for _, redirect := range meta.Redirects {
+		command.Redirects = append(command.Redirects, Redirect{Op: redirect.Op, Target: redirect.Target})
+	}
+
+	for _, substitution := range meta.Substitutions {
+		converted := Substitution{Op: substitution.Op, Text: substitution.Text, Commands: []Command{}}
+		for _, inner := range substitution.Commands {
+			converted.Commands = append(converted.Commands, newCommand(inner))
+		}
+		command.Substitutions = append(command.Substitutions, converted)
+	}
+
+	names := make([]string, 0, len(meta.Envs.Vars))
+	for name := range meta.Envs.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		command.Envs = append(command.Envs, Assignment{Name: name, Value: meta.Envs.Vars[name]})
+	}
+	return command
+}
@@ -0,0 +1,48 @@
+package syntaxcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReportsEveryError(t *testing.T) {
+	script := "echo ok\necho | | wc\necho $((a / b))\nx=$((1 + )) ls\ncat >\necho 'open\n"
+	errs, err := Check(strings.NewReader(script))
+	require.NoError(t, err)
+
+	positions := [][2]int{}
+	for _, e := range errs {
+		positions = append(positions, [2]int{e.Line, e.Column})
+	}
+	// Деление на ноль - ошибка вычисления, а не синтаксиса, поэтому строка 3 не сообщается
+	require.Equal(t, [][2]int{{2, 8}, {4, 11}, {5, 6}, {6, 6}}, positions)
+	require.True(t, errs[3].Incomplete)
+}
+
+func TestCheckValidScript(t *testing.T) {
+	errs, err := Check(strings.NewReader("x=1\necho \"$x\" |\n  wc > out\n"))
+	require.NoError(t, err)
+	require.Empty(t, errs)
+}
+
+func TestDump(t *testing.T) {
+	var output bytes.Buffer
+	ast, err := Dump(strings.NewReader("A=1 echo \"$HOME\" *.go | wc >out\n"), &output)
+	require.NoError(t, err)
+
+	var decoded AST
+	require.NoError(t, json.Unmarshal(output.Bytes(), &decoded))
+	require.Equal(t, *ast, decoded)
+
+	require.Equal(t, Token{Type: "word", Value: "*.go", Pattern: "*.go", Line: 1, Column: 18}, decoded.Tokens[3])
+	require.Equal(t, Token{Type: "redirect", Value: ">", Line: 1, Column: 28}, decoded.Tokens[6])
+	require.Equal(t, []Pipeline{{Commands: []Command{
+		{Name: "echo", Args: []string{"$HOME", "*.go"}, Envs: []Assignment{{Name: "A", Value: "1"}}},
+		{Name: "wc", Args: []string{}, Redirects: []Redirect{{Op: ">", Target: "out"}}},
+	}}}, decoded.Pipelines)
+	require.Empty(t, decoded.Errors)
+}
@@ -1,23 +1,274 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"shell/internal/commands"
+	envsholder "shell/internal/envs_holder"
+	"shell/internal/executor"
+	lineeditor "shell/internal/line_editor"
+	"shell/internal/remote"
+	"shell/internal/session"
 	shellmodel "shell/internal/shell_model"
+	syntaxcheck "shell/internal/syntax_check"
+	"strings"
 	"syscall"
+
+	"github.com/jessevdk/go-flags"
 )
 
+// Аргументы командной строки оболочки
+type options struct {
+	Listen  string `long:"listen" value-name:"ADDRESS" description:"serve isolated shell sessions on unix:PATH or tcp:HOST:PORT"`
+	NoExec  bool   `short:"n" description:"check the syntax of SCRIPT without executing it"`
+	DumpAST bool   `long:"dump-ast" description:"print tokens and parsed pipelines of SCRIPT as JSON without executing it"`
+
+	AuditLog    string   `long:"audit-log" value-name:"FILE" description:"append every executed pipeline to FILE as JSON lines"`
+	AuditRedact []string `long:"audit-redact" value-name:"PATTERN" description:"hide values of variables matching PATTERN in the audit log (may be repeated)"`
+
+	EnvFiles []string `long:"env-file" value-name:"FILE" description:"set session variables from a .env FILE (may be repeated)"`
+
+	Restricted bool   `long:"restricted" description:"forbid cd, changing PATH/SHELL/ENV, output redirection and command names with /"`
+	Allowlist  string `long:"allowlist" value-name:"FILE" description:"external programs allowed in restricted mode, one name per line"`
+
+	Positional struct {
+		Command string `positional-arg-name:"attach|SCRIPT"`
+		Address string `positional-arg-name:"ADDRESS"`
+	} `positional-args:"true"`
+}
+
 func main() {
+	var opts options
+	if _, err := flags.NewParser(&opts, flags.Default).Parse(); err != nil {
+		os.Exit(2)
+	}
+
+	audit, err := openAuditLog(opts.AuditLog, opts.AuditRedact)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		os.Exit(1)
+	}
+
+	var allowed []string
+	if opts.Allowlist != "" {
+		if allowed, err = readAllowlist(opts.Allowlist); err != nil {
+			fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	envFiles, err := readEnvFiles(opts.EnvFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		os.Exit(1)
+	}
+	// Переменные из --env-file задаются до включения ограниченного режима,
+	// поэтому файл может задать и PATH. Ошибка не дает запустить сессию
+	// с частично примененными файлами.
+	setup := func(sess *session.Session) error {
+		for i, data := range envFiles {
+			if err := commands.LoadEnv(sess, bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("%s: %v", opts.EnvFiles[i], err)
+			}
+		}
+		if opts.Restricted || opts.Allowlist != "" {
+			sess.Restrict(allowed)
+		}
+		return nil
+	}
+
+	switch {
+	case opts.NoExec || opts.DumpAST:
+		os.Exit(check(opts.Positional.Command, opts.DumpAST))
+	case opts.Listen != "":
+		os.Exit(listen(opts.Listen, audit, setup))
+	case opts.Positional.Command == "attach":
+		os.Exit(attach(opts.Positional.Address))
+	case opts.Positional.Command != "":
+		fmt.Fprintf(os.Stderr, "shell: unknown command %q\n", opts.Positional.Command)
+		os.Exit(2)
+	}
+
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	for _, sig := range commands.TrapSignals() {
+		signal.Notify(sigChan, sig)
+	}
 
-	sh := shellmodel.NewShell()
+	sess, err := session.New("", os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		os.Exit(1)
+	}
+	if err := setup(sess); err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		os.Exit(1)
+	}
+	sh := shellmodel.NewShell(sess)
+	sh.SetAuditLog(audit)
 
+	// С терминала команды читаются редактором строки с подсветкой синтаксиса
+	editor, err := lineeditor.New(os.Stdin, os.Stdout, func(name string) bool {
+		return commands.IsKnownCommand(sess, name)
+	})
+	done := make(chan int, 1)
 	go func() {
-		sh.ShellLoop(os.Stdin, os.Stdout, false)
-		os.Exit(0)
+		if err != nil {
+			done <- sh.ShellLoop(os.Stdin, os.Stdout, false)
+			return
+		}
+		done <- sh.Interactive(editor, os.Stdin, os.Stdout)
 	}()
 
-	<-sigChan
-	sh.Terminate()
+	// Без обработчиков trap SIGINT прерывает только текущий пайплайн,
+	// остальные сигналы завершают оболочку
+	for {
+		select {
+		case status := <-done:
+			os.Exit(status)
+		case sig := <-sigChan:
+			if status, terminated := sh.Signal(sig.(syscall.Signal)); terminated {
+				// Цикл оболочки может ожидать ввода, поэтому сессия завершается здесь
+				os.Exit(sh.Shutdown(status, os.Stdin, os.Stdout))
+			}
+		}
+	}
+}
+
+// check разбирает скрипт, не исполняя его, и выводит синтаксические ошибки в stderr,
+// а с dump - результат разбора в формате JSON. Пустой путь или "-" означает стандартный ввод.
+// Возвращает 2, если найдены ошибки.
+func check(path string, dump bool) int {
+	script, name := io.Reader(os.Stdin), "stdin"
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+			return 1
+		}
+		defer file.Close()
+		script, name = file, path
+	}
+
+	if dump {
+		ast, err := syntaxcheck.Dump(script, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+			return 1
+		}
+		if len(ast.Errors) != 0 {
+			return 2
+		}
+		return 0
+	}
+
+	errs, err := syntaxcheck.Check(script)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		return 1
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", name, e.Diagnostic())
+	}
+	if len(errs) != 0 {
+		return 2
+	}
+	return 0
+}
+
+// openAuditLog открывает журнал исполненных пайплайнов на дозапись.
+// Пустой путь означает, что журнал не ведется.
+func openAuditLog(path string, redact []string) (*executor.AuditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return executor.NewAuditLog(file, redact)
+}
+
+// readAllowlist читает список внешних программ, разрешенных в ограниченном режиме:
+// по одному имени в строке, пустые строки и строки, начинающиеся с #, пропускаются
+func readAllowlist(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.ContainsAny(line, "/ \t") {
+			return nil, fmt.Errorf("%s:%d: invalid program name %q", path, i+1, line)
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// readEnvFiles читает файлы .env для --env-file и проверяет их синтаксис
+func readEnvFiles(paths []string) ([][]byte, error) {
+	var files [][]byte
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := envsholder.ParseDotenv(bytes.NewReader(data), nil); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		files = append(files, data)
+	}
+	return files, nil
+}
+
+// listen обслуживает удаленные сессии до получения SIGINT или SIGTERM.
+// Функция setup вызывается для каждой новой сессии.
+func listen(address string, audit *executor.AuditLog, setup func(*session.Session) error) int {
+	listener, err := remote.Listen(address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "shell: listening on %s\n", listener.Addr())
+	server := remote.NewServer(listener, os.Stderr)
+	server.SetAuditLog(audit)
+	server.SetSessionSetup(setup)
+	if err := server.Serve(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// attach подключает терминал к удаленной сессии
+func attach(address string) int {
+	if address == "" {
+		fmt.Fprintf(os.Stderr, "shell: attach: address expected\n")
+		return 2
+	}
+
+	conn, err := remote.Dial(address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := remote.Attach(conn, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "shell: %s\n", err)
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,49 @@
+// Пакет shell позволяет встраивать интерпретатор в Go-программы.
+// Каждый вызов Run работает в собственной сессии со своими переменными,
+// текущей директорией, алиасами и опциями, поэтому вызовы безопасно
+// исполнять одновременно из разных горутин.
+package shell
+
+import (
+	"context"
+	"io"
+	"shell/internal/session"
+	shellmodel "shell/internal/shell_model"
+	"shell/pkg/vfs"
+	"strings"
+)
+
+// Run исполняет скрипт script в новой сессии, текущей директорией которой
+// становится текущая директория процесса.
+// Команды читают ввод из stdin и пишут вывод в stdout, сообщения об ошибках
+// выводятся в stderr. Любой из потоков может быть nil.
+// Отмена ctx прерывает исполняемый пайплайн и завершает скрипт.
+// Команда exit завершает только сессию скрипта.
+// Возвращает код завершения сессии; ошибка возвращается, только если сессию не удалось создать.
+func Run(ctx context.Context, script string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+	sess, err := session.New("", stderr)
+	if err != nil {
+		return 0, err
+	}
+
+	sh := shellmodel.NewShell(sess)
+	return sh.Run(ctx, strings.NewReader(script), stdin, stdout), nil
+}
+
+// RunFS исполняет скрипт, как Run, но встроенные команды, перенаправления
+// и шаблоны имен файлов работают с файловой системой fsys, например vfs.NewMem()
+// или vfs.NewDir(dir). Текущей директорией сессии становится dir - абсолютный путь
+// в fsys. Внешние программы по-прежнему работают с диском.
+func RunFS(ctx context.Context, fsys vfs.FS, dir string, script string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+	sess, err := session.New("/", stderr)
+	if err != nil {
+		return 0, err
+	}
+	sess.SetFS(fsys)
+	if err := sess.Chdir(dir); err != nil {
+		return 0, err
+	}
+
+	sh := shellmodel.NewShell(sess)
+	return sh.Run(ctx, strings.NewReader(script), stdin, stdout), nil
+}
@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"shell/pkg/vfs"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	status, err := Run(context.Background(), "read line\necho got $line | tr a-z A-Z\n", strings.NewReader("data\n"), &stdout, &stderr)
+	require.NoError(t, err)
+	require.Equal(t, 0, status)
+	require.Equal(t, "GOT DATA\n", stdout.String())
+	require.Empty(t, stderr.String())
+}
+
+func TestRunFS(t *testing.T) {
+	fsys := vfs.NewMem()
+	require.NoError(t, fsys.Mkdir("/work", 0755))
+	require.NoError(t, vfs.WriteFile(fsys, "/work/a.txt", []byte("one\ntwo\n"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	script := "cat a.txt | grep t > b.txt\nls\necho *.txt\ncat b.txt\ncd /\npwd\n"
+	status, err := RunFS(context.Background(), fsys, "/work", script, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.Equal(t, 0, status, stderr.String())
+	require.Equal(t, "-rw-r--r-- a.txt\n-rw-rw-rw- b.txt\na.txt b.txt\ntwo\n/", stdout.String())
+
+	_, err = RunFS(context.Background(), fsys, "/missing", "", nil, nil, nil)
+	require.Error(t, err)
+}
+
+func TestRunStatus(t *testing.T) {
+	status, err := Run(context.Background(), "test 1 -eq 2\n", nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, status)
+
+	var stderr bytes.Buffer
+	status, err = Run(context.Background(), "echo a\nexit\necho b\n", nil, nil, &stderr)
+	require.NoError(t, err)
+	require.Equal(t, 0, status)
+	require.Empty(t, stderr.String())
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	status, err := Run(ctx, "tail -f /dev/null\necho unreachable\n", nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 130, status)
+}
+
+func TestRunConcurrentSessions(t *testing.T) {
+	const sessions = 16
+
+	var wg sync.WaitGroup
+	outputs := make([]bytes.Buffer, sessions)
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			script := fmt.Sprintf("n=%d\nalias show=echo\ncd /\nlet n*=2\nshow $n\npwd\n", i)
+			_, err := Run(context.Background(), script, nil, &outputs[i], nil)
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < sessions; i++ {
+		require.Equal(t, fmt.Sprintf("%d\n/", 2*i), outputs[i].String())
+	}
+}
@@ -0,0 +1,123 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Файловая система, корень которой - директория ОС
+type dirFS struct {
+	root string
+}
+
+// NewDir создает файловую систему, корнем которой служит директория root.
+// Пути внутри нее абсолютные ("/" - сама директория root), выйти за ее пределы
+// нельзя ни через "..", ни через символические ссылки. Сообщения об ошибках
+// содержат пути внутри файловой системы, а не пути на диске.
+func NewDir(root string) (FS, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: root, Err: errors.New("not a directory")}
+	}
+	return dirFS{root}, nil
+}
+
+// resolve возвращает путь на диске для пути name. Проверяется, что ближайший
+// существующий предок пути (или сам путь, если follow) после раскрытия
+// символических ссылок остается внутри корня.
+func (d dirFS) resolve(op string, name string, follow bool) (string, error) {
+	path := filepath.Join(d.root, filepath.Clean("/"+name))
+	check := path
+	if !follow {
+		check = filepath.Dir(path)
+	}
+
+	for {
+		real, err := filepath.EvalSymlinks(check)
+		if err == nil {
+			if real != d.root && !strings.HasPrefix(real, d.root+string(filepath.Separator)) {
+				return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+			}
+			return path, nil
+		}
+		// Висящая ссылка может указывать за пределы корня, а файл по ней можно создать
+		if info, err := os.Lstat(check); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+		}
+		parent := filepath.Dir(check)
+		if parent == check {
+			return path, nil
+		}
+		check = parent
+	}
+}
+
+// virtualError заменяет путь на диске в ошибке на путь внутри файловой системы
+func virtualError(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+func (d dirFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	path, err := d.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, virtualError(err, name)
+	}
+	return file, nil
+}
+
+func (d dirFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := d.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	return info, virtualError(err, name)
+}
+
+func (d dirFS) Lstat(name string) (fs.FileInfo, error) {
+	path, err := d.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(path)
+	return info, virtualError(err, name)
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := d.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	return entries, virtualError(err, name)
+}
+
+func (d dirFS) Mkdir(name string, perm fs.FileMode) error {
+	path, err := d.resolve("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	return virtualError(os.Mkdir(path, perm), name)
+}
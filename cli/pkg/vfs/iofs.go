@@ -0,0 +1,69 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Файловая система только для чтения поверх io/fs
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+// FromFS создает файловую систему только для чтения из fs.FS, например
+// из архива (zip.Reader), embed.FS или os.DirFS. Абсолютный путь "/a/b"
+// соответствует пути "a/b" в fsys. Запись и создание директорий
+// завершаются ошибкой fs.ErrPermission.
+func FromFS(fsys fs.FS) FS {
+	return readOnlyFS{fsys}
+}
+
+// fsPath переводит абсолютный путь в путь io/fs
+func fsPath(name string) string {
+	path := strings.TrimPrefix(clean(name), "/")
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func (r readOnlyFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	file, err := r.fsys.Open(fsPath(name))
+	if err != nil {
+		return nil, virtualError(err, name)
+	}
+	return readOnlyFile{file, name}, nil
+}
+
+func (r readOnlyFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(r.fsys, fsPath(name))
+	return info, virtualError(err, name)
+}
+
+// Lstat совпадает со Stat, так как io/fs не различает символические ссылки
+func (r readOnlyFS) Lstat(name string) (fs.FileInfo, error) {
+	return r.Stat(name)
+}
+
+func (r readOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(r.fsys, fsPath(name))
+	return entries, virtualError(err, name)
+}
+
+func (r readOnlyFS) Mkdir(name string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+// Открытый файл io/fs, запись в который запрещена
+type readOnlyFile struct {
+	fs.File
+	name string
+}
+
+func (f readOnlyFile) Write(data []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+}
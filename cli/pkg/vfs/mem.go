@@ -0,0 +1,237 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemFS - файловая система в памяти. Символических ссылок в ней нет,
+// права доступа хранятся, но не проверяются.
+// Безопасна для одновременного использования несколькими командами.
+type MemFS struct {
+	mutex sync.RWMutex
+	// Файлы и директории по абсолютному очищенному пути
+	nodes map[string]*memNode
+}
+
+// Файл или директория MemFS
+type memNode struct {
+	mode    fs.FileMode
+	data    []byte
+	modTime time.Time
+}
+
+var _ FS = (*MemFS)(nil)
+
+// NewMem создает пустую файловую систему в памяти с корневой директорией "/"
+func NewMem() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{
+		"/": {mode: fs.ModeDir | 0755, modTime: time.Now()},
+	}}
+}
+
+// clean приводит путь к виду, в котором он хранится
+func clean(name string) string {
+	return filepath.Clean("/" + name)
+}
+
+// lookup находит узел по пути. Вызывается под блокировкой.
+func (m *MemFS) lookup(op string, name string) (*memNode, error) {
+	node, ok := m.nodes[clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+// checkParent проверяет, что родитель пути - существующая директория.
+// Вызывается под блокировкой.
+func (m *MemFS) checkParent(op string, name string) error {
+	parent, ok := m.nodes[filepath.Dir(clean(name))]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: errors.New("not a directory")}
+	}
+	return nil
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	node, err := m.lookup("open", name)
+	switch {
+	case err != nil && flag&os.O_CREATE == 0:
+		return nil, err
+	case err != nil:
+		if err := m.checkParent("open", name); err != nil {
+			return nil, err
+		}
+		node = &memNode{mode: perm & fs.ModePerm, modTime: time.Now()}
+		m.nodes[clean(name)] = node
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if node.mode.IsDir() && writable {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	if flag&os.O_TRUNC != 0 && writable {
+		node.data = nil
+		node.modTime = time.Now()
+	}
+	return &memFile{fs: m, name: name, node: node, flag: flag}, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	node, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return node.info(filepath.Base(clean(name))), nil
+}
+
+// Lstat совпадает со Stat, так как символических ссылок в MemFS нет
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.Stat(name)
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	dir := clean(name)
+	node, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	var entries []fs.DirEntry
+	for path, child := range m.nodes {
+		if path != dir && filepath.Dir(path) == dir {
+			entries = append(entries, fs.FileInfoToDirEntry(child.info(filepath.Base(path))))
+		}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, err := m.lookup("mkdir", name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if err := m.checkParent("mkdir", name); err != nil {
+		return err
+	}
+	m.nodes[clean(name)] = &memNode{mode: fs.ModeDir | perm&fs.ModePerm, modTime: time.Now()}
+	return nil
+}
+
+// info возвращает сведения об узле с именем name. Вызывается под блокировкой.
+func (n *memNode) info(name string) fs.FileInfo {
+	return memFileInfo{name: name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+//////////////////////////////////
+
+// Сведения о файле MemFS
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
+
+// Открытый файл MemFS
+type memFile struct {
+	fs     *MemFS
+	name   string
+	node   *memNode
+	flag   int
+	offset int
+	closed bool
+}
+
+func (f *memFile) Read(data []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrPermission}
+	}
+	if f.node.mode.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	if f.offset >= len(f.node.data) {
+		return 0, io.EOF
+	}
+	n := copy(data, f.node.data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *memFile) Write(data []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = len(f.node.data)
+	}
+	if end := f.offset + len(data); end > len(f.node.data) {
+		f.node.data = append(f.node.data, make([]byte, end-len(f.node.data))...)
+	}
+	copy(f.node.data[f.offset:], data)
+	f.offset += len(data)
+	f.node.modTime = time.Now()
+	return len(data), nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fs.mutex.RLock()
+	defer f.fs.mutex.RUnlock()
+	return f.node.info(filepath.Base(clean(f.name))), nil
+}
@@ -0,0 +1,213 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File - открытый файл файловой системы FS.
+// Запись в файл, открытый только на чтение, завершается ошибкой.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// FS - файловая система, через которую встроенные команды и перенаправления
+// обращаются к файлам. В отличие от io/fs, она допускает запись и работает
+// с абсолютными путями в стиле ОС: пути строит сессия из своей текущей директории,
+// поэтому смена директории (cd) - это проверка пути через Stat.
+type FS interface {
+	// OpenFile открывает файл с флагами os.O_*, как os.OpenFile
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	// Stat возвращает сведения о файле, раскрывая символические ссылки
+	Stat(name string) (fs.FileInfo, error)
+	// Lstat возвращает сведения о файле, не раскрывая символическую ссылку
+	Lstat(name string) (fs.FileInfo, error)
+	// ReadDir возвращает содержимое директории, отсортированное по имени
+	ReadDir(name string) ([]fs.DirEntry, error)
+	// Mkdir создает директорию
+	Mkdir(name string, perm fs.FileMode) error
+}
+
+// Open открывает файл на чтение
+func Open(fsys FS, name string) (File, error) {
+	return fsys.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// ReadFile читает файл целиком
+func ReadFile(fsys FS, name string) ([]byte, error) {
+	file, err := Open(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// WriteFile записывает данные в файл, создавая его или обрезая существующий
+func WriteFile(fsys FS, name string, data []byte, perm fs.FileMode) error {
+	file, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WalkDir обходит дерево файлов с корнем root, как filepath.WalkDir.
+// Символические ссылки на директории не раскрываются.
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, path string, entry fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, entry, nil); err != nil || !entry.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && entry.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Второй вызов сообщает об ошибке чтения директории
+		err = fn(path, entry, err)
+		if err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, child := range entries {
+		if err := walkDir(fsys, filepath.Join(path, child.Name()), child, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+//////////////////////////////////
+
+// Файловая система ОС
+type osFS struct{}
+
+// OS - файловая система ОС, с которой сессии работают по умолчанию.
+// Файлы открываются как *os.File, поэтому их можно передать внешнему процессу.
+var OS FS = osFS{}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	file, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) Mkdir(name string, perm fs.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+// sortEntries упорядочивает содержимое директории по имени
+func sortEntries(entries []fs.DirEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+}
+
+// Glob возвращает пути, подходящие под шаблон, как filepath.Glob.
+// Ошибки чтения директорий игнорируются.
+func Glob(fsys FS, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, err := fsys.Lstat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := filepath.Split(pattern)
+	dir = cleanGlobPath(dir)
+	if !hasMeta(dir) {
+		return glob(fsys, dir, file, nil), nil
+	}
+	// Защита от бесконечной рекурсии на шаблонах вида `\\?\`
+	if dir == pattern {
+		return nil, filepath.ErrBadPattern
+	}
+
+	dirs, err := Glob(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, dir := range dirs {
+		matches = glob(fsys, dir, file, matches)
+	}
+	return matches, nil
+}
+
+// glob добавляет к matches файлы директории dir, имена которых подходят под шаблон
+func glob(fsys FS, dir string, pattern string, matches []string) []string {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return matches
+	}
+	for _, entry := range entries {
+		if ok, _ := filepath.Match(pattern, entry.Name()); ok {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return matches
+}
+
+// hasMeta сообщает, что путь содержит символы шаблона
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
+
+// cleanGlobPath убирает завершающий разделитель из директории шаблона
+func cleanGlobPath(path string) string {
+	switch path {
+	case "":
+		return "."
+	case string(filepath.Separator):
+		return path
+	}
+	return path[:len(path)-1]
+}
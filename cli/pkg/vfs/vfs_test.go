@@ -0,0 +1,163 @@
+package vfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemFS(t *testing.T) {
+	fsys := NewMem()
+	require.NoError(t, fsys.Mkdir("/dir", 0755))
+	require.ErrorIs(t, fsys.Mkdir("/dir", 0755), fs.ErrExist)
+	require.ErrorIs(t, fsys.Mkdir("/missing/dir", 0755), fs.ErrNotExist)
+
+	require.NoError(t, WriteFile(fsys, "/dir/b", []byte("hello"), 0644))
+	require.NoError(t, WriteFile(fsys, "/dir/a", nil, 0600))
+
+	file, err := fsys.OpenFile("/dir/b", os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.Write([]byte(" world"))
+	require.NoError(t, err)
+	_, err = file.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.NoError(t, file.Close())
+
+	data, err := ReadFile(fsys, "/dir/../dir/b")
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+
+	file, err = Open(fsys, "/dir/b")
+	require.NoError(t, err)
+	_, err = file.Write([]byte("x"))
+	require.ErrorIs(t, err, fs.ErrPermission)
+	file.Close()
+
+	_, err = fsys.OpenFile("/dir/b", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	require.ErrorIs(t, err, fs.ErrExist)
+	_, err = Open(fsys, "/dir/c")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = fsys.OpenFile("/dir", os.O_WRONLY, 0)
+	require.Error(t, err)
+
+	entries, err := fsys.ReadDir("/dir")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "a", entries[0].Name())
+	require.Equal(t, "b", entries[1].Name())
+
+	info, err := fsys.Stat("/dir/b")
+	require.NoError(t, err)
+	require.Equal(t, int64(11), info.Size())
+	require.Equal(t, fs.FileMode(0644), info.Mode())
+
+	info, err = fsys.Stat("/")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+}
+
+func TestDirFS(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	require.NoError(t, os.Mkdir(root, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "secret"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(base, "secret"), filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(base, "new"), filepath.Join(root, "dangling")))
+	require.NoError(t, os.Symlink(base, filepath.Join(root, "up")))
+
+	fsys, err := NewDir(root)
+	require.NoError(t, err)
+
+	require.NoError(t, WriteFile(fsys, "/file", []byte("data"), 0644))
+	data, err := os.ReadFile(filepath.Join(root, "file"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	// Путь с ".." не выходит за пределы корня
+	data, err = ReadFile(fsys, "/../../file")
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	_, err = ReadFile(fsys, "/escape")
+	require.ErrorIs(t, err, fs.ErrPermission)
+	require.ErrorIs(t, WriteFile(fsys, "/dangling", nil, 0644), fs.ErrPermission)
+	_, err = os.Stat(filepath.Join(base, "new"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = fsys.ReadDir("/up")
+	require.ErrorIs(t, err, fs.ErrPermission)
+	_, err = ReadFile(fsys, "/up/secret")
+	require.ErrorIs(t, err, fs.ErrPermission)
+
+	// Сама ссылка видна через Lstat
+	info, err := fsys.Lstat("/escape")
+	require.NoError(t, err)
+	require.NotZero(t, info.Mode()&fs.ModeSymlink)
+
+	// Ошибки не раскрывают путь на диске
+	_, err = ReadFile(fsys, "/missing")
+	require.EqualError(t, err, "open /missing: no such file or directory")
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := FromFS(fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"dir/b.txt": {Data: []byte("b")},
+	})
+
+	data, err := ReadFile(fsys, "/dir/b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "b", string(data))
+
+	entries, err := fsys.ReadDir("/")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	info, err := fsys.Stat("/dir")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+
+	require.ErrorIs(t, WriteFile(fsys, "/c.txt", nil, 0644), fs.ErrPermission)
+	require.ErrorIs(t, fsys.Mkdir("/new", 0755), fs.ErrPermission)
+	_, err = Open(fsys, "/missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestGlobAndWalkDir(t *testing.T) {
+	fsys := NewMem()
+	require.NoError(t, fsys.Mkdir("/src", 0755))
+	require.NoError(t, fsys.Mkdir("/src/sub", 0755))
+	for _, name := range []string{"/a.go", "/src/b.go", "/src/c.txt", "/src/sub/d.go"} {
+		require.NoError(t, WriteFile(fsys, name, nil, 0644))
+	}
+
+	matches, err := Glob(fsys, "/*/*.go")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/src/b.go"}, matches)
+	matches, err = Glob(fsys, "/src/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/src/c.txt"}, matches)
+	_, err = Glob(fsys, "/[")
+	require.Error(t, err)
+
+	var walked []string
+	err = WalkDir(fsys, "/", func(path string, entry fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		if entry.Name() == "sub" {
+			return fs.SkipDir
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/", "/a.go", "/src", "/src/b.go", "/src/c.txt"}, walked)
+
+	file, err := Open(fsys, "/a.go")
+	require.NoError(t, err)
+	_, err = file.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+}